@@ -11,6 +11,7 @@ package backends
 
 import (
 	"github.com/gomlx/exceptions"
+	"github.com/pkg/errors"
 	"os"
 	"strings"
 )
@@ -81,16 +82,24 @@ const GOMLX_BACKEND = "GOMLX_BACKEND"
 // 2. Next the variable DefaultConfig is used as a configuration if defined.
 // 3. The first registered backend is used with an empty configuration.
 //
-// It panics if not backend was registered.
-func New() Backend {
+// Options (e.g.: WithMemoryFraction) are appended to the configuration, see Option.
+//
+// If a fallback order was set with SetFallbackOrder, the configuration above (if not empty) and then each of
+// the fallback platforms are tried in order, and the first one successfully created is returned.
+//
+// It panics if not backend was registered, or if it fails to create the backend.
+func New(options ...Option) Backend {
 	config, found := os.LookupEnv(GOMLX_BACKEND)
-	if found {
-		return NewWithConfig(config)
+	if !found {
+		config = DefaultConfig
 	}
-	if DefaultConfig != "" {
-		return NewWithConfig(DefaultConfig)
+	if fallbacks := getFallbackOrder(); len(fallbacks) > 0 {
+		if config != "" {
+			fallbacks = append([]string{config}, fallbacks...)
+		}
+		return newWithFallback(fallbacks, options)
 	}
-	return NewWithConfig("")
+	return NewWithConfig(appendOptions(config, options))
 }
 
 // NewWithConfig takes a configurations string formated as
@@ -114,3 +123,23 @@ func NewWithConfig(config string) Backend {
 	}
 	return constructor(backendConfig)
 }
+
+// NewWithPlatform returns a new Backend of the first registered backend type (usually "xla") pinned to the given
+// platform (e.g.: "cpu", "cuda"), ignoring GOMLX_BACKEND and DefaultConfig -- e.g.: to force tests to run on the CPU
+// even when a GPU is available.
+//
+// For the "xla" backend, the platform is the name of the PJRT plugin. It returns an error if no backend was
+// registered, or if the platform is not available.
+func NewWithPlatform(platform string) (backend Backend, err error) {
+	if len(registeredConstructors) == 0 {
+		return nil, errors.New(`no registered backends for GoMLX -- maybe import the default XLA one with import _ "github.com/gomlx/gomlx/backends/xla"?`)
+	}
+	if platform == "" {
+		return nil, errors.Errorf("NewWithPlatform requires a platform for backend %q, e.g.: \"cpu\"", firstRegistered)
+	}
+	err = exceptions.TryCatch[error](func() { backend = NewWithConfig(firstRegistered + ":" + platform) })
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to create backend %q for platform %q", firstRegistered, platform)
+	}
+	return backend, nil
+}
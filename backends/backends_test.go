@@ -0,0 +1,78 @@
+package backends_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gomlx/gomlx/backends"
+	"github.com/gomlx/gomlx/backends/xla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWithPlatform(t *testing.T) {
+	backend, err := backends.NewWithPlatform("cpu")
+	require.NoError(t, err)
+	require.NotNil(t, backend)
+	backend.Finalize()
+
+	_, err = backends.NewWithPlatform("bogus_platform")
+	require.Error(t, err)
+	require.ErrorContains(t, err, "bogus_platform")
+
+	_, err = backends.NewWithPlatform("")
+	require.Error(t, err)
+}
+
+func TestNewFromConfig(t *testing.T) {
+	backend, err := backends.NewWithPlatform("cpu")
+	require.NoError(t, err)
+	defer backend.Finalize()
+	configurable, ok := backend.(backends.Configurable)
+	require.True(t, ok, "backend %q doesn't implement backends.Configurable", backend.Name())
+	cfg := configurable.Config()
+	require.Equal(t, "cpu", cfg.Platform)
+	require.Greater(t, cfg.NumDevices, 0)
+
+	// Round-trip the configuration.
+	restored, err := backends.NewFromConfig(cfg)
+	require.NoError(t, err)
+	defer restored.Finalize()
+	restoredCfg := restored.(backends.Configurable).Config()
+	require.Equal(t, cfg.Platform, restoredCfg.Platform)
+	require.Equal(t, cfg.Config, restoredCfg.Config)
+	require.Equal(t, cfg.Options, restoredCfg.Options)
+
+	// Platform mismatch.
+	cfg.Platform = "bogus_platform"
+	_, err = backends.NewFromConfig(cfg)
+	require.ErrorContains(t, err, "bogus_platform")
+}
+
+func TestNewWithOptions(t *testing.T) {
+	// Client options are only supported by the CUDA plugin.
+	if !slices.Contains(xla.GetAvailablePlugins(), "cuda") {
+		t.Skip("PJRT \"cuda\" plugin not available")
+	}
+	t.Setenv(backends.GOMLX_BACKEND, "xla:cuda")
+	backend := backends.New(backends.WithMemoryFraction(0.5), backends.WithPreallocate(false))
+	defer backend.Finalize()
+	cfg := backend.(backends.Configurable).Config()
+	require.Equal(t, "0.5", cfg.Options["memory_fraction"])
+	require.Equal(t, "false", cfg.Options["preallocate"])
+	require.Contains(t, cfg.Config, "memory_fraction=0.5")
+}
+
+func TestSetFallbackOrder(t *testing.T) {
+	t.Setenv(backends.GOMLX_BACKEND, "")
+	defer backends.SetFallbackOrder(nil)
+
+	// The primary platform fails, and it falls back to the CPU.
+	backends.SetFallbackOrder([]string{"bogus_platform", "cpu"})
+	backend := backends.New()
+	defer backend.Finalize()
+	require.Equal(t, "cpu", backend.(backends.Configurable).Config().Platform)
+
+	// No platform can be created.
+	backends.SetFallbackOrder([]string{"bogus_platform", "another_bogus_platform"})
+	require.Panics(t, func() { backends.New() })
+}
@@ -0,0 +1,71 @@
+package backends
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/pkg/errors"
+)
+
+// BackendConfig describes the configuration of a Backend, as reported by Configurable.Config, so it can be
+// recorded (e.g.: with the results of an experiment) and restored with NewFromConfig.
+type BackendConfig struct {
+	// Name of the registered backend, e.g.: "xla".
+	Name string
+
+	// Config is the backend specific configuration string, as given to NewWithConfig after the "<backend_name>:"
+	// prefix -- e.g.: for the "xla" backend, the plugin name followed by its options, like "cpu,shared_buffers".
+	// It includes the backend default compilation options.
+	Config string
+
+	// Platform of the backend, e.g.: "cpu" or "cuda".
+	Platform string
+
+	// PlatformVersion reported by the backend, if any.
+	PlatformVersion string
+
+	// PluginVersion is the version of the backend plugin (e.g.: the PJRT C API version), if any.
+	PluginVersion string
+
+	// NumDevices available to the backend.
+	NumDevices int
+
+	// Options are the backend default options, as "key" -> "value", for information only: they are restored
+	// from Config.
+	Options map[string]string
+}
+
+// Configurable is implemented by backends that can report their configuration, see BackendConfig.
+type Configurable interface {
+	// Config returns the configuration of the backend, that can be given to NewFromConfig to create a new
+	// backend with the same configuration.
+	Config() BackendConfig
+}
+
+// NewFromConfig creates a new Backend from a configuration reported by Configurable.Config -- possibly on
+// another machine, to reproduce an experiment.
+//
+// The backend is created with NewWithConfig using cfg.Name (or the first registered backend if empty) and
+// cfg.Config. If the new backend is Configurable and cfg.Platform is set, it verifies that the platform is the same.
+// The other fields (versions and number of devices) are informational, and may differ across machines.
+//
+// It returns an error if the backend can't be created or if the platform doesn't match.
+func NewFromConfig(cfg BackendConfig) (backend Backend, err error) {
+	if len(registeredConstructors) == 0 {
+		return nil, errors.New(`no registered backends for GoMLX -- maybe import the default XLA one with import _ "github.com/gomlx/gomlx/backends/xla"?`)
+	}
+	name := cfg.Name
+	if name == "" {
+		name = firstRegistered
+	}
+	err = exceptions.TryCatch[error](func() { backend = NewWithConfig(name + ":" + cfg.Config) })
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to create backend %q with configuration %q", name, cfg.Config)
+	}
+	if configurable, ok := backend.(Configurable); ok && cfg.Platform != "" {
+		if platform := configurable.Config().Platform; platform != cfg.Platform {
+			backend.Finalize()
+			return nil, errors.Errorf("backend %q created with configuration %q is on platform %q, but platform %q was expected",
+				name, cfg.Config, platform, cfg.Platform)
+		}
+	}
+	return backend, nil
+}
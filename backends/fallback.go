@@ -0,0 +1,65 @@
+package backends
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gomlx/exceptions"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+var (
+	fallbackMu    sync.Mutex
+	fallbackOrder []string
+)
+
+// SetFallbackOrder sets the platforms New tries, in order, until one is successfully created -- e.g.:
+// SetFallbackOrder([]string{"cuda", "cpu"}) makes New fall back to the CPU if the GPU plugin fails to load,
+// instead of panicking.
+//
+// Each entry is a platform of the first registered backend (usually "xla"), as in NewWithPlatform, or a full
+// "<backend_name>:<backend_configuration>" configuration. If a configuration is given by GOMLX_BACKEND or
+// DefaultConfig, it is tried first.
+//
+// An empty (or nil) order disables the fallback, which is the default.
+func SetFallbackOrder(platforms []string) {
+	fallbackMu.Lock()
+	defer fallbackMu.Unlock()
+	fallbackOrder = slices.Clone(platforms)
+}
+
+// getFallbackOrder returns a copy of the fallback order set with SetFallbackOrder.
+func getFallbackOrder() []string {
+	fallbackMu.Lock()
+	defer fallbackMu.Unlock()
+	return slices.Clone(fallbackOrder)
+}
+
+// newWithFallback tries to create a backend with each configuration in order, and returns the first created.
+// The options are appended to each configuration. It panics with all the errors if none can be created.
+func newWithFallback(configs []string, options []Option) Backend {
+	if len(registeredConstructors) == 0 {
+		exceptions.Panicf(`no registered backends for GoMLX -- maybe import the default XLA one with import _ "github.com/gomlx/gomlx/backends/xla"?`)
+	}
+	var errs []string
+	for _, config := range configs {
+		if !strings.Contains(config, ":") {
+			config = firstRegistered + ":" + config
+		}
+		config = appendOptions(config, options)
+		var backend Backend
+		err := exceptions.TryCatch[error](func() { backend = NewWithConfig(config) })
+		if err == nil {
+			klog.Infof("backends.New(): selected backend configuration %q", config)
+			return backend
+		}
+		klog.Warningf("backends.New(): failed to create backend with configuration %q, trying the next one: %v",
+			config, err)
+		errs = append(errs, errors.WithMessagef(err, "configuration %q", config).Error())
+	}
+	exceptions.Panicf("backends.New() failed to create a backend with any of the configurations %q:\n\t%s",
+		configs, strings.Join(errs, "\n\t"))
+	return nil
+}
@@ -0,0 +1,58 @@
+package backends
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Option configures the creation of a Backend by New.
+//
+// The options are given to the backend as "key=value" options appended (separated by commas) to the backend
+// configuration -- e.g.: New(WithMemoryFraction(0.5)) with GOMLX_BACKEND="xla:cuda" creates the backend with the
+// configuration "xla:cuda,memory_fraction=0.5". For the "xla" backend they are passed to the creation of the PJRT
+// client.
+type Option func(options *newOptions)
+
+// newOptions holds the options given to New.
+type newOptions struct {
+	// keyValues are the options in the "key=value" format, in the order given.
+	keyValues []string
+}
+
+// WithMemoryFraction sets the fraction (in (0, 1]) of the accelerator memory the backend preallocates (or is
+// limited to), e.g.: to co-locate multiple jobs on one GPU.
+//
+// For the "xla" backend it sets the PJRT client option "memory_fraction", which is only supported by the CUDA (GPU)
+// plugin: the CPU plugin ignores it.
+func WithMemoryFraction(fraction float64) Option {
+	return func(options *newOptions) {
+		options.keyValues = append(options.keyValues, fmt.Sprintf("memory_fraction=%g", fraction))
+	}
+}
+
+// WithPreallocate sets whether the backend preallocates the accelerator memory when created (see
+// WithMemoryFraction), or allocates it on demand.
+//
+// For the "xla" backend it sets the PJRT client option "preallocate", which is only supported by the CUDA (GPU)
+// plugin: the CPU plugin ignores it.
+func WithPreallocate(preallocate bool) Option {
+	return func(options *newOptions) {
+		options.keyValues = append(options.keyValues, fmt.Sprintf("preallocate=%v", preallocate))
+	}
+}
+
+// appendOptions appends to the configuration (formatted as "<backend_name>:<backend_configuration>") the
+// options, as ",key=value" options of the backend configuration.
+func appendOptions(config string, options []Option) string {
+	if len(options) == 0 {
+		return config
+	}
+	var parsed newOptions
+	for _, option := range options {
+		option(&parsed)
+	}
+	if len(parsed.keyValues) == 0 {
+		return config
+	}
+	return config + "," + strings.Join(parsed.keyValues, ",")
+}
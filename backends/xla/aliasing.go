@@ -0,0 +1,111 @@
+package xla
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gopjrt/pjrt"
+	"github.com/gomlx/gopjrt/protos/hlo"
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+// inputOutputAlias is an alias recorded with Builder.SetInputOutputAlias.
+type inputOutputAlias struct {
+	paramIndex, outputIndex int
+}
+
+// SetInputOutputAlias records that the output outputIndex (in the order given to Compile) should reuse the buffer
+// of the parameter paramIndex, which must have the same shape. It must be called before Compile.
+//
+// The alias is passed to the XLA compiler, and when the corresponding input is donated to Execute, its buffer is
+// updated in place -- e.g.: to update the exponential moving average of some weights without a new allocation.
+// If the input is not donated, PJRT copies it first, so the results are the same.
+//
+// See Executable.OutputDonationMap.
+func (b *Builder) SetInputOutputAlias(paramIndex, outputIndex int) {
+	if paramIndex < 0 || paramIndex >= len(b.parameterShapes) {
+		exceptions.Panicf("backend %q, computation %q: SetInputOutputAlias paramIndex %d out-of-bounds, there are %d parameters",
+			BackendName, b.name, paramIndex, len(b.parameterShapes))
+	}
+	if outputIndex < 0 {
+		exceptions.Panicf("backend %q, computation %q: SetInputOutputAlias invalid outputIndex %d", BackendName, b.name, outputIndex)
+	}
+	for _, alias := range b.aliases {
+		if alias.paramIndex == paramIndex || alias.outputIndex == outputIndex {
+			exceptions.Panicf("backend %q, computation %q: SetInputOutputAlias(%d, %d) conflicts with the alias of parameter #%d to output #%d",
+				BackendName, b.name, paramIndex, outputIndex, alias.paramIndex, alias.outputIndex)
+		}
+	}
+	b.aliases = append(b.aliases, inputOutputAlias{paramIndex: paramIndex, outputIndex: outputIndex})
+}
+
+// checkAliases verifies the aliases recorded with SetInputOutputAlias against the outputs being compiled, and returns
+// the map of output index to parameter index.
+func (b *Builder) checkAliases(xOutputs []*xlabuilder.Op) map[int]int {
+	if len(b.aliases) == 0 {
+		return nil
+	}
+	donationMap := make(map[int]int, len(b.aliases))
+	for _, alias := range b.aliases {
+		if alias.outputIndex >= len(xOutputs) {
+			exceptions.Panicf("backend %q, computation %q: parameter #%d aliased to output #%d, but there are only %d outputs",
+				BackendName, b.name, alias.paramIndex, alias.outputIndex, len(xOutputs))
+		}
+		outputShape := xshapeToShape(xOutputs[alias.outputIndex].Shape)
+		paramShape := b.parameterShapes[alias.paramIndex]
+		if !outputShape.Equal(paramShape) {
+			exceptions.Panicf("backend %q, computation %q: parameter #%d (%q) of shape %s aliased to output #%d of different shape %s",
+				BackendName, b.name, alias.paramIndex, b.parameterNames[alias.paramIndex], paramShape, alias.outputIndex, outputShape)
+		}
+		donationMap[alias.outputIndex] = alias.paramIndex
+	}
+	return donationMap
+}
+
+// compileWithAliases compiles the computation with the aliases recorded with SetInputOutputAlias.
+//
+// xlabuilder doesn't support aliasing, so they are set directly in the HloModuleProto, before giving it to PJRT.
+func (b *Builder) compileWithAliases(comp *xlabuilder.XlaComputation, numOutputs int) (*pjrt.LoadedExecutable, error) {
+	serialized := comp.SerializedHLO()
+	defer serialized.Free()
+	var module hlo.HloModuleProto
+	if err := proto.Unmarshal(serialized.Bytes(), &module); err != nil {
+		return nil, errors.Wrapf(err, "backend %q: failed to parse HLO of computation %q to set input/output aliases", BackendName, b.name)
+	}
+	aliasProto := &hlo.HloInputOutputAliasProto{}
+	for _, alias := range b.aliases {
+		var outputShapeIndex []int64
+		if numOutputs > 1 {
+			// Outputs are tupled, see Compile.
+			outputShapeIndex = []int64{int64(alias.outputIndex)}
+		}
+		aliasProto.Entries = append(aliasProto.Entries, &hlo.HloInputOutputAliasProto_AliasEntryProto{
+			OutputShapeIndex: outputShapeIndex,
+			ParameterNumber:  int64(alias.paramIndex),
+			Kind:             hlo.Kind_MAY_ALIAS,
+		})
+	}
+	module.InputOutputAlias = aliasProto
+	program, err := proto.Marshal(&module)
+	if err != nil {
+		return nil, errors.Wrapf(err, "backend %q: failed to serialize HLO of computation %q with input/output aliases", BackendName, b.name)
+	}
+	compileConfig, err := b.backend.newCompileConfig()
+	if err != nil {
+		return nil, err
+	}
+	return compileConfig.WithHLO(program).Done()
+}
+
+// OutputDonationMap returns a map of output index to the index of the parameter whose buffer it reuses, as set
+// with Builder.SetInputOutputAlias. It returns nil if there are no aliases.
+func (e *Executable) OutputDonationMap() map[int]int {
+	if len(e.outputDonationMap) == 0 {
+		return nil
+	}
+	donationMap := make(map[int]int, len(e.outputDonationMap))
+	for output, param := range e.outputDonationMap {
+		donationMap[output] = param
+	}
+	return donationMap
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/gomlx/exceptions"
 	"github.com/gomlx/gomlx/backends"
 	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gomlx/types/xslices"
 	"github.com/gomlx/gopjrt/dtypes"
 	"github.com/gomlx/gopjrt/pjrt"
 	"github.com/pkg/errors"
@@ -27,6 +28,24 @@ type Backend struct {
 
 	supressLogging   bool
 	hasSharedBuffers bool
+
+	// inFlight tracks the work in flight, see Synchronize.
+	inFlight workTracker
+
+	// executables compiled by this backend and not yet finalized, see Finalize.
+	executables executablesRegistry
+
+	// nanGuard enables checking outputs for NaN/Inf values, see WithNaNGuard.
+	nanGuard bool
+
+	// deterministicReductions enables the XLA deterministic ops flag, see WithDeterministicReductions.
+	deterministicReductions bool
+
+	// clientOptions are the PJRT client options given as "key=value" plugin options, see NewWithOptions.
+	clientOptions map[string]string
+
+	// bufferTracker tracks the live buffers, if enabled, see WithBufferTracking.
+	bufferTracker *bufferTracker
 }
 
 // AssertValid will panic if the backend is not valid: if it's nil or has already been finalized.
@@ -57,10 +76,13 @@ func (backend *Backend) NumDevices() backends.DeviceNum {
 }
 
 // Finalize releases all the associated resources immediately, and makes the backend invalid.
+//
+// Executables compiled by the backend and not yet finalized are finalized, and a warning with their count is logged.
 func (backend *Backend) Finalize() {
 	if backend.plugin == nil {
 		return
 	}
+	backend.finalizeExecutables()
 	if backend.client != nil {
 		err := backend.client.Destroy()
 		if err != nil {
@@ -76,6 +98,10 @@ func (backend *Backend) Finalize() {
 func castToPJRT(buffer backends.Buffer) *pjrt.Buffer {
 	pb, ok := buffer.(*pjrt.Buffer)
 	if !ok {
+		if tuple, isTuple := buffer.(*TupleBuffer); isTuple {
+			exceptions.Panicf("backend %q: buffer given is a *TupleBuffer with %d elements, use its elements "+
+				"(TupleBuffer.Element or TupleBuffer.Elements) instead", BackendName, tuple.Len())
+		}
 		exceptions.Panicf("buffer given is not a %q backend (pjrt) buffer", BackendName)
 	}
 	return pb
@@ -85,7 +111,15 @@ func castToPJRT(buffer backends.Buffer) *pjrt.Buffer {
 // freed immediately.
 func (backend *Backend) BufferFinalize(buffer backends.Buffer) {
 	backend.AssertValid()
+	if tuple, ok := buffer.(*TupleBuffer); ok {
+		for _, element := range tuple.elements {
+			backend.BufferFinalize(element)
+		}
+		tuple.elements = nil
+		return
+	}
 	buf := castToPJRT(buffer)
+	backend.untrackBuffer(buf)
 	err := buf.Destroy()
 	if err != nil {
 		panic(errors.WithMessagef(err, "backend %q: BufferFinalize", BackendName))
@@ -95,6 +129,9 @@ func (backend *Backend) BufferFinalize(buffer backends.Buffer) {
 // BufferShape returns the shape for the buffer.
 func (backend *Backend) BufferShape(buffer backends.Buffer) shapes.Shape {
 	backend.AssertValid()
+	if tuple, ok := buffer.(*TupleBuffer); ok {
+		return shapes.MakeTuple(xslices.Map(tuple.elements, backend.BufferShape))
+	}
 	pBuffer := castToPJRT(buffer)
 	dtype, err := pBuffer.DType()
 	if err != nil {
@@ -174,6 +211,7 @@ func (backend *Backend) BufferFromFlatData(deviceNum backends.DeviceNum, flat an
 	if err != nil {
 		panic(errors.WithMessagef(err, "backend %q: BuffferFromFlatData", BackendName))
 	}
+	backend.trackBuffer(buffer)
 	return buffer
 }
 
@@ -198,6 +236,7 @@ func (backend *Backend) NewSharedBuffer(deviceNum backends.DeviceNum, shape shap
 	if err != nil {
 		panic(err)
 	}
+	backend.trackBuffer(buffer)
 	return
 }
 
@@ -0,0 +1,38 @@
+package xla
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+	"time"
+)
+
+// Benchmark executes the computation iterations times back-to-back, reusing the same inputs (not donated), and
+// returns the total duration and the duration per iteration.
+//
+// It measures the device throughput of the compiled computation: the outputs are kept on device and freed
+// after each iteration, there are no host transfers, and the backend is synchronized only once at the end. It
+// doesn't measure end-to-end time (e.g.: transferring inputs or outputs, or the overhead of graph.Exec).
+//
+// One warm-up execution is done before the measured iterations, and isn't included in the durations.
+func (e *Executable) Benchmark(inputs []backends.Buffer, iterations int) (totalDuration time.Duration, perIter time.Duration) {
+	e.AssertValid()
+	if iterations <= 0 {
+		exceptions.Panicf("backend %q: Benchmark %q requires iterations > 0, got %d", BackendName, e.name, iterations)
+	}
+	run := func() {
+		for _, output := range e.executeOutputs(inputs, nil) {
+			e.backend.BufferFinalize(output)
+		}
+	}
+	run()
+	start := time.Now()
+	for range iterations {
+		run()
+	}
+	if err := e.backend.Synchronize(); err != nil {
+		panic(err)
+	}
+	totalDuration = time.Since(start)
+	perIter = totalDuration / time.Duration(iterations)
+	return
+}
@@ -0,0 +1,172 @@
+package xla
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+	"github.com/gomlx/gomlx/types/shapes"
+	"slices"
+	"sync"
+)
+
+// BufferPool recycles device buffers of one fixed shape, to avoid the cost of allocating and freeing them
+// repeatedly -- e.g.: in steady-state inference, where inputs of the same shape are fed again and again.
+//
+// Buffers returned by Get can be filled directly with Backend.BufferData, if the backend supports shared buffers
+// (see Backend.HasSharedBuffers), and later returned to the pool with Put.
+//
+// Notice that PJRT always allocates new buffers for the outputs of a computation, unless the output is aliased
+// to a donated input. See Executable.ExecutePooled on how to use the pool to provide the storage of outputs.
+//
+// It is safe for concurrent use.
+type BufferPool struct {
+	backend  *Backend
+	shape    shapes.Shape
+	capacity int
+
+	mu   sync.Mutex
+	free []backends.Buffer
+}
+
+// NewBufferPool creates a BufferPool for buffers of the given shape, on the default device (0).
+// The capacity is the maximum number of free buffers held by the pool: buffers Put into a full pool are
+// finalized immediately.
+func (backend *Backend) NewBufferPool(shape shapes.Shape, capacity int) *BufferPool {
+	backend.AssertValid()
+	if capacity <= 0 {
+		exceptions.Panicf("backend %q: NewBufferPool requires capacity > 0, got %d", BackendName, capacity)
+	}
+	if !shape.Ok() || shape.IsTuple() {
+		exceptions.Panicf("backend %q: NewBufferPool requires a valid non-tuple shape, got %s", BackendName, shape)
+	}
+	return &BufferPool{
+		backend:  backend,
+		shape:    shape,
+		capacity: capacity,
+		free:     make([]backends.Buffer, 0, capacity),
+	}
+}
+
+// Shape of the buffers held by the pool.
+func (pool *BufferPool) Shape() shapes.Shape {
+	return pool.shape
+}
+
+// Len returns the number of free buffers currently held by the pool.
+func (pool *BufferPool) Len() int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	return len(pool.free)
+}
+
+// Get returns a free buffer from the pool, or allocates a new one if the pool is empty.
+//
+// The contents of a recycled buffer are whatever was left there by its previous use.
+func (pool *BufferPool) Get() backends.Buffer {
+	pool.mu.Lock()
+	if n := len(pool.free); n > 0 {
+		buffer := pool.free[n-1]
+		pool.free[n-1] = nil
+		pool.free = pool.free[:n-1]
+		pool.mu.Unlock()
+		return buffer
+	}
+	pool.mu.Unlock()
+	return pool.allocate()
+}
+
+// allocate a new buffer with the pool's shape.
+func (pool *BufferPool) allocate() backends.Buffer {
+	return pool.backend.NewBuffer(pool.shape, 0)
+}
+
+// Put returns the buffer to the pool, so it can be reused by a later Get.
+// If the pool is already at capacity the buffer is finalized instead.
+//
+// It panics if the buffer shape doesn't match the pool's shape.
+func (pool *BufferPool) Put(buffer backends.Buffer) {
+	shape := pool.backend.BufferShape(buffer)
+	if !shape.Equal(pool.shape) {
+		exceptions.Panicf("backend %q: BufferPool.Put() given buffer shaped %s, but pool holds buffers shaped %s",
+			BackendName, shape, pool.shape)
+	}
+	pool.mu.Lock()
+	if len(pool.free) < pool.capacity {
+		pool.free = append(pool.free, buffer)
+		pool.mu.Unlock()
+		return
+	}
+	pool.mu.Unlock()
+	pool.backend.BufferFinalize(buffer)
+}
+
+// Finalize frees all the buffers held by the pool. The pool can still be used afterward.
+func (pool *BufferPool) Finalize() {
+	pool.mu.Lock()
+	free := pool.free
+	pool.free = make([]backends.Buffer, 0, pool.capacity)
+	pool.mu.Unlock()
+	for _, buffer := range free {
+		pool.backend.BufferFinalize(buffer)
+	}
+}
+
+// ExecutePooled executes the computation like Execute, but drawing the storage of the outputs from the pool.
+//
+// Since PJRT can only write outputs into the buffers of donated inputs, this works through input/output aliasing
+// (see Builder.SetInputOutputAlias): for each parameter aliased to an output, the corresponding entry in inputs
+// can be left nil, in which case ExecutePooled fills it with a buffer taken from the pool with BufferPool.Get
+// and donates it, and the output is written in place. The value of such a parameter is whatever was left in the
+// recycled buffer, so the computation should only use it as the storage of the output.
+//
+// Nil inputs are only accepted for parameters aliased to an output shaped like the pool's buffers.
+// Outputs not aliased to a pooled input are allocated by PJRT, as usual.
+//
+// Ownership contract: the buffers taken from the pool are consumed by the execution and must not be used by the
+// caller. The returned outputs are owned by the caller, as with Execute: when done with them, the caller should
+// return those matching the pool's shape with BufferPool.Put -- so the next call reuses them without a new
+// allocation -- and free the others with Backend.BufferFinalize. A buffer must not be used after it is returned
+// to the pool.
+func (e *Executable) ExecutePooled(pool *BufferPool, inputs []backends.Buffer, donate []bool) []backends.Buffer {
+	e.AssertValid()
+	if pool.backend != e.backend {
+		exceptions.Panicf("backend %q: ExecutePooled %q given a BufferPool from a different backend", BackendName, e.name)
+	}
+	if len(inputs) != len(e.parameterShapes) {
+		exceptions.Panicf("backend %q: wrong number of parameters to ExecutePooled %q: %d given, %d expected:\n%s",
+			BackendName, e.name, len(inputs), len(e.parameterShapes), e.parametersTable())
+	}
+	if len(donate) > 0 && len(donate) != len(e.parameterShapes) {
+		exceptions.Panicf("backend %q: wrong number of donate values to ExecutePooled %q: %d given, nil or %d expected",
+			BackendName, e.name, len(donate), len(e.parameterShapes))
+	}
+	aliasedParams := make(map[int]bool, len(e.outputDonationMap))
+	for _, paramIdx := range e.outputDonationMap {
+		aliasedParams[paramIdx] = true
+	}
+	var pooledInputs []backends.Buffer
+	var pooledDonate []bool
+	for paramIdx, input := range inputs {
+		if input != nil {
+			continue
+		}
+		if !aliasedParams[paramIdx] {
+			exceptions.Panicf("backend %q: ExecutePooled %q given nil input for parameter #%d (%q), which is not aliased to an output",
+				BackendName, e.name, paramIdx, e.parameterNames[paramIdx])
+		}
+		if !e.parameterShapes[paramIdx].Equal(pool.shape) {
+			exceptions.Panicf("backend %q: ExecutePooled %q given nil input for parameter #%d (%q) shaped %s, but pool holds buffers shaped %s",
+				BackendName, e.name, paramIdx, e.parameterNames[paramIdx], e.parameterShapes[paramIdx], pool.shape)
+		}
+		if pooledInputs == nil {
+			pooledInputs = slices.Clone(inputs)
+			pooledDonate = make([]bool, len(inputs))
+			copy(pooledDonate, donate)
+		}
+		pooledInputs[paramIdx] = pool.Get()
+		pooledDonate[paramIdx] = true
+	}
+	if pooledInputs == nil {
+		return e.Execute(inputs, donate)
+	}
+	return e.Execute(pooledInputs, pooledDonate)
+}
@@ -0,0 +1,153 @@
+package xla
+
+import (
+	"github.com/gomlx/gomlx/backends"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestBufferPool(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 2, 3)
+	pool := backend.NewBufferPool(shape, 2)
+	defer pool.Finalize()
+
+	buf := pool.Get()
+	require.NotNil(t, buf)
+	assert.True(t, backend.BufferShape(buf).Equal(shape))
+	assert.Equal(t, 0, pool.Len())
+
+	// Get after Put returns the recycled buffer.
+	pool.Put(buf)
+	assert.Equal(t, 1, pool.Len())
+	buf2 := pool.Get()
+	assert.Same(t, buf, buf2)
+	assert.Equal(t, 0, pool.Len())
+
+	// Recycled buffer can be reused as an input.
+	builder := backend.Builder("buffer_pool")
+	x := builder.Parameter("x", shape)
+	exec := builder.Compile(builder.Add(x, x))
+	defer exec.Finalize()
+	outputs := exec.Execute([]backends.Buffer{buf2}, nil)
+	assert.True(t, backend.BufferShape(outputs[0]).Equal(shape))
+	backend.BufferFinalize(outputs[0])
+
+	// Put into a full pool finalizes the buffer.
+	pool.Put(buf2)
+	pool.Put(pool.allocate())
+	pool.Put(pool.allocate())
+	assert.Equal(t, 2, pool.Len())
+
+	// Wrong shape panics.
+	wrong := backend.BufferFromFlatData(0, []float32{1, 2}, shapes.Make(dtypes.Float32, 2))
+	require.Panics(t, func() { pool.Put(wrong) })
+	backend.BufferFinalize(wrong)
+}
+
+func BenchmarkBufferPool(b *testing.B) {
+	backend := New(*flagPlugin).(*Backend)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 256, 256)
+
+	b.Run("Pooled", func(b *testing.B) {
+		pool := backend.NewBufferPool(shape, 4)
+		defer pool.Finalize()
+		for range b.N {
+			pool.Put(pool.Get())
+		}
+	})
+
+	b.Run("Unpooled", func(b *testing.B) {
+		for range b.N {
+			backend.BufferFinalize(allocateUnpooled(backend, shape))
+		}
+	})
+}
+
+// allocateUnpooled allocates a buffer the same way BufferPool does, without recycling it.
+func allocateUnpooled(backend *Backend, shape shapes.Shape) backends.Buffer {
+	return (&BufferPool{backend: backend, shape: shape}).allocate()
+}
+
+// buildPooledExec compiles 2*x, with its output aliased to a second "out" parameter, used only as storage.
+func buildPooledExec(backend *Backend, shape shapes.Shape, aliased bool) *Executable {
+	builder := backend.Builder("execute_pooled").(*Builder)
+	x := builder.Parameter("x", shape)
+	builder.Parameter("out", shape)
+	if aliased {
+		builder.SetInputOutputAlias(1, 0)
+	}
+	return builder.Compile(builder.Add(x, x)).(*Executable)
+}
+
+func TestExecutePooled(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 3)
+	pool := backend.NewBufferPool(shape, 2)
+	defer pool.Finalize()
+	exec := buildPooledExec(backend, shape, true)
+	defer exec.Finalize()
+
+	x := backend.BufferFromFlatData(0, []float32{1, 2, 3}, shape)
+	defer backend.BufferFinalize(x)
+	for range 3 {
+		outputs := exec.ExecutePooled(pool, []backends.Buffer{x, nil}, nil)
+		require.Len(t, outputs, 1)
+		assert.Equal(t, 0, pool.Len())
+		got := make([]float32, 3)
+		backend.BufferToFlatData(outputs[0], got)
+		assert.Equal(t, []float32{2, 4, 6}, got)
+		pool.Put(outputs[0])
+		assert.Equal(t, 1, pool.Len())
+	}
+
+	// Nil input for a parameter not aliased to an output panics.
+	require.Panics(t, func() { exec.ExecutePooled(pool, []backends.Buffer{nil, x}, nil) })
+
+	// Nil input for a parameter of a shape different from the pool's panics.
+	otherPool := backend.NewBufferPool(shapes.Make(dtypes.Float32, 4), 1)
+	defer otherPool.Finalize()
+	require.Panics(t, func() { exec.ExecutePooled(otherPool, []backends.Buffer{x, nil}, nil) })
+}
+
+func BenchmarkExecutePooled(b *testing.B) {
+	backend := New(*flagPlugin).(*Backend)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 256, 256)
+	x := allocateUnpooled(backend, shape)
+	defer backend.BufferFinalize(x)
+
+	b.Run("Pooled", func(b *testing.B) {
+		pool := backend.NewBufferPool(shape, 4)
+		defer pool.Finalize()
+		exec := buildPooledExec(backend, shape, true)
+		defer exec.Finalize()
+		inputs := []backends.Buffer{x, nil}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for range b.N {
+			outputs := exec.ExecutePooled(pool, inputs, nil)
+			pool.Put(outputs[0])
+		}
+	})
+
+	b.Run("Execute", func(b *testing.B) {
+		exec := buildPooledExec(backend, shape, false)
+		defer exec.Finalize()
+		out := allocateUnpooled(backend, shape)
+		defer backend.BufferFinalize(out)
+		inputs := []backends.Buffer{x, out}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for range b.N {
+			outputs := exec.Execute(inputs, nil)
+			backend.BufferFinalize(outputs[0])
+		}
+	})
+}
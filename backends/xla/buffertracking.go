@@ -0,0 +1,153 @@
+package xla
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/gomlx/gomlx/backends"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/pjrt"
+	"github.com/pkg/errors"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// BufferInfo describes a live buffer created by a backend with buffer tracking enabled, see
+// Backend.WithBufferTracking.
+type BufferInfo struct {
+	// ID is a sequential number of the buffers created by the backend, starting at 1.
+	ID uint64
+
+	// Shape of the buffer.
+	Shape shapes.Shape
+
+	// Created is the time of the creation of the buffer.
+	Created time.Time
+
+	// Stack is the formatted stack trace of the creation of the buffer.
+	Stack string
+}
+
+// bufferTracker keeps the BufferInfo of the live buffers created by a backend.
+//
+// The buffers are keyed by the address of the pjrt.Buffer, so the tracking doesn't keep them alive: buffers
+// that are only freed by the garbage collector remain tracked -- until the address is reused by a new buffer.
+type bufferTracker struct {
+	mu     sync.Mutex
+	lastID uint64
+	live   map[uintptr]*BufferInfo
+}
+
+// WithBufferTracking enables or disables the tracking of the buffers created by the backend afterward: transfers
+// from the host, new (and shared) buffers and the outputs of Executable.Execute. A buffer is tracked until it is
+// freed with BufferFinalize. See LiveBuffers and CheckLeaks.
+//
+// It's a debugging aid to find leaked buffers in long runs, and it's off by default, since it captures the stack
+// trace of the creation of every buffer. It can also be enabled with the "buffer_tracking" backend option, e.g.:
+// GOMLX_BACKEND="xla:cpu,buffer_tracking".
+//
+// Disabling it discards the buffers tracked so far. It returns the backend itself, to allow cascading calls.
+func (backend *Backend) WithBufferTracking(enabled bool) *Backend {
+	if !enabled {
+		backend.bufferTracker = nil
+	} else if backend.bufferTracker == nil {
+		backend.bufferTracker = &bufferTracker{live: make(map[uintptr]*BufferInfo)}
+	}
+	return backend
+}
+
+// trackBuffer records the creation of buffer, if buffer tracking is enabled.
+func (backend *Backend) trackBuffer(buffer backends.Buffer) {
+	tracker := backend.bufferTracker
+	if tracker == nil {
+		return
+	}
+	pBuffer, ok := buffer.(*pjrt.Buffer)
+	if !ok {
+		return
+	}
+	info := &BufferInfo{
+		Shape:   backend.BufferShape(buffer),
+		Created: time.Now(),
+		Stack:   creationStack(3),
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.lastID++
+	info.ID = tracker.lastID
+	tracker.live[uintptr(unsafe.Pointer(pBuffer))] = info
+}
+
+// untrackBuffer removes the buffer from the tracked buffers, if buffer tracking is enabled.
+func (backend *Backend) untrackBuffer(buffer *pjrt.Buffer) {
+	tracker := backend.bufferTracker
+	if tracker == nil {
+		return
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	delete(tracker.live, uintptr(unsafe.Pointer(buffer)))
+}
+
+// creationStack returns the formatted stack trace of the caller, skipping the given number of frames
+// (runtime.Callers and creationStack included).
+func creationStack(skip int) string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var sb strings.Builder
+	for {
+		frame, more := frames.Next()
+		_, _ = fmt.Fprintf(&sb, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// LiveBuffers returns the information of the buffers created by the backend and not yet freed with
+// BufferFinalize, ordered by creation. It returns nil if buffer tracking is not enabled, see WithBufferTracking.
+func (backend *Backend) LiveBuffers() []BufferInfo {
+	tracker := backend.bufferTracker
+	if tracker == nil {
+		return nil
+	}
+	tracker.mu.Lock()
+	infos := make([]BufferInfo, 0, len(tracker.live))
+	for _, info := range tracker.live {
+		infos = append(infos, *info)
+	}
+	tracker.mu.Unlock()
+	slices.SortFunc(infos, func(a, b BufferInfo) int { return cmp.Compare(a.ID, b.ID) })
+	return infos
+}
+
+// CheckLeaks returns an error listing the live buffers (see LiveBuffers) created more than maxAge ago, with their
+// creation stack traces, or nil if there are none.
+//
+// It returns an error if buffer tracking is not enabled, see WithBufferTracking.
+func (backend *Backend) CheckLeaks(maxAge time.Duration) error {
+	if backend.bufferTracker == nil {
+		return errors.Errorf("backend %q: CheckLeaks requires buffer tracking, see Backend.WithBufferTracking", BackendName)
+	}
+	now := time.Now()
+	var leaks []BufferInfo
+	for _, info := range backend.LiveBuffers() {
+		if now.Sub(info.Created) > maxAge {
+			leaks = append(leaks, info)
+		}
+	}
+	if len(leaks) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	for _, info := range leaks {
+		_, _ = fmt.Fprintf(&sb, "\n* buffer #%d (shape %s) created %s ago at:\n%s",
+			info.ID, info.Shape, now.Sub(info.Created).Round(time.Millisecond), info.Stack)
+	}
+	return errors.Errorf("backend %q: %d buffer(s) live for more than %s:%s", BackendName, len(leaks), maxAge, sb.String())
+}
@@ -19,6 +19,12 @@ type Builder struct {
 
 	parameterNames  []string
 	parameterShapes []shapes.Shape
+
+	// aliases set with SetInputOutputAlias.
+	aliases []inputOutputAlias
+
+	// histograms added with AddHistogramProbe.
+	histograms []histogramProbe
 }
 
 // Builder creates a new builder used to define a new computation.
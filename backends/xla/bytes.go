@@ -0,0 +1,46 @@
+package xla
+
+import (
+	"github.com/gomlx/gomlx/backends"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/pkg/errors"
+	"unsafe"
+)
+
+// ErrUnsupportedDType is returned (wrapped) when a computation or a buffer has a dtype not supported by the backend.
+//
+// Notice XLA has no string dtype: strings (e.g.: tokenized text) should be represented as Uint8 byte tensors,
+// which can be transferred to the host with Backend.BufferToBytes.
+var ErrUnsupportedDType = errors.New("dtype not supported")
+
+// checkOutputDTypes panics with an ErrUnsupportedDType if any of the outputs has a dtype not supported.
+func (b *Builder) checkOutputDTypes(outputShapes []shapes.Shape) {
+	for ii, shape := range outputShapes {
+		if !shape.DType.IsSupported() {
+			panic(errors.Wrapf(ErrUnsupportedDType, "backend %q, computation %q: output #%d has dtype %s",
+				BackendName, b.name, ii, shape.DType))
+		}
+	}
+}
+
+// BufferToBytes transfers a byte buffer (dtype Uint8 or Int8) to the host, and returns its contents as a []byte.
+//
+// It returns an error wrapping ErrUnsupportedDType if the buffer is not of a byte dtype.
+func (backend *Backend) BufferToBytes(buffer backends.Buffer) ([]byte, error) {
+	shape := backend.BufferShape(buffer)
+	if shape.DType != dtypes.Uint8 && shape.DType != dtypes.Int8 {
+		return nil, errors.Wrapf(ErrUnsupportedDType, "backend %q: BufferToBytes requires a Uint8 or Int8 buffer, got shape %s",
+			BackendName, shape)
+	}
+	data := make([]byte, shape.Size())
+	if len(data) == 0 {
+		return data, nil
+	}
+	if shape.DType == dtypes.Uint8 {
+		backend.BufferToFlatData(buffer, data)
+	} else {
+		backend.BufferToFlatData(buffer, unsafe.Slice((*int8)(unsafe.Pointer(&data[0])), len(data)))
+	}
+	return data, nil
+}
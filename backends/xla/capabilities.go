@@ -0,0 +1,70 @@
+package xla
+
+import (
+	"github.com/gomlx/gopjrt/dtypes"
+	"slices"
+	"strings"
+)
+
+// BackendCapabilities reports features supported by a Backend, so callers can choose dtypes and strategies
+// before compiling a graph -- as opposed to having PJRT reject it.
+type BackendCapabilities struct {
+	// Platform reported by the PJRT plugin, e.g.: "cpu" or "cuda".
+	Platform string
+
+	// NumDevices addressable by the backend.
+	NumDevices int
+
+	// DTypes supported by the backend.
+	DTypes []dtypes.DType
+
+	// Donation indicates whether input buffers can be donated to an execution, see backends.Executable.Execute.
+	Donation bool
+
+	// InputOutputAliasing indicates whether the outputs of a computation can be compiled to reuse the memory of
+	// the inputs, see Builder.SetInputOutputAlias.
+	InputOutputAliasing bool
+
+	// SharedBuffers indicates whether the backend supports buffers whose memory is shared with the host,
+	// see Backend.NewSharedBuffer.
+	SharedBuffers bool
+}
+
+// SupportsDType returns whether the dtype is listed as supported.
+func (c BackendCapabilities) SupportsDType(dtype dtypes.DType) bool {
+	return slices.Contains(c.DTypes, dtype)
+}
+
+// MultiDevice returns whether more than one device is available.
+func (c BackendCapabilities) MultiDevice() bool {
+	return c.NumDevices > 1
+}
+
+// unsupportedDTypesPerPlatform lists the dtypes known not to be supported by some PJRT platforms.
+var unsupportedDTypesPerPlatform = map[string][]dtypes.DType{
+	"tpu": {dtypes.Float64, dtypes.Complex128},
+}
+
+// Capabilities returns the features supported by the backend.
+//
+// The supported dtypes are the ones handled by GoMLX and PJRT, minus the ones known not to be supported by the
+// platform (e.g.: TPUs don't support Float64).
+func (backend *Backend) Capabilities() BackendCapabilities {
+	backend.AssertValid()
+	platform := strings.ToLower(backend.client.Platform())
+	unsupported := unsupportedDTypesPerPlatform[platform]
+	var supportedDTypes []dtypes.DType
+	for dtype := dtypes.Bool; dtype <= dtypes.Complex128; dtype++ {
+		if dtype.IsSupported() && !slices.Contains(unsupported, dtype) {
+			supportedDTypes = append(supportedDTypes, dtype)
+		}
+	}
+	return BackendCapabilities{
+		Platform:            platform,
+		NumDevices:          int(backend.NumDevices()),
+		DTypes:              supportedDTypes,
+		Donation:            true,
+		InputOutputAliasing: true,
+		SharedBuffers:       backend.HasSharedBuffers(),
+	}
+}
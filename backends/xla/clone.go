@@ -0,0 +1,24 @@
+package xla
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Clone returns a new handle to the same compiled executable, that can be finalized independently: the underlying
+// PJRT executable is shared, with reference counting, and it's only freed when the last handle is finalized.
+//
+// Execute is safe to call concurrently on the same handle, so Clone is only needed to decouple the lifecycle
+// of the users of the executable -- e.g.: goroutines that finalize their handle when done, without breaking
+// the others.
+//
+// The clone is also finalized by Backend.Finalize, if not finalized before. It returns an error if the executable
+// was already finalized.
+func (e *Executable) Clone() (*Executable, error) {
+	if e == nil || e.exec == nil || e.backend == nil {
+		return nil, errors.Errorf("backend %q: cannot Clone executable, it is nil or already finalized", BackendName)
+	}
+	clone := *e
+	clone.refs.Add(1)
+	e.backend.executables.add(&clone)
+	return &clone, nil
+}
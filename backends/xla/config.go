@@ -0,0 +1,61 @@
+package xla
+
+import (
+	"fmt"
+	"github.com/gomlx/gomlx/backends"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Config returns the configuration of the backend, including its default options and the PJRT client options given
+// as "key=value" plugin options (e.g.: "memory_fraction"), so it can be recorded and later restored with
+// backends.NewFromConfig.
+//
+// It implements backends.Configurable.
+func (backend *Backend) Config() backends.BackendConfig {
+	backend.AssertValid()
+	major, minor := backend.plugin.Version()
+	options := map[string]string{
+		"shared_buffers":           fmt.Sprintf("%v", backend.hasSharedBuffers),
+		"supress_logging":          fmt.Sprintf("%v", backend.supressLogging),
+		"nan_guard":                fmt.Sprintf("%v", backend.nanGuard),
+		"deterministic_reductions": fmt.Sprintf("%v", backend.deterministicReductions),
+		"buffer_tracking":          fmt.Sprintf("%v", backend.bufferTracker != nil),
+	}
+
+	// Backend configuration string: options are always set explicitly, so the defaults of the machine restoring
+	// the configuration don't matter.
+	parts := []string{backend.pluginName}
+	if backend.hasSharedBuffers {
+		parts = append(parts, "shared_buffers")
+	} else {
+		parts = append(parts, "noshared_buffers")
+	}
+	if backend.supressLogging {
+		parts = append(parts, "supress_logging")
+	}
+	if backend.nanGuard {
+		parts = append(parts, "nan_guard")
+	}
+	if backend.deterministicReductions {
+		parts = append(parts, "deterministic_reductions")
+	}
+	if backend.bufferTracker != nil {
+		parts = append(parts, "buffer_tracking")
+	}
+	for _, key := range slices.Sorted(maps.Keys(backend.clientOptions)) {
+		value := backend.clientOptions[key]
+		options[key] = value
+		parts = append(parts, key+"="+value)
+	}
+	return backends.BackendConfig{
+		Name:            BackendName,
+		Config:          strings.Join(parts, ","),
+		Platform:        strings.ToLower(backend.client.Platform()),
+		PlatformVersion: backend.client.PlatformVersion(),
+		PluginVersion:   fmt.Sprintf("%d.%d", major, minor),
+		NumDevices:      int(backend.NumDevices()),
+		Options:         options,
+	}
+}
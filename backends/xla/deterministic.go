@@ -0,0 +1,85 @@
+package xla
+
+import (
+	"github.com/gomlx/gopjrt/pjrt"
+	xlaprotos "github.com/gomlx/gopjrt/protos/xla"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/prototext"
+	"os"
+	"sync"
+)
+
+// WithDeterministicReductions enables or disables deterministic reductions (and other deterministic ops) for all
+// computations compiled by the backend afterward: the results of the same computation with the same inputs are
+// then bit-identical run-to-run, as required by regression tests.
+//
+// It sets the XLA flag xla_gpu_deterministic_ops (which implies xla_gpu_exclude_nondeterministic_ops): it only
+// affects GPUs, since the XLA CPU reductions are already deterministic. It may slow down reductions, scatters and
+// other ops that otherwise use atomic operations, and it disables the autotuning of convolutions and dot products,
+// so it's off by default. It can also be enabled with the "deterministic_reductions" backend option, e.g.:
+// GOMLX_BACKEND="xla:cuda,deterministic_reductions".
+//
+// The flag is merged with the XLA debug options given in the environment variable pjrt.EnvXlaDebugOptions, if set.
+//
+// Notice gopjrt only takes the XLA debug options from pjrt.EnvXlaDebugOptions, so when this is enabled the
+// backend sets the environment variable -- a process-wide side effect -- while creating the configuration of each
+// compilation, and restores it right after. The change is serialized with the other backends of this package,
+// but any other code reading the environment concurrently (e.g.: gopjrt clients used directly) may see the
+// modified value.
+//
+// It returns the backend itself, to allow cascading calls.
+func (backend *Backend) WithDeterministicReductions(enabled bool) *Backend {
+	backend.deterministicReductions = enabled
+	return backend
+}
+
+// compileEnvMu serializes the creation of pjrt.CompileConfig objects, since gopjrt only takes the XLA debug options
+// from the environment variable pjrt.EnvXlaDebugOptions, read when the configuration is created, and it has to be
+// temporarily changed for backends with deterministic reductions.
+var compileEnvMu sync.Mutex
+
+// newCompileConfig returns a new compilation configuration for the backend's client, with the XLA debug options
+// required by the backend's options.
+func (backend *Backend) newCompileConfig() (*pjrt.CompileConfig, error) {
+	compileEnvMu.Lock()
+	defer compileEnvMu.Unlock()
+	if !backend.deterministicReductions {
+		return backend.client.Compile(), nil
+	}
+
+	// Merge the deterministic ops flag with the debug options set by the user, if any.
+	userOptions, hasUserOptions := os.LookupEnv(pjrt.EnvXlaDebugOptions)
+	options, err := deterministicDebugOptions(userOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Setenv(pjrt.EnvXlaDebugOptions, options); err != nil {
+		return nil, errors.Wrapf(err, "backend %q: failed to set $%s", BackendName, pjrt.EnvXlaDebugOptions)
+	}
+	defer func() {
+		if hasUserOptions {
+			_ = os.Setenv(pjrt.EnvXlaDebugOptions, userOptions)
+		} else {
+			_ = os.Unsetenv(pjrt.EnvXlaDebugOptions)
+		}
+	}()
+	return backend.client.Compile(), nil
+}
+
+// deterministicDebugOptions returns the XLA debug options in userOptions (in protobuf text format, it can be empty)
+// with the deterministic ops flag set, in protobuf text format.
+func deterministicDebugOptions(userOptions string) (string, error) {
+	debugOptions := &xlaprotos.DebugOptions{}
+	if userOptions != "" {
+		if err := prototext.Unmarshal([]byte(userOptions), debugOptions); err != nil {
+			return "", errors.Wrapf(err, "backend %q: failed to parse XLA debug options from $%s=%q",
+				BackendName, pjrt.EnvXlaDebugOptions, userOptions)
+		}
+	}
+	debugOptions.XlaGpuDeterministicOps = true
+	options, err := prototext.Marshal(debugOptions)
+	if err != nil {
+		return "", errors.Wrapf(err, "backend %q: failed to serialize XLA debug options", BackendName)
+	}
+	return string(options), nil
+}
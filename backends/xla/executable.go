@@ -1,6 +1,7 @@
 package xla
 
 import (
+	"fmt"
 	"github.com/gomlx/exceptions"
 	"github.com/gomlx/gomlx/backends"
 	"github.com/gomlx/gomlx/types/shapes"
@@ -9,6 +10,9 @@ import (
 	"github.com/gomlx/gopjrt/xlabuilder"
 	"github.com/pkg/errors"
 	"k8s.io/klog/v2"
+	"slices"
+	"strings"
+	"sync/atomic"
 )
 
 // Executable implements backends.Executable for XLA/PJRT github.com/gomlx/gopjrt
@@ -19,9 +23,36 @@ type Executable struct {
 	parameterNames  []string
 	parameterShapes []shapes.Shape
 	outputShapes    []shapes.Shape
+
+	// outputDonationMap maps output index to the parameter index it aliases, see Builder.SetInputOutputAlias.
+	outputDonationMap map[int]int
+
+	// probeShapes are the shapes of the extra outputs set with Builder.CompileWithProbes.
+	probeShapes []shapes.Shape
+
+	// histogramEdges are the bin edges of the histograms added with Builder.AddHistogramProbe, which are
+	// output after the probes.
+	histogramEdges [][]float64
+
+	// computation compiled, kept for OptimizationReport.
+	computation *xlabuilder.XlaComputation
+
+	// tupled is set by Builder.CompileTupled.
+	tupled bool
+
+	// refs counts the handles (the original and its clones) sharing exec and computation, see Clone.
+	refs *atomic.Int32
 }
 
 func (b *Builder) Compile(outputs ...backends.Op) backends.Executable {
+	if len(b.histograms) > 0 {
+		return b.CompileWithProbes(nil, outputs...)
+	}
+	return b.compile(outputs...)
+}
+
+// compile implements Compile: all outputs given are outputs of the computation.
+func (b *Builder) compile(outputs ...backends.Op) backends.Executable {
 	if len(outputs) == 0 {
 		exceptions.Panicf("backend %q, computation %q: you must have at least one output to a computation", BackendName, b.name)
 	}
@@ -31,6 +62,8 @@ func (b *Builder) Compile(outputs ...backends.Op) backends.Executable {
 		xOutputs[ii] = castToXlaOp(output)
 		outputShapes[ii] = xshapeToShape(xOutputs[ii].Shape)
 	}
+	b.checkOutputDTypes(outputShapes)
+	outputDonationMap := b.checkAliases(xOutputs)
 
 	// If there are more than 1 outputs, use a tuple output -- PJRT un-tuples them during execution..
 	tupleOutput := xOutputs[0]
@@ -46,24 +79,86 @@ func (b *Builder) Compile(outputs ...backends.Op) backends.Executable {
 		panic(errors.WithMessagef(err, "backend %q: failed to build HLO from computation %q", BackendName, b.name))
 	}
 	var exec *pjrt.LoadedExecutable
+	compileFn := func() {
+		if len(b.aliases) > 0 {
+			exec, err = b.compileWithAliases(comp, len(xOutputs))
+		} else {
+			var compileConfig *pjrt.CompileConfig
+			compileConfig, err = b.backend.newCompileConfig()
+			if err == nil {
+				exec, err = compileConfig.WithComputation(comp).Done()
+			}
+		}
+	}
 	if b.backend.supressLogging {
-		pjrt.SuppressAbseilLoggingHack(func() {
-			exec, err = b.backend.client.Compile().WithComputation(comp).Done()
-		})
+		pjrt.SuppressAbseilLoggingHack(compileFn)
 	} else {
-		exec, err = b.backend.client.Compile().WithComputation(comp).Done()
+		compileFn()
 	}
 	if err != nil {
 		panic(errors.WithMessagef(err, "backend %q: failed to compile computation %q", BackendName, b.name))
 	}
-	return &Executable{
+	e := &Executable{
 		backend:         b.backend,
 		exec:            exec,
 		name:            b.name,
 		parameterNames:  b.parameterNames,
 		parameterShapes: b.parameterShapes,
 		outputShapes:    outputShapes,
+
+		outputDonationMap: outputDonationMap,
+		computation:       comp,
+		refs:              &atomic.Int32{},
 	}
+	e.refs.Store(1)
+	b.backend.executables.add(e)
+	return e
+}
+
+// CompileExpecting is like Compile, but first verifies that the shape of each output matches exactly the
+// corresponding expected shape, and panics with a message pointing to the offending output otherwise.
+//
+// It allows catching graph-construction mistakes at compile time, instead of at the first Execute.
+func (b *Builder) CompileExpecting(expected []shapes.Shape, outputs ...backends.Op) backends.Executable {
+	if len(expected) != len(outputs) {
+		exceptions.Panicf("backend %q, computation %q: CompileExpecting given %d expected shapes, but there are %d outputs",
+			BackendName, b.name, len(expected), len(outputs))
+	}
+	for ii, output := range outputs {
+		outputShape := xshapeToShape(castToXlaOp(output).Shape)
+		if !outputShape.Equal(expected[ii]) {
+			exceptions.Panicf("backend %q, computation %q: output #%d has shape %s, but shape %s was expected",
+				BackendName, b.name, ii, outputShape, expected[ii])
+		}
+	}
+	return b.Compile(outputs...)
+}
+
+// CompileSubset compiles only the outputs selected by indices, in the order given by indices. The parts of the
+// graph only used by the outputs not selected are pruned by the compiler (dead-code elimination), so they are
+// never computed.
+//
+// This is useful when a large shared graph has an expensive auxiliary output that is not always needed.
+// The returned executable's Outputs() only lists the selected outputs.
+func (b *Builder) CompileSubset(indices []int, outputs ...backends.Op) backends.Executable {
+	if len(indices) == 0 {
+		exceptions.Panicf("backend %q, computation %q: CompileSubset requires at least one output index", BackendName, b.name)
+	}
+	selected := make([]backends.Op, len(indices))
+	seen := make(map[int]bool, len(indices))
+	for ii, idx := range indices {
+		if idx < 0 || idx >= len(outputs) {
+			exceptions.Panicf("backend %q, computation %q: CompileSubset index #%d is %d, but there are only %d outputs",
+				BackendName, b.name, ii, idx, len(outputs))
+		}
+		if seen[idx] {
+			exceptions.Panicf("backend %q, computation %q: CompileSubset index %d selected more than once",
+				BackendName, b.name, idx)
+		}
+		seen[idx] = true
+		selected[ii] = outputs[idx]
+	}
+	return b.Compile(selected...)
 }
 
 // AssertValid panics if the backend or the executable are not ok -- e.g.: if they have been finalized or the builder
@@ -76,19 +171,33 @@ func (e *Executable) AssertValid() {
 }
 
 // Finalize immediately frees resources associated to the executable.
+//
+// If the executable has clones (see Clone), only this handle is finalized, and the underlying PJRT executable is
+// freed when the last handle is finalized.
 func (e *Executable) Finalize() {
 	if e == nil || e.exec == nil || e.backend == nil {
 		return
 	}
-	err := e.exec.Destroy()
-	if err != nil {
-		klog.Warningf("Error while destroying executable %q on backend %q: %+v", e.name, BackendName, err)
+	e.backend.executables.remove(e)
+	lastRef := e.refs == nil || e.refs.Add(-1) == 0
+	if lastRef {
+		err := e.exec.Destroy()
+		if err != nil {
+			klog.Warningf("Error while destroying executable %q on backend %q: %+v", e.name, BackendName, err)
+		}
+		if e.computation != nil {
+			e.computation.Destroy()
+		}
 	}
 	e.exec = nil
 	e.backend = nil
 	e.parameterNames = nil
 	e.parameterShapes = nil
 	e.outputShapes = nil
+	e.probeShapes = nil
+	e.histogramEdges = nil
+	e.outputDonationMap = nil
+	e.computation = nil
 }
 
 // Inputs returns the list of parameters names and shapes, in order created by the Builder.Parameter calls.
@@ -97,20 +206,82 @@ func (e *Executable) Inputs() (names []string, inputShapes []shapes.Shape) {
 }
 
 // Outputs returns the list of the shapes of the outputs of the computation, in order given to the Builder.Compile call.
+//
+// If the executable was compiled with Builder.CompileTupled, it returns the one tuple shape.
 func (e *Executable) Outputs() (outputShapes []shapes.Shape) {
+	if e.tupled {
+		return []shapes.Shape{shapes.MakeTuple(e.outputShapes)}
+	}
 	return e.outputShapes
 }
 
+// ParameterBytes returns the number of bytes occupied by each parameter (dtype size × number of elements), in the
+// order returned by Inputs. It's derived from the shapes, and it doesn't allocate any buffer.
+func (e *Executable) ParameterBytes() []int64 {
+	return shapesBytes(e.parameterShapes)
+}
+
+// OutputBytes returns the number of bytes occupied by each output (dtype size × number of elements), in the
+// order given to the Builder.Compile call. It's derived from the shapes, and it doesn't allocate any buffer.
+//
+// If the executable was compiled with Builder.CompileTupled, it returns the sizes of the elements of the tuple.
+func (e *Executable) OutputBytes() []int64 {
+	return shapesBytes(e.outputShapes)
+}
+
+// shapesBytes returns the memory used by each of the shapes.
+func shapesBytes(shapesList []shapes.Shape) []int64 {
+	sizes := make([]int64, len(shapesList))
+	for ii, shape := range shapesList {
+		sizes[ii] = int64(shape.Memory())
+	}
+	return sizes
+}
+
 // Execute the executable on the default device (0). The number and shapes of the inputs must match those returned by Inputs.
+//
+// It's safe to call Execute concurrently from multiple goroutines on the same executable, but not concurrently
+// with Finalize: use Clone to give each goroutine its own handle, that can be finalized independently.
+//
+// If the executable was compiled with probes (see Builder.CompileWithProbes) or histogram probes
+// (see Builder.AddHistogramProbe), they are discarded.
+//
+// If the executable was compiled with Builder.CompileTupled, it returns one *TupleBuffer with all the outputs.
 func (e *Executable) Execute(inputs []backends.Buffer, donate []bool) []backends.Buffer {
+	outputs := e.executeOutputs(inputs, donate)
+	if e.tupled {
+		return []backends.Buffer{&TupleBuffer{elements: outputs}}
+	}
+	return outputs
+}
+
+// executeOutputs implements Execute, and returns the outputs (not tupled), discarding probes and histograms.
+func (e *Executable) executeOutputs(inputs []backends.Buffer, donate []bool) []backends.Buffer {
+	outputs := e.execute(inputs, donate)
+	numOutputs := len(e.outputShapes)
+	if len(outputs) == numOutputs {
+		return outputs
+	}
+	for _, probe := range outputs[numOutputs:] {
+		e.backend.BufferFinalize(probe)
+	}
+	return outputs[:numOutputs]
+}
+
+// execute implements Execute, and returns all the outputs of the computation, including the probes and histograms.
+func (e *Executable) execute(inputs []backends.Buffer, donate []bool) []backends.Buffer {
 	e.AssertValid()
 	if len(inputs) != len(e.parameterShapes) {
-		exceptions.Panicf("backend %q: wrong number of parameters to Execute %q: %d given, %d expected", BackendName, e.name, len(inputs), len(e.parameterShapes))
+		exceptions.Panicf("backend %q: wrong number of parameters to Execute %q: %d given, %d expected:\n%s",
+			BackendName, e.name, len(inputs), len(e.parameterShapes), e.parametersTable())
 	}
 	if len(donate) > 0 && len(donate) != len(e.parameterShapes) {
 		exceptions.Panicf("backend %q: wrong number of donate values to Execute %q: %d given, nil or %d expected", BackendName, e.name, len(donate), len(e.parameterShapes))
 	}
 	pInputs := xslices.Map(inputs, castToPJRT)
+	e.checkInputs(pInputs)
+	e.backend.inFlight.begin()
+	defer e.backend.inFlight.end()
 	var pOutputs []*pjrt.Buffer
 	var err error
 	if len(donate) == 0 {
@@ -121,5 +292,108 @@ func (e *Executable) Execute(inputs []backends.Buffer, donate []bool) []backends
 	if err != nil {
 		panic(errors.WithMessagef(err, "backend %q: failed to execute computation %q", BackendName, e.name))
 	}
-	return xslices.Map(pOutputs, func(e *pjrt.Buffer) backends.Buffer { return e })
+	outputs := xslices.Map(pOutputs, func(e *pjrt.Buffer) backends.Buffer { return e })
+	for _, output := range outputs {
+		e.backend.trackBuffer(output)
+	}
+	if e.backend.nanGuard {
+		e.checkNaNGuard(outputs)
+	}
+	return outputs
+}
+
+// ExecuteFetch executes the computation like Execute, but only returns the outputs selected by fetch, in the
+// order given. The buffers of the other outputs are freed immediately.
+//
+// Notice that all outputs are still computed: this only saves holding the device memory of the outputs not
+// fetched (and their eventual transfer to the host). To prune the computation of unused outputs, compile
+// only the needed ones with Builder.CompileSubset.
+func (e *Executable) ExecuteFetch(inputs []backends.Buffer, fetch []int, donate []bool) []backends.Buffer {
+	e.AssertValid()
+	numOutputs := len(e.outputShapes)
+	seen := make([]bool, numOutputs)
+	for ii, idx := range fetch {
+		if idx < 0 || idx >= numOutputs {
+			exceptions.Panicf("backend %q: ExecuteFetch %q fetch index #%d is %d, but there are only %d outputs",
+				BackendName, e.name, ii, idx, numOutputs)
+		}
+		if seen[idx] {
+			exceptions.Panicf("backend %q: ExecuteFetch %q output %d fetched more than once", BackendName, e.name, idx)
+		}
+		seen[idx] = true
+	}
+	outputs := e.executeOutputs(inputs, donate)
+	fetched := make([]backends.Buffer, len(fetch))
+	for ii, idx := range fetch {
+		fetched[ii] = outputs[idx]
+	}
+	for idx, output := range outputs {
+		if !seen[idx] {
+			e.backend.BufferFinalize(output)
+		}
+	}
+	return fetched
+}
+
+// parametersTable returns a human-readable table with one line per parameter, "#<index> <name>: <shape>", used
+// in error messages.
+func (e *Executable) parametersTable() string {
+	var sb strings.Builder
+	for ii, name := range e.parameterNames {
+		_, _ = fmt.Fprintf(&sb, "\t#%d %s: %s\n", ii, name, e.parameterShapes[ii])
+	}
+	return sb.String()
+}
+
+// checkInputs verifies that the inputs match exactly the parameters' shapes -- including the dtype: there are no
+// implicit conversions, so for instance an Int8 parameter (e.g.: for quantized models) requires an Int8 buffer.
+func (e *Executable) checkInputs(pInputs []*pjrt.Buffer) {
+	for ii, pInput := range pInputs {
+		paramShape := e.parameterShapes[ii]
+		dtype, err := pInput.DType()
+		if err != nil {
+			panic(errors.WithMessagef(err, "backend %q: failed to get dtype of input #%d to Execute %q", BackendName, ii, e.name))
+		}
+		if dtype != paramShape.DType {
+			exceptions.Panicf("backend %q: input #%d (%q) to Execute %q has dtype %s, but parameter requires dtype %s (shape %s) -- "+
+				"no implicit conversion is done, please convert the input to the expected dtype; parameters:\n%s",
+				BackendName, ii, e.parameterNames[ii], e.name, dtype, paramShape.DType, paramShape, e.parametersTable())
+		}
+		dims, err := pInput.Dimensions()
+		if err != nil {
+			panic(errors.WithMessagef(err, "backend %q: failed to get dimensions of input #%d to Execute %q", BackendName, ii, e.name))
+		}
+		if !slices.Equal(dims, paramShape.Dimensions) {
+			exceptions.Panicf("backend %q: input #%d (%q) to Execute %q has dimensions %v, but parameter requires shape %s; parameters:\n%s",
+				BackendName, ii, e.parameterNames[ii], e.name, dims, paramShape, e.parametersTable())
+		}
+	}
+}
+
+// PrepareInput transfers the host data to the device (0), with the shape expected by the parameter paramIndex of the
+// executable, so it can be used as an input to Execute.
+//
+// The data must be either a flat slice of the parameter's Go type, with the same number of elements as the parameter
+// shape, or, for scalar parameters, a scalar value of the parameter's Go type.
+//
+// The dtype must match exactly the parameter's dtype, including integer dtypes (e.g.: Int8 for quantized
+// models): no implicit conversion is done.
+//
+// It returns an error if paramIndex is out-of-bounds or if the data doesn't match the parameter's dtype or size.
+//
+// See also Backend.NewBufferFromHost, to transfer data to any device with any shape.
+func (e *Executable) PrepareInput(paramIndex int, data any) (backends.Buffer, error) {
+	if e == nil || e.exec == nil || e.backend == nil {
+		return nil, errors.Errorf("backend %q: Executable nil or already finalized", BackendName)
+	}
+	if paramIndex < 0 || paramIndex >= len(e.parameterShapes) {
+		return nil, errors.Errorf("backend %q: PrepareInput for computation %q: paramIndex %d out-of-bounds, there are %d parameters",
+			BackendName, e.name, paramIndex, len(e.parameterShapes))
+	}
+	buffer, err := e.backend.NewBufferFromHost(data, e.parameterShapes[paramIndex], 0)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "backend %q: PrepareInput for parameter %q (#%d) of computation %q",
+			BackendName, e.parameterNames[paramIndex], paramIndex, e.name)
+	}
+	return buffer, nil
 }
@@ -0,0 +1,62 @@
+package xla
+
+import (
+	"k8s.io/klog/v2"
+	"sync"
+)
+
+// executablesRegistry keeps track of the live executables compiled by a Backend, so they can be freed when the
+// Backend is finalized.
+type executablesRegistry struct {
+	mu   sync.Mutex
+	live map[*Executable]struct{}
+}
+
+// add registers a newly compiled executable.
+func (r *executablesRegistry) add(e *Executable) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.live == nil {
+		r.live = make(map[*Executable]struct{})
+	}
+	r.live[e] = struct{}{}
+}
+
+// remove unregisters an executable, usually because it is being finalized.
+func (r *executablesRegistry) remove(e *Executable) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.live, e)
+}
+
+// len returns the number of live executables.
+func (r *executablesRegistry) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.live)
+}
+
+// finalizeAll finalizes all the executables still alive, and returns how many there were.
+func (r *executablesRegistry) finalizeAll() int {
+	r.mu.Lock()
+	live := make([]*Executable, 0, len(r.live))
+	for e := range r.live {
+		live = append(live, e)
+	}
+	r.mu.Unlock()
+
+	// Executable.Finalize removes itself from the registry, so it must be called without holding the lock.
+	for _, e := range live {
+		e.Finalize()
+	}
+	return len(live)
+}
+
+// finalizeExecutables finalizes the executables created by the backend and not yet finalized. It's called
+// by Backend.Finalize.
+func (backend *Backend) finalizeExecutables() {
+	numLive := backend.executables.finalizeAll()
+	if numLive > 0 {
+		klog.Warningf("backend %q: Finalize() freed %d executable(s) that were still alive", BackendName, numLive)
+	}
+}
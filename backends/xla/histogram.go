@@ -0,0 +1,88 @@
+package xla
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gomlx/types/xslices"
+	"github.com/gomlx/gopjrt/dtypes"
+	"slices"
+)
+
+// histogramProbe is a histogram added with Builder.AddHistogramProbe.
+type histogramProbe struct {
+	op       backends.Op
+	binEdges []float64
+}
+
+// AddHistogramProbe adds to the computation the histogram of the values of x, computed on device, as an auxiliary
+// output: use Executable.ExecuteWithHistograms to retrieve it. It returns the index of the histogram, in the
+// order they are added. It must be called before Compile (or CompileWithProbes).
+//
+// The binEdges must be sorted in increasing order, and define len(binEdges)-1 bins. Bins are left-closed:
+// bin i counts the values v with binEdges[i] <= v < binEdges[i+1]. Values outside [binEdges[0], binEdges[-1]),
+// including NaNs, are not counted.
+//
+// x must be of a float or integer dtype. Its values are compared to the bin edges converted to its dtype, and it
+// uses memory proportional to x.Size() * (len(binEdges)-1), so it's meant for monitoring, with few bins.
+func (b *Builder) AddHistogramProbe(x backends.Op, binEdges []float64) int {
+	b.AssertValid()
+	if len(binEdges) < 2 {
+		exceptions.Panicf("backend %q, computation %q: AddHistogramProbe requires at least 2 bin edges, got %d",
+			BackendName, b.name, len(binEdges))
+	}
+	for ii := 1; ii < len(binEdges); ii++ {
+		if binEdges[ii] <= binEdges[ii-1] {
+			exceptions.Panicf("backend %q, computation %q: AddHistogramProbe bin edges must be strictly increasing, got %v",
+				BackendName, b.name, binEdges)
+		}
+	}
+	xShape := b.OpShape(x)
+	dtype := xShape.DType
+	if !dtype.IsFloat() && !dtype.IsInt() {
+		exceptions.Panicf("backend %q, computation %q: AddHistogramProbe requires a float or integer x, got shape %s",
+			BackendName, b.name, xShape)
+	}
+	numValues, numBins := xShape.Size(), len(binEdges)-1
+
+	// Compare every value with the lower and upper edges of every bin: shaped [numValues, numBins].
+	pairsShape := shapes.Make(dtype, numValues, numBins)
+	values := b.BroadcastInDim(b.Reshape(x, numValues), pairsShape, []int{0})
+	edgesConstant := func(edges []float64) backends.Op {
+		edgesOp := b.Constant(edges, len(edges))
+		if dtype != dtypes.Float64 {
+			edgesOp = b.ConvertDType(edgesOp, dtype)
+		}
+		return b.BroadcastInDim(edgesOp, pairsShape, []int{1})
+	}
+	lower, upper := edgesConstant(binEdges[:numBins]), edgesConstant(binEdges[1:])
+	inBin := b.LogicalAnd(b.GreaterOrEqual(values, lower), b.LessThan(values, upper))
+	counts := b.ReduceSum(b.ConvertDType(inBin, dtypes.Int64), 0)
+	b.histograms = append(b.histograms, histogramProbe{op: counts, binEdges: slices.Clone(binEdges)})
+	return len(b.histograms) - 1
+}
+
+// HistogramsBinEdges returns the bin edges of the histograms added with Builder.AddHistogramProbe, in order.
+func (e *Executable) HistogramsBinEdges() [][]float64 {
+	return e.histogramEdges
+}
+
+// ExecuteWithHistograms executes the computation like Execute, and returns also the counts of the histograms
+// added with Builder.AddHistogramProbe (in the order they were added), transferred to the host.
+//
+// The caller owns the returned output buffers, and should finalize them when no longer needed.
+func (e *Executable) ExecuteWithHistograms(inputs []backends.Buffer, donate []bool) (outputs []backends.Buffer, histograms [][]int64) {
+	all := e.execute(inputs, donate)
+	numOutputs, numProbes := len(e.outputShapes), len(e.probeShapes)
+	for _, probe := range all[numOutputs : numOutputs+numProbes] {
+		e.backend.BufferFinalize(probe)
+	}
+	histogramBuffers := all[numOutputs+numProbes:]
+	histograms = xslices.Map(histogramBuffers, func(buffer backends.Buffer) []int64 {
+		counts := make([]int64, e.backend.BufferShape(buffer).Size())
+		e.backend.BufferToFlatData(buffer, counts)
+		e.backend.BufferFinalize(buffer)
+		return counts
+	})
+	return all[:numOutputs:numOutputs], histograms
+}
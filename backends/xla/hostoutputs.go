@@ -0,0 +1,142 @@
+package xla
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"reflect"
+	"sync"
+)
+
+// ExecuteToHost executes the computation like Execute, and transfers the outputs to host values, which are
+// returned as flat slices of the Go type corresponding to each output dtype (e.g.: []float32 for Float32).
+// The shapes of the outputs are given by Outputs. The device buffers of the outputs are freed before returning.
+//
+// The values are always copied to Go-owned slices. For a zero-copy read of the outputs on CPU, see
+// ExecuteToHostViews.
+//
+// It's a convenience for notebooks, examples and tests. For performance-sensitive code, use Execute and keep the
+// outputs on device.
+func (e *Executable) ExecuteToHost(inputs []backends.Buffer, donate []bool) (outputs []any, err error) {
+	var buffers []backends.Buffer
+	err = exceptions.TryCatch[error](func() { buffers = e.executeOutputs(inputs, donate) })
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if freeErr := e.freeHostOutputs(buffers); freeErr != nil && err == nil {
+			err = freeErr
+		}
+	}()
+
+	outputs = make([]any, len(buffers))
+	for ii, buffer := range buffers {
+		shape := e.outputShapes[ii]
+		outputs[ii] = reflect.MakeSlice(reflect.SliceOf(shape.DType.GoType()), shape.Size(), shape.Size()).Interface()
+		if shape.Size() == 0 {
+			continue
+		}
+		err = exceptions.TryCatch[error](func() { e.backend.BufferToFlatData(buffer, outputs[ii]) })
+		if err != nil {
+			return nil, errors.WithMessagef(err, "backend %q: ExecuteToHost %q failed to transfer output #%d", BackendName, e.name, ii)
+		}
+	}
+	return outputs, nil
+}
+
+// ExecuteToHostViews is like ExecuteToHost, but on platforms with shared buffers (CPU, see
+// Backend.HasSharedBuffers) the returned flat slices are zero-copy views into the memory of the output buffers.
+// On other platforms the outputs are transferred, as in ExecuteToHost.
+//
+// The output buffers are kept alive until release is called, which frees them: the views must not be used after
+// that. The caller must call release exactly once when done with the outputs (it's safe to call it more than once),
+// otherwise the buffers are leaked -- the memory of the views is not managed by the Go garbage collector.
+// On platforms without shared buffers release is a no-op. If err is not nil, release is nil.
+func (e *Executable) ExecuteToHostViews(inputs []backends.Buffer, donate []bool) (outputs []any, release func(), err error) {
+	if !e.backend.hasSharedBuffers {
+		outputs, err = e.ExecuteToHost(inputs, donate)
+		if err != nil {
+			return nil, nil, err
+		}
+		return outputs, func() {}, nil
+	}
+	var buffers []backends.Buffer
+	err = exceptions.TryCatch[error](func() { buffers = e.executeOutputs(inputs, donate) })
+	if err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			if freeErr := e.freeHostOutputs(buffers); freeErr != nil {
+				klog.Warningf("%+v", freeErr)
+			}
+		})
+	}
+
+	outputs = make([]any, len(buffers))
+	for ii, buffer := range buffers {
+		shape := e.outputShapes[ii]
+		if shape.Size() == 0 {
+			outputs[ii] = reflect.MakeSlice(reflect.SliceOf(shape.DType.GoType()), 0, 0).Interface()
+			continue
+		}
+		outputs[ii], err = castToPJRT(buffer).Data()
+		if err != nil {
+			release()
+			return nil, nil, errors.WithMessagef(err, "backend %q: ExecuteToHostViews %q failed to access output #%d", BackendName, e.name, ii)
+		}
+	}
+	return outputs, release, nil
+}
+
+// freeHostOutputs frees the output buffers of ExecuteToHost or ExecuteToHostViews, returning the first error.
+func (e *Executable) freeHostOutputs(buffers []backends.Buffer) (err error) {
+	for _, buffer := range buffers {
+		pBuffer := castToPJRT(buffer)
+		e.backend.untrackBuffer(pBuffer)
+		if destroyErr := pBuffer.Destroy(); destroyErr != nil && err == nil {
+			err = errors.WithMessagef(destroyErr, "backend %q: %q failed to free output", BackendName, e.name)
+		}
+	}
+	return err
+}
+
+// ExecuteValues executes the computation like ExecuteToHost, but returns each output as a Go value shaped like the
+// output: a scalar (e.g.: float32) for scalar outputs, a slice for rank-1 outputs (e.g.: []int32), a slice of
+// slices for rank-2 outputs (e.g.: [][]bool), and so on. The device buffers of the outputs are freed.
+//
+// It panics if the execution or the transfers fail. It's a convenience for examples and notebooks.
+func (e *Executable) ExecuteValues(inputs []backends.Buffer, donate []bool) []any {
+	flatOutputs, err := e.ExecuteToHost(inputs, donate)
+	if err != nil {
+		panic(err)
+	}
+	values := make([]any, len(flatOutputs))
+	for ii, flat := range flatOutputs {
+		values[ii] = unflatten(reflect.ValueOf(flat), e.outputShapes[ii].Dimensions).Interface()
+	}
+	return values
+}
+
+// unflatten returns the flat slice as a value with the given dimensions: a scalar if there are no dimensions,
+// otherwise a (multi-dimensional) slice.
+func unflatten(flat reflect.Value, dimensions []int) reflect.Value {
+	if len(dimensions) == 0 {
+		return flat.Index(0)
+	}
+	if len(dimensions) == 1 {
+		return flat
+	}
+	subSize := flat.Len() / max(dimensions[0], 1)
+	subType := flat.Type()
+	for range len(dimensions) - 2 {
+		subType = reflect.SliceOf(subType)
+	}
+	values := reflect.MakeSlice(reflect.SliceOf(subType), dimensions[0], dimensions[0])
+	for ii := range dimensions[0] {
+		values.Index(ii).Set(unflatten(flat.Slice(ii*subSize, (ii+1)*subSize), dimensions[1:]))
+	}
+	return values
+}
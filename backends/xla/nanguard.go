@@ -0,0 +1,89 @@
+package xla
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+	"github.com/gomlx/gopjrt/dtypes/bfloat16"
+	"github.com/x448/float16"
+	"math"
+	"math/cmplx"
+	"reflect"
+)
+
+// WithNaNGuard enables or disables the NaN guard: when enabled, Executable.Execute checks every float
+// (and complex) output for NaN or Inf values, and panics with an error naming the offending output. The outputs
+// are freed before panicking.
+//
+// It's off by default, since it transfers every output to the host to scan it. It's meant for debugging
+// diverging training. It can also be enabled with the "nan_guard" backend option, e.g.: GOMLX_BACKEND="xla:cpu,nan_guard".
+//
+// It returns the backend itself, to allow cascading calls.
+func (backend *Backend) WithNaNGuard(enabled bool) *Backend {
+	backend.nanGuard = enabled
+	return backend
+}
+
+// checkNaNGuard panics if any of the float or complex outputs has a NaN or Inf value.
+func (e *Executable) checkNaNGuard(outputs []backends.Buffer) {
+	for ii, output := range outputs {
+		shape := e.backend.BufferShape(output)
+		if !shape.DType.IsFloat() && !shape.DType.IsComplex() || shape.Size() == 0 {
+			continue
+		}
+		flat := reflect.MakeSlice(reflect.SliceOf(shape.DType.GoType()), shape.Size(), shape.Size()).Interface()
+		e.backend.BufferToFlatData(output, flat)
+		if idx := indexNaNOrInf(flat); idx >= 0 {
+			// Free the outputs, since they are not going to be returned.
+			for _, buffer := range outputs {
+				e.backend.BufferFinalize(buffer)
+			}
+			exceptions.Panicf("backend %q: NaN guard: output #%d (shape %s) of computation %q has NaN or Inf value at flat index %d",
+				BackendName, ii, shape, e.name, idx)
+		}
+	}
+}
+
+// indexNaNOrInf returns the index of the first NaN or Inf value in the flat slice, or -1 if there are none,
+// or if the dtype is not float or complex.
+func indexNaNOrInf(flat any) int {
+	isNotFinite := func(v float64) bool { return math.IsNaN(v) || math.IsInf(v, 0) }
+	switch values := flat.(type) {
+	case []float32:
+		for ii, v := range values {
+			if isNotFinite(float64(v)) {
+				return ii
+			}
+		}
+	case []float64:
+		for ii, v := range values {
+			if isNotFinite(v) {
+				return ii
+			}
+		}
+	case []float16.Float16:
+		for ii, v := range values {
+			if isNotFinite(float64(v.Float32())) {
+				return ii
+			}
+		}
+	case []bfloat16.BFloat16:
+		for ii, v := range values {
+			if isNotFinite(float64(v.Float32())) {
+				return ii
+			}
+		}
+	case []complex64:
+		for ii, v := range values {
+			if cmplx.IsNaN(complex128(v)) || cmplx.IsInf(complex128(v)) {
+				return ii
+			}
+		}
+	case []complex128:
+		for ii, v := range values {
+			if cmplx.IsNaN(v) || cmplx.IsInf(v) {
+				return ii
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,83 @@
+package xla
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/pkg/errors"
+	"reflect"
+)
+
+// NewBuffer allocates a new device buffer with the given shape on the device deviceNum, e.g.: to be used as an
+// input to Executable.Execute.
+//
+// PJRT doesn't offer a way to allocate uninitialized buffers, so the buffer is zero-filled -- with a transfer
+// from the host, or directly in place if the backend supports shared buffers (see Backend.HasSharedBuffers).
+func (backend *Backend) NewBuffer(shape shapes.Shape, deviceNum int) backends.Buffer {
+	backend.AssertValid()
+	if !shape.Ok() || shape.IsTuple() {
+		exceptions.Panicf("backend %q: NewBuffer requires a valid non-tuple shape, got %s", BackendName, shape)
+	}
+	if numDevices := int(backend.NumDevices()); deviceNum < 0 || deviceNum >= numDevices {
+		exceptions.Panicf("backend %q: NewBuffer deviceNum=%d not available, only %d devices are available",
+			BackendName, deviceNum, numDevices)
+	}
+	if backend.HasSharedBuffers() {
+		buffer, _ := backend.NewSharedBuffer(backends.DeviceNum(deviceNum), shape)
+		return buffer
+	}
+	flat := reflect.MakeSlice(reflect.SliceOf(shape.DType.GoType()), shape.Size(), shape.Size())
+	return backend.BufferFromFlatData(backends.DeviceNum(deviceNum), flat.Interface(), shape)
+}
+
+// NewBufferFromHost transfers the host data to a new buffer with the given shape on the device deviceNum.
+//
+// The data must be either a flat slice of the shape's Go type, with the same number of elements as the shape,
+// or, for scalar shapes, a scalar value of the shape's Go type. No implicit dtype conversion is done.
+//
+// It returns an error if the data doesn't match the shape's dtype or size, or if deviceNum is not valid.
+func (backend *Backend) NewBufferFromHost(data any, shape shapes.Shape, deviceNum int) (backends.Buffer, error) {
+	if backend == nil || backend.plugin == nil {
+		return nil, errors.Errorf("backend %q: nil or already finalized", BackendName)
+	}
+	if !shape.Ok() || shape.IsTuple() {
+		return nil, errors.Errorf("backend %q: NewBufferFromHost requires a valid non-tuple shape, got %s", BackendName, shape)
+	}
+	if numDevices := int(backend.NumDevices()); deviceNum < 0 || deviceNum >= numDevices {
+		return nil, errors.Errorf("backend %q: NewBufferFromHost deviceNum=%d not available, only %d devices are available",
+			BackendName, deviceNum, numDevices)
+	}
+	dataV := reflect.ValueOf(data)
+	if !dataV.IsValid() {
+		return nil, errors.Errorf("backend %q: NewBufferFromHost: nil data given", BackendName)
+	}
+	if dataV.Kind() != reflect.Slice {
+		if !shape.IsScalar() {
+			return nil, errors.Errorf("backend %q: NewBufferFromHost: expected a flat slice for shape %s, got %T",
+				BackendName, shape, data)
+		}
+		// Wrap scalar value in a slice of one element.
+		sliceV := reflect.MakeSlice(reflect.SliceOf(dataV.Type()), 1, 1)
+		sliceV.Index(0).Set(dataV)
+		dataV = sliceV
+	}
+	dataDType := dtypes.FromGoType(dataV.Type().Elem())
+	if dataDType != shape.DType {
+		return nil, errors.Errorf("backend %q: NewBufferFromHost: expected dtype %s (shape %s), got data of type %T",
+			BackendName, shape.DType, shape, data)
+	}
+	if dataV.Len() != shape.Size() {
+		return nil, errors.Errorf("backend %q: NewBufferFromHost: shape %s requires %d elements, got %d",
+			BackendName, shape, shape.Size(), dataV.Len())
+	}
+	buffer, err := backend.client.BufferFromHost().
+		FromFlatDataWithDimensions(dataV.Interface(), shape.Dimensions).
+		ToDeviceNum(deviceNum).
+		Done()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "backend %q: NewBufferFromHost with shape %s", BackendName, shape)
+	}
+	backend.trackBuffer(buffer)
+	return buffer, nil
+}
@@ -0,0 +1,77 @@
+package xla
+
+import (
+	"slices"
+
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+)
+
+// ExecuteOptimizerStep executes a training (optimizer) step whose state -- weights, optimizer moments, etc. -- is
+// updated in place, and returns the updated state keyed by the same names.
+//
+// The state buffers are matched to the parameters by name (see Inputs), and they are donated to the execution: the
+// caller must not use them afterward. The otherInputs fill the remaining parameters, in order, and they are not
+// donated. Each state parameter must be aliased to an output with Builder.SetInputOutputAlias, and that output is
+// returned under the parameter name -- see OutputDonationMap.
+//
+// Outputs not aliased to a state parameter (e.g.: the loss) are freed: use Execute with the donate argument
+// if they are needed.
+func (e *Executable) ExecuteOptimizerStep(state map[string]backends.Buffer, otherInputs []backends.Buffer) map[string]backends.Buffer {
+	e.AssertValid()
+	if e.tupled {
+		exceptions.Panicf("backend %q: ExecuteOptimizerStep %q not supported for executables compiled with CompileTupled",
+			BackendName, e.name)
+	}
+	paramToOutput := make(map[int]int, len(e.outputDonationMap))
+	for output, param := range e.outputDonationMap {
+		paramToOutput[param] = output
+	}
+	numParams := len(e.parameterNames)
+	inputs := make([]backends.Buffer, numParams)
+	donate := make([]bool, numParams)
+	numState := 0
+	for ii, name := range e.parameterNames {
+		buffer, found := state[name]
+		if !found {
+			continue
+		}
+		if _, aliased := paramToOutput[ii]; !aliased {
+			exceptions.Panicf("backend %q: ExecuteOptimizerStep %q state %q (parameter #%d) is not aliased to any output, see Builder.SetInputOutputAlias",
+				BackendName, e.name, name, ii)
+		}
+		inputs[ii] = buffer
+		donate[ii] = true
+		numState++
+	}
+	if numState != len(state) {
+		for name := range state {
+			if !slices.Contains(e.parameterNames, name) {
+				exceptions.Panicf("backend %q: ExecuteOptimizerStep %q state %q doesn't match any parameter:\n%s",
+					BackendName, e.name, name, e.parametersTable())
+			}
+		}
+	}
+	if numState+len(otherInputs) != numParams {
+		exceptions.Panicf("backend %q: ExecuteOptimizerStep %q given %d state buffers and %d other inputs, but there are %d parameters:\n%s",
+			BackendName, e.name, numState, len(otherInputs), numParams, e.parametersTable())
+	}
+	nextOther := 0
+	for ii := range inputs {
+		if inputs[ii] == nil {
+			inputs[ii] = otherInputs[nextOther]
+			nextOther++
+		}
+	}
+
+	outputs := e.executeOutputs(inputs, donate)
+	updated := make(map[string]backends.Buffer, numState)
+	for ii, output := range outputs {
+		if param, aliased := e.outputDonationMap[ii]; aliased && donate[param] {
+			updated[e.parameterNames[param]] = output
+			continue
+		}
+		e.backend.BufferFinalize(output)
+	}
+	return updated
+}
@@ -0,0 +1,70 @@
+package xla
+
+import (
+	"github.com/gomlx/gopjrt/protos/hlo"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+// OptReport summarizes the HLO program of an Executable, see Executable.OptimizationReport.
+type OptReport struct {
+	// NumComputations in the module before optimization, including the entry computation and the sub-computations
+	// (e.g.: the reduction functions).
+	NumComputations int
+
+	// NumInstructions in all computations before optimization.
+	NumInstructions int
+
+	// NumConstants is the number of constant instructions before optimization.
+	NumConstants int
+
+	// LargestConstantSize is the number of elements of the largest constant -- large values usually indicate an
+	// accidentally materialized tensor (e.g.: a large one-hot encoding).
+	LargestConstantSize int
+
+	// OpcodeCounts is the number of instructions per opcode (e.g.: "add", "constant") before optimization.
+	OpcodeCounts map[string]int
+
+	// Optimized is whether the statistics of the program after optimization (OptimizedNumInstructions) are
+	// available. It's false if PJRT doesn't expose the optimized program.
+	Optimized bool
+
+	// OptimizedNumInstructions is the number of instructions after optimization, or -1 if not Optimized.
+	OptimizedNumInstructions int
+}
+
+// OptimizationReport returns a summary of the program compiled in the executable, to help debug why a
+// compiled graph is larger than expected.
+//
+// The gopjrt version used doesn't expose the optimized (post-compilation) program, so the report only holds
+// statistics of the program before optimization, with Optimized set to false and OptimizedNumInstructions
+// set to -1. An error means the report couldn't be created.
+func (e *Executable) OptimizationReport() (OptReport, error) {
+	report := OptReport{OptimizedNumInstructions: -1}
+	if e == nil || e.exec == nil || e.computation == nil {
+		return report, errors.Errorf("backend %q: Executable nil or already finalized", BackendName)
+	}
+	serialized := e.computation.SerializedHLO()
+	defer serialized.Free()
+	var module hlo.HloModuleProto
+	if err := proto.Unmarshal(serialized.Bytes(), &module); err != nil {
+		return report, errors.Wrapf(err, "backend %q: failed to parse HLO of computation %q", BackendName, e.name)
+	}
+	report.OpcodeCounts = make(map[string]int)
+	for _, computation := range module.GetComputations() {
+		report.NumComputations++
+		for _, instruction := range computation.GetInstructions() {
+			report.NumInstructions++
+			report.OpcodeCounts[instruction.GetOpcode()]++
+			if instruction.GetOpcode() == "constant" {
+				report.NumConstants++
+				size := 1
+				for _, dim := range instruction.GetShape().GetDimensions() {
+					size *= int(dim)
+				}
+				report.LargestConstantSize = max(report.LargestConstantSize, size)
+			}
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,11 @@
+package xla
+
+// IsPortable returns whether the executable was compiled portably: a portable executable is not bound to a
+// particular device (no device assignment or device-specific layouts), and can be serialized and loaded on any
+// device of the same platform.
+//
+// It always returns true: gopjrt always compiles with CompilePortableExecutable set, and it doesn't offer a way
+// to compile device-specific executables.
+func (e *Executable) IsPortable() bool {
+	return true
+}
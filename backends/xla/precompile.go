@@ -0,0 +1,47 @@
+package xla
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+)
+
+// PendingExecutable is a handle to an executable being compiled in the background, see Backend.PrecompileAsync.
+type PendingExecutable struct {
+	done chan struct{}
+	exec *Executable
+	err  error
+}
+
+// PrecompileAsync builds and compiles a computation in a background goroutine, and returns immediately a handle
+// whose Get method blocks until the executable is ready -- e.g.: to start compiling at startup, without blocking
+// the initialization of a server.
+//
+// The build function is called in the background goroutine with a new Builder for the computation name, and it
+// must return the outputs to compile -- it must not call Builder.Compile itself.
+//
+// The goroutine exits as soon as the compilation finishes (or fails), and it's not possible to cancel it.
+// The executable returned by Get is owned by the caller, like one returned by Builder.Compile: it should be
+// finalized when no longer needed, or it will be freed by Backend.Finalize. The backend must not be finalized
+// while a compilation is pending: call Get first.
+func (backend *Backend) PrecompileAsync(name string, build func(b *Builder) (outputs []backends.Op)) *PendingExecutable {
+	backend.AssertValid()
+	p := &PendingExecutable{done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		p.err = exceptions.TryCatch[error](func() {
+			b := backend.Builder(name).(*Builder)
+			p.exec = b.Compile(build(b)...).(*Executable)
+		})
+	}()
+	return p
+}
+
+// Get blocks until the compilation started by Backend.PrecompileAsync is finished, and returns its executable, or
+// the error that happened while building or compiling it.
+//
+// It can be called any number of times, from any goroutine, and it always returns the same executable: the
+// computation is compiled only once.
+func (p *PendingExecutable) Get() (*Executable, error) {
+	<-p.done
+	return p.exec, p.err
+}
@@ -0,0 +1,146 @@
+package xla
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/pkg/errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Prefetcher stages host data onto the device in a background goroutine, ahead of its use, so that the
+// host-to-device transfer of the next inputs overlaps with the execution of the current ones.
+//
+// Create it with Backend.NewPrefetcher, and consume the staged buffers, in order, with Next.
+// Call Close when done, to stop the background goroutine and free any staged buffer not consumed.
+type Prefetcher struct {
+	backend *Backend
+	shape   shapes.Shape
+
+	staged   chan prefetched
+	done     chan struct{}
+	wg       sync.WaitGroup
+	muClosed sync.Mutex
+	closed   bool
+
+	numTransferred atomic.Int64
+}
+
+// prefetched holds one staged buffer or an error.
+type prefetched struct {
+	buffer backends.Buffer
+	err    error
+}
+
+// NewPrefetcher creates a Prefetcher that reads flat slices of host data from source, each one with the values for
+// the given shape, and transfers them to the default device (0) in the background.
+//
+// The depth is the maximum number of buffers staged on the device and not yet consumed by Next (it must be >= 1).
+// It bounds the device memory used by the prefetcher: up to depth+1 buffers of the given shape may be allocated at
+// any time (depth waiting to be consumed, plus one in transfer). A depth of 1 or 2 is usually enough to
+// overlap transfer and execution.
+//
+// The prefetcher finishes when source is closed.
+func (backend *Backend) NewPrefetcher(shape shapes.Shape, depth int, source <-chan any) *Prefetcher {
+	backend.AssertValid()
+	if depth < 1 {
+		exceptions.Panicf("backend %q: NewPrefetcher requires depth >= 1, got %d", BackendName, depth)
+	}
+	p := &Prefetcher{
+		backend: backend,
+		shape:   shape,
+		staged:  make(chan prefetched, depth),
+		done:    make(chan struct{}),
+	}
+	p.wg.Add(1)
+	go p.run(source)
+	return p
+}
+
+// run is the background goroutine that transfers the host data to the device.
+func (p *Prefetcher) run(source <-chan any) {
+	defer p.wg.Done()
+	defer close(p.staged)
+	for {
+		var flat any
+		var ok bool
+		select {
+		case <-p.done:
+			return
+		case flat, ok = <-source:
+			if !ok {
+				return
+			}
+		}
+		if !p.stage(flat) {
+			return
+		}
+	}
+}
+
+// stage transfers flat to the device and sends it to the staged channel. The transfer is tracked by the backend
+// (see Backend.Synchronize).
+//
+// It returns false if the prefetcher was closed in the meantime.
+func (p *Prefetcher) stage(flat any) bool {
+	result := p.transfer(flat)
+	select {
+	case <-p.done:
+		if result.buffer != nil {
+			p.backend.BufferFinalize(result.buffer)
+		}
+		return false
+	case p.staged <- result:
+		return true
+	}
+}
+
+// transfer flat to the device.
+func (p *Prefetcher) transfer(flat any) (result prefetched) {
+	p.backend.inFlight.begin()
+	defer p.backend.inFlight.end()
+	result.err = exceptions.TryCatch[error](func() {
+		result.buffer = p.backend.BufferFromFlatData(0, flat, p.shape)
+	})
+	if result.err != nil {
+		result.err = errors.WithMessagef(result.err, "backend %q: Prefetcher failed to transfer data to device", BackendName)
+	}
+	p.numTransferred.Add(1)
+	return
+}
+
+// Next returns the next staged buffer, waiting for it if not yet available. The buffers are returned in the same
+// order the data was read from source, and the caller owns them.
+//
+// It returns io.EOF when the source has been closed and all the data has been consumed, or an error if the
+// transfer of the data failed.
+func (p *Prefetcher) Next() (backends.Buffer, error) {
+	result, ok := <-p.staged
+	if !ok {
+		return nil, io.EOF
+	}
+	return result.buffer, result.err
+}
+
+// Close stops the prefetcher and frees the buffers staged but not consumed. It doesn't close the source channel.
+// It's safe to call Close more than once.
+func (p *Prefetcher) Close() {
+	p.muClosed.Lock()
+	if p.closed {
+		p.muClosed.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	p.muClosed.Unlock()
+
+	// Drain and free buffers not consumed.
+	for result := range p.staged {
+		if result.buffer != nil {
+			p.backend.BufferFinalize(result.buffer)
+		}
+	}
+	p.wg.Wait()
+}
@@ -0,0 +1,60 @@
+package xla
+
+import (
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func TestPrefetcher(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 3)
+	source := make(chan any)
+	const numBatches = 10
+	go func() {
+		for ii := range numBatches {
+			v := float32(ii)
+			source <- []float32{v, v + 0.5, -v}
+		}
+		close(source)
+	}()
+
+	prefetcher := backend.NewPrefetcher(shape, 2, source)
+	defer prefetcher.Close()
+	for ii := range numBatches {
+		buffer, err := prefetcher.Next()
+		require.NoError(t, err)
+		require.True(t, backend.BufferShape(buffer).Equal(shape))
+		got := make([]float32, 3)
+		backend.BufferToFlatData(buffer, got)
+		v := float32(ii)
+		assert.Equal(t, []float32{v, v + 0.5, -v}, got)
+		backend.BufferFinalize(buffer)
+	}
+	_, err := prefetcher.Next()
+	require.ErrorIs(t, err, io.EOF)
+
+	// Closing a prefetcher with staged buffers not consumed.
+	source = make(chan any, 3)
+	for range 3 {
+		source <- []float32{1, 2, 3}
+	}
+	prefetcher2 := backend.NewPrefetcher(shape, 1, source)
+	buffer, err := prefetcher2.Next()
+	require.NoError(t, err)
+	backend.BufferFinalize(buffer)
+	prefetcher2.Close()
+
+	// Invalid data is reported by Next.
+	source = make(chan any, 1)
+	source <- []float64{1, 2, 3}
+	close(source)
+	prefetcher3 := backend.NewPrefetcher(shape, 1, source)
+	defer prefetcher3.Close()
+	_, err = prefetcher3.Next()
+	require.Error(t, err)
+}
@@ -0,0 +1,50 @@
+package xla
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+	"github.com/gomlx/gomlx/types/shapes"
+	"slices"
+)
+
+// CompileWithProbes is like Compile, but it also includes the probes (any intermediary nodes of the graph) as
+// extra outputs of the computation, so their values can be inspected -- e.g.: to debug where NaNs come from.
+//
+// The returned Executable's Outputs and Execute only include the outputs: use ExecuteWithProbes to also get
+// the values of the probes, and ProbeShapes for their shapes.
+func (b *Builder) CompileWithProbes(probes []backends.Op, outputs ...backends.Op) backends.Executable {
+	if len(outputs) == 0 {
+		exceptions.Panicf("backend %q, computation %q: you must have at least one output to a computation", BackendName, b.name)
+	}
+	allOutputs := append(slices.Clone(outputs), probes...)
+	for _, histogram := range b.histograms {
+		allOutputs = append(allOutputs, histogram.op)
+	}
+	e := b.compile(allOutputs...).(*Executable)
+	numOutputs, numProbes := len(outputs), len(probes)
+	e.probeShapes = e.outputShapes[numOutputs : numOutputs+numProbes : numOutputs+numProbes]
+	e.outputShapes = e.outputShapes[:numOutputs:numOutputs]
+	e.histogramEdges = make([][]float64, len(b.histograms))
+	for ii, histogram := range b.histograms {
+		e.histogramEdges[ii] = histogram.binEdges
+	}
+	return e
+}
+
+// ProbeShapes returns the shapes of the probes given to Builder.CompileWithProbes, in the same order.
+func (e *Executable) ProbeShapes() []shapes.Shape {
+	return e.probeShapes
+}
+
+// ExecuteWithProbes executes the computation like Execute, and returns separately the outputs and the values of the
+// probes given to Builder.CompileWithProbes.
+//
+// The caller owns all returned buffers, and should finalize them when no longer needed.
+func (e *Executable) ExecuteWithProbes(inputs []backends.Buffer, donate []bool) (outputs, probes []backends.Buffer) {
+	all := e.execute(inputs, donate)
+	numOutputs, numProbes := len(e.outputShapes), len(e.probeShapes)
+	for _, histogram := range all[numOutputs+numProbes:] {
+		e.backend.BufferFinalize(histogram)
+	}
+	return all[:numOutputs:numOutputs], all[numOutputs : numOutputs+numProbes : numOutputs+numProbes]
+}
@@ -0,0 +1,69 @@
+package xla
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+	"slices"
+)
+
+// ExecuteScan executes the computation steps times, threading a state through the executions -- e.g.: the hidden
+// state of a recurrent model during inference.
+//
+// The state are the first len(initialState) parameters of the computation, and the corresponding first
+// len(initialState) outputs are the updated state, which must have the same shapes. The remaining parameters
+// are fed, at each step, by perStepInputs[step] (perStepInputs can be nil if there are no other parameters),
+// and the remaining outputs of each step are returned in outputs[step].
+//
+// The state is kept on device between steps, and the intermediary state buffers are donated to the following
+// step, so they can be updated in place -- specially if the state outputs are aliased to the state parameters
+// with Builder.SetInputOutputAlias. The initialState and perStepInputs buffers are not donated, they are still
+// owned by the caller, and all returned buffers are owned by the caller. If steps is 0, finalState is initialState.
+func (e *Executable) ExecuteScan(steps int, initialState []backends.Buffer, perStepInputs [][]backends.Buffer) (
+	finalState []backends.Buffer, outputs [][]backends.Buffer) {
+	e.AssertValid()
+	numState := len(initialState)
+	numInputs := len(e.parameterShapes) - numState
+	if numState > len(e.parameterShapes) || numState > len(e.outputShapes) {
+		exceptions.Panicf("backend %q: ExecuteScan %q given %d state buffers, but computation has %d parameters and %d outputs",
+			BackendName, e.name, numState, len(e.parameterShapes), len(e.outputShapes))
+	}
+	for ii := range numState {
+		if !e.parameterShapes[ii].Equal(e.outputShapes[ii]) {
+			exceptions.Panicf("backend %q: ExecuteScan %q state #%d parameter %q has shape %s, but the corresponding output has shape %s",
+				BackendName, e.name, ii, e.parameterNames[ii], e.parameterShapes[ii], e.outputShapes[ii])
+		}
+	}
+	if steps < 0 {
+		exceptions.Panicf("backend %q: ExecuteScan %q invalid number of steps %d", BackendName, e.name, steps)
+	}
+	if numInputs > 0 || perStepInputs != nil {
+		if len(perStepInputs) != steps {
+			exceptions.Panicf("backend %q: ExecuteScan %q requires inputs for each of the %d steps, got %d",
+				BackendName, e.name, steps, len(perStepInputs))
+		}
+		for step, stepInputs := range perStepInputs {
+			if len(stepInputs) != numInputs {
+				exceptions.Panicf("backend %q: ExecuteScan %q step %d given %d inputs, but %d are required (besides the %d state parameters)",
+					BackendName, e.name, step, len(stepInputs), numInputs, numState)
+			}
+		}
+	}
+
+	state := initialState
+	outputs = make([][]backends.Buffer, steps)
+	donate := make([]bool, len(e.parameterShapes))
+	for step := range steps {
+		inputs := slices.Clone(state)
+		if numInputs > 0 {
+			inputs = append(inputs, perStepInputs[step]...)
+		}
+		// The state is owned by ExecuteScan after the first step.
+		for ii := range numState {
+			donate[ii] = step > 0
+		}
+		stepOutputs := e.executeOutputs(inputs, donate)
+		state = stepOutputs[:numState:numState]
+		outputs[step] = stepOutputs[numState:]
+	}
+	return state, outputs
+}
@@ -0,0 +1,109 @@
+package xla
+
+import (
+	"github.com/gomlx/gopjrt/protos/hlo"
+	"github.com/gomlx/gopjrt/protos/xla_data"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"hash/fnv"
+)
+
+// StructuralHash returns a hash of the computation compiled in the executable, that can be used to key a cache of
+// executables or to detect the recompilation of identical graphs: executables of the same computation have the
+// same hash, even if compiled independently or in different processes (for the same build of GoMLX and gopjrt).
+//
+// It's derived from the HLO program, with the names (of the computation, parameters and instructions), the
+// unique ids, the source code metadata and the layouts removed -- so the layouts chosen for a particular device
+// don't change the hash. Notice the gopjrt version used doesn't expose the optimized (post-compilation) HLO, so
+// the hash is of the program before optimization: computations that only become identical after optimization
+// have different hashes.
+//
+// As any hash, different computations may collide, although it's unlikely.
+func (e *Executable) StructuralHash() uint64 {
+	e.AssertValid()
+	serialized := e.computation.SerializedHLO()
+	defer serialized.Free()
+	var module hlo.HloModuleProto
+	if err := proto.Unmarshal(serialized.Bytes(), &module); err != nil {
+		panic(errors.Wrapf(err, "backend %q: failed to parse HLO of computation %q", BackendName, e.name))
+	}
+	canonicalizeHLO(&module)
+	canonical, err := proto.MarshalOptions{Deterministic: true}.Marshal(&module)
+	if err != nil {
+		panic(errors.Wrapf(err, "backend %q: failed to serialize HLO of computation %q", BackendName, e.name))
+	}
+	hasher := fnv.New64a()
+	_, _ = hasher.Write(canonical)
+	return hasher.Sum64()
+}
+
+// canonicalizeHLO removes from the HLO module everything that doesn't change the computation: names, metadata and
+// layouts. The unique ids are replaced by their positions, in the module for the computations and in their
+// computation for the instructions.
+func canonicalizeHLO(module *hlo.HloModuleProto) {
+	computationIds := make(map[int64]int64, len(module.Computations))
+	for ii, computation := range module.Computations {
+		computationIds[computation.Id] = int64(ii)
+	}
+	module.Name = ""
+	module.Id = 0
+	module.EntryComputationName = ""
+	module.EntryComputationId = computationIds[module.EntryComputationId]
+	module.StackFrameIndex = nil
+	canonicalizeProgramShape(module.HostProgramShape)
+	for ii, computation := range module.Computations {
+		instructionIds := make(map[int64]int64, len(computation.Instructions))
+		for jj, instruction := range computation.Instructions {
+			instructionIds[instruction.Id] = int64(jj)
+		}
+		computation.Name = ""
+		computation.Id = int64(ii)
+		computation.RootId = instructionIds[computation.RootId]
+		canonicalizeProgramShape(computation.ProgramShape)
+		for _, instruction := range computation.Instructions {
+			instruction.Name = ""
+			instruction.Metadata = nil
+			instruction.Id = instructionIds[instruction.Id]
+			remapIds(instruction.OperandIds, instructionIds)
+			remapIds(instruction.ControlPredecessorIds, instructionIds)
+			remapIds(instruction.CalledComputationIds, computationIds)
+			clearLayouts(instruction.Shape)
+			for _, shape := range instruction.OperandShapesWithLayout {
+				clearLayouts(shape)
+			}
+			if instruction.Literal != nil {
+				clearLayouts(instruction.Literal.Shape)
+			}
+		}
+	}
+}
+
+// canonicalizeProgramShape removes the parameter names and layouts of the program shape.
+func canonicalizeProgramShape(programShape *xla_data.ProgramShapeProto) {
+	if programShape == nil {
+		return
+	}
+	programShape.ParameterNames = nil
+	for _, shape := range programShape.Parameters {
+		clearLayouts(shape)
+	}
+	clearLayouts(programShape.Result)
+}
+
+// clearLayouts removes the layout of the shape, and of its tuple elements, recursively.
+func clearLayouts(shape *xla_data.ShapeProto) {
+	if shape == nil {
+		return
+	}
+	shape.Layout = nil
+	for _, element := range shape.TupleShapes {
+		clearLayouts(element)
+	}
+}
+
+// remapIds replaces in place each id by its new value in newIds.
+func remapIds(ids []int64, newIds map[int64]int64) {
+	for ii, id := range ids {
+		ids[ii] = newIds[id]
+	}
+}
@@ -0,0 +1,67 @@
+package xla
+
+import (
+	"github.com/pkg/errors"
+	"sync"
+)
+
+// workTracker counts the operations in flight on a Backend, so Synchronize can wait for them.
+//
+// A sync.WaitGroup can't be used, since new work may start concurrently with a Synchronize.
+type workTracker struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	count int
+}
+
+// begin registers the start of an operation. It must be matched by a call to end.
+func (w *workTracker) begin() {
+	w.mu.Lock()
+	w.count++
+	w.mu.Unlock()
+}
+
+// end registers the end of an operation, and wakes up any waiting Synchronize if there is no more work in flight.
+func (w *workTracker) end() {
+	w.mu.Lock()
+	w.count--
+	if w.count == 0 && w.cond != nil {
+		w.cond.Broadcast()
+	}
+	w.mu.Unlock()
+}
+
+// inFlight returns the number of operations currently in flight.
+func (w *workTracker) inFlight() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// wait until there are no operations in flight.
+func (w *workTracker) wait() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cond == nil {
+		w.cond = sync.NewCond(&w.mu)
+	}
+	for w.count > 0 {
+		w.cond.Wait()
+	}
+}
+
+// Synchronize blocks until all outstanding work started on the backend completes: executions running in other
+// goroutines, and background transfers (e.g.: from a Prefetcher).
+//
+// Notice that each individual PJRT execution and transfer is waited for by the goroutine that issued it, so this
+// is only needed when work is issued concurrently -- e.g.: before measuring end-to-end time, or before finalizing
+// buffers and the backend.
+//
+// It returns an error if the backend has already been finalized.
+func (backend *Backend) Synchronize() error {
+	if backend == nil || backend.plugin == nil {
+		return errors.Errorf("backend %q: Synchronize called on a nil or already finalized backend", BackendName)
+	}
+	backend.inFlight.wait()
+	return nil
+}
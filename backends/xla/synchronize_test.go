@@ -0,0 +1,37 @@
+package xla
+
+import (
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/stretchr/testify/require"
+	"runtime"
+	"testing"
+)
+
+func TestSynchronize(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend)
+	require.NoError(t, backend.Synchronize()) // Nothing in flight.
+
+	// Launch a background transfer with a Prefetcher.
+	shape := shapes.Make(dtypes.Float32, 1024, 1024)
+	source := make(chan any, 1)
+	source <- make([]float32, shape.Size())
+	close(source)
+	prefetcher := backend.NewPrefetcher(shape, 1, source)
+
+	// Wait for the transfer to start (or finish), and then Synchronize must wait for it to complete.
+	for backend.inFlight.inFlight() == 0 && prefetcher.numTransferred.Load() == 0 {
+		runtime.Gosched()
+	}
+	require.NoError(t, backend.Synchronize())
+	require.Equal(t, int64(1), prefetcher.numTransferred.Load())
+	require.Equal(t, 0, backend.inFlight.inFlight())
+
+	buffer, err := prefetcher.Next()
+	require.NoError(t, err)
+	backend.BufferFinalize(buffer)
+	prefetcher.Close()
+
+	backend.Finalize()
+	require.Error(t, backend.Synchronize())
+}
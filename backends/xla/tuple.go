@@ -0,0 +1,50 @@
+package xla
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+)
+
+// TupleBuffer is the single tuple-typed output returned by Execute for executables compiled with
+// Builder.CompileTupled.
+//
+// PJRT always un-tuples the results of a computation, and doesn't expose tuple-typed device buffers, so a TupleBuffer
+// simply holds the buffers of each element of the tuple, still on device. Use Element (or Elements) to index into
+// it, e.g. to feed the elements as inputs to another executable.
+//
+// Backend.BufferShape returns its tuple shape, and Backend.BufferFinalize frees all its elements. Other buffer
+// methods (e.g.: BufferToFlatData) and Executable.Execute don't accept a TupleBuffer, and panic if given one:
+// use its elements instead, e.g.: exec.Execute(tuple.Elements(), nil).
+type TupleBuffer struct {
+	elements []backends.Buffer
+}
+
+// Len returns the number of elements of the tuple.
+func (t *TupleBuffer) Len() int {
+	return len(t.elements)
+}
+
+// Element returns the buffer of the element ii of the tuple. It is still owned by the TupleBuffer: it is freed
+// when the TupleBuffer is finalized.
+func (t *TupleBuffer) Element(ii int) backends.Buffer {
+	if ii < 0 || ii >= len(t.elements) {
+		exceptions.Panicf("backend %q: TupleBuffer.Element(%d) out-of-bounds, tuple has %d elements", BackendName, ii, len(t.elements))
+	}
+	return t.elements[ii]
+}
+
+// Elements returns the buffers of all elements of the tuple, see Element.
+func (t *TupleBuffer) Elements() []backends.Buffer {
+	return t.elements
+}
+
+// CompileTupled is like Compile, but the outputs are kept as one tuple output: Executable.Outputs returns the one
+// tuple shape, and Executable.Execute returns one *TupleBuffer, holding the buffers of each output.
+//
+// It's for interoperation with code expecting one tuple-typed output. Executable.ExecuteFetch, ExecuteScan and
+// ExecuteToHost still operate on the individual outputs.
+func (b *Builder) CompileTupled(outputs ...backends.Op) backends.Executable {
+	e := b.Compile(outputs...).(*Executable)
+	e.tupled = true
+	return e
+}
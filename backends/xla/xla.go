@@ -30,6 +30,15 @@
 // This is enabled by default if the plugin is called "cpu". To force advertising support for this
 // for other PJRTs provide the "shared_buffers" option, e.g.: GOMLX_BACKEND="xla:my_pjrt,shared_buffers".
 // Or to force disabling the support, provide the "noshared_buffers" option.
+//
+// # Other Options:
+//
+//   - "supress_logging": suppresses the PJRT (Abseil) logging during compilation. Always enabled for "cuda".
+//   - "nan_guard": enables the NaN guard, see Backend.WithNaNGuard.
+//   - "deterministic_reductions": enables deterministic reductions, see Backend.WithDeterministicReductions.
+//   - "buffer_tracking": enables the tracking of live buffers, see Backend.WithBufferTracking.
+//   - "<key>=<value>": sets the PJRT client option key, e.g.: "memory_fraction=0.5" or "preallocate=false" for the
+//     CUDA plugin (see backends.WithMemoryFraction and backends.WithPreallocate).
 package xla
 
 //go:generate go run ../../cmd/xla_generator
@@ -42,8 +51,11 @@ import (
 	"github.com/gomlx/gopjrt/pjrt"
 	"github.com/pkg/errors"
 	"k8s.io/klog/v2"
+	"maps"
 	"path"
+	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -81,6 +93,25 @@ func NewWithOptions(pluginName string, options pjrt.NamedValuesMap) *Backend {
 		}
 	}
 
+	// Client options, given as "key=value" plugin options.
+	clientOptions := make(map[string]string)
+	pluginOptions = slices.DeleteFunc(pluginOptions, func(option string) bool {
+		key, value, found := strings.Cut(option, "=")
+		if found {
+			clientOptions[key] = value
+		}
+		return found
+	})
+	if len(clientOptions) > 0 {
+		options = maps.Clone(options)
+		if options == nil {
+			options = make(pjrt.NamedValuesMap, len(clientOptions))
+		}
+		for key, value := range clientOptions {
+			options[key] = parseClientOption(key, value)
+		}
+	}
+
 	plugin, err := pjrt.GetPlugin(pluginName)
 	if err != nil {
 		panic(errors.WithMessagef(err, "backend %q:", BackendName))
@@ -101,7 +132,24 @@ func NewWithOptions(pluginName string, options pjrt.NamedValuesMap) *Backend {
 		plugin:         plugin,
 		client:         client,
 		pluginName:     pluginName,
-		supressLogging: pluginName == "cuda" || slices.Index(pluginOptions, "supress_logging") != -1,
+		supressLogging: pluginName == "cuda",
+		clientOptions:  clientOptions,
+	}
+	if idx := slices.Index(pluginOptions, "supress_logging"); idx != -1 {
+		backend.supressLogging = true
+		pluginOptions = slices.Delete(pluginOptions, idx, idx+1)
+	}
+	if idx := slices.Index(pluginOptions, "nan_guard"); idx != -1 {
+		backend.nanGuard = true
+		pluginOptions = slices.Delete(pluginOptions, idx, idx+1)
+	}
+	if idx := slices.Index(pluginOptions, "deterministic_reductions"); idx != -1 {
+		backend.deterministicReductions = true
+		pluginOptions = slices.Delete(pluginOptions, idx, idx+1)
+	}
+	if idx := slices.Index(pluginOptions, "buffer_tracking"); idx != -1 {
+		backend.WithBufferTracking(true)
+		pluginOptions = slices.Delete(pluginOptions, idx, idx+1)
 	}
 
 	// Support "shared buffers":
@@ -119,6 +167,37 @@ func NewWithOptions(pluginName string, options pjrt.NamedValuesMap) *Backend {
 	return backend
 }
 
+// clientOptionTypes are the types of the known PJRT client options, used to parse them.
+var clientOptionTypes = map[string]reflect.Kind{
+	"memory_fraction": reflect.Float32,
+	"preallocate":     reflect.Bool,
+}
+
+// parseClientOption parses the value of a PJRT client option given as a "key=value" plugin option: if the key is not
+// one of the known options, its type is inferred from the value -- int64, float32, bool or string.
+func parseClientOption(key, value string) any {
+	kind, known := clientOptionTypes[key]
+	if !known || kind == reflect.Int64 {
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	}
+	if !known || kind == reflect.Float32 {
+		if v, err := strconv.ParseFloat(value, 32); err == nil {
+			return float32(v)
+		}
+	}
+	if !known || kind == reflect.Bool {
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	if known {
+		exceptions.Panicf("backend %q: invalid value %q for client option %q, expected a %s", BackendName, value, key, kind)
+	}
+	return value
+}
+
 // SupressLogging during compilation of a graph.
 func (backend *Backend) SupressLogging(supressLogging bool) *Backend {
 	backend.supressLogging = supressLogging
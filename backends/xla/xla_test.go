@@ -3,12 +3,22 @@ package xla
 import (
 	"flag"
 	"fmt"
+	"github.com/gomlx/exceptions"
 	"github.com/gomlx/gomlx/backends"
 	"github.com/gomlx/gomlx/types/shapes"
 	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/pjrt"
+	xlaprotos "github.com/gomlx/gopjrt/protos/xla"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/prototext"
+	"math"
 	"math/rand"
+	"os"
 	"runtime"
+	"sync"
 	"testing"
+	"time"
 )
 
 var flagPlugin = flag.String("plugin", "cpu", "Plugin to use for testing for xla backend")
@@ -62,3 +72,883 @@ func TestRepeatedClients(t *testing.T) {
 		backend.Finalize()
 	}
 }
+
+func TestPrepareInput(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	builder := backend.Builder("prepare_input")
+	x := builder.Parameter("x", shapes.Make(dtypes.Float32, 3))
+	exec := builder.Compile(builder.Mul(x, x)).(*Executable)
+	defer exec.Finalize()
+
+	// Float32 vector with matching shape.
+	bIn, err := exec.PrepareInput(0, []float32{1, 2, 3})
+	require.NoError(t, err)
+	bOuts := exec.Execute([]backends.Buffer{bIn}, nil)
+	out := make([]float32, 3)
+	backend.BufferToFlatData(bOuts[0], out)
+	assert.Equal(t, []float32{1, 4, 9}, out)
+	backend.BufferFinalize(bIn)
+	backend.BufferFinalize(bOuts[0])
+
+	// Shape mismatch.
+	_, err = exec.PrepareInput(0, []float32{1, 2})
+	require.Error(t, err)
+	fmt.Printf("\tExpected error: %v\n", err)
+
+	// DType mismatch.
+	_, err = exec.PrepareInput(0, []float64{1, 2, 3})
+	require.Error(t, err)
+
+	// Invalid parameter index.
+	_, err = exec.PrepareInput(1, []float32{1, 2, 3})
+	require.Error(t, err)
+}
+
+func TestNewBuffer(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 2, 3)
+
+	// Zero-filled buffer.
+	buffer := backend.NewBuffer(shape, 0)
+	require.True(t, backend.BufferShape(buffer).Equal(shape))
+	out := make([]float32, 6)
+	backend.BufferToFlatData(buffer, out)
+	assert.Equal(t, []float32{0, 0, 0, 0, 0, 0}, out)
+	backend.BufferFinalize(buffer)
+	require.Panics(t, func() { backend.NewBuffer(shape, int(backend.NumDevices())) })
+
+	// Host initialized buffer.
+	buffer, err := backend.NewBufferFromHost([]float32{1, 2, 3, 4, 5, 6}, shape, 0)
+	require.NoError(t, err)
+	require.True(t, backend.BufferShape(buffer).Equal(shape))
+	backend.BufferToFlatData(buffer, out)
+	assert.Equal(t, []float32{1, 2, 3, 4, 5, 6}, out)
+	backend.BufferFinalize(buffer)
+
+	// Scalar.
+	buffer, err = backend.NewBufferFromHost(int32(7), shapes.Make(dtypes.Int32), 0)
+	require.NoError(t, err)
+	scalar := make([]int32, 1)
+	backend.BufferToFlatData(buffer, scalar)
+	assert.Equal(t, int32(7), scalar[0])
+	backend.BufferFinalize(buffer)
+
+	// Size, dtype and device mismatches.
+	_, err = backend.NewBufferFromHost([]float32{1, 2}, shape, 0)
+	require.Error(t, err)
+	fmt.Printf("\tExpected error: %v\n", err)
+	_, err = backend.NewBufferFromHost([]float64{1, 2, 3, 4, 5, 6}, shape, 0)
+	require.Error(t, err)
+	_, err = backend.NewBufferFromHost([]float32{1, 2, 3, 4, 5, 6}, shape, -1)
+	require.Error(t, err)
+}
+
+func TestExecuteInt8(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	builder := backend.Builder("int8_add")
+	shape := shapes.Make(dtypes.Int8, 4)
+	x := builder.Parameter("x", shape)
+	y := builder.Parameter("y", shape)
+	exec := builder.Compile(builder.Add(x, y)).(*Executable)
+	defer exec.Finalize()
+
+	bX, err := exec.PrepareInput(0, []int8{1, -2, 3, 100})
+	require.NoError(t, err)
+	bY, err := exec.PrepareInput(1, []int8{1, 2, -3, 20})
+	require.NoError(t, err)
+	bOuts := exec.Execute([]backends.Buffer{bX, bY}, nil)
+	require.True(t, backend.BufferShape(bOuts[0]).Equal(shape))
+	out := make([]int8, 4)
+	backend.BufferToFlatData(bOuts[0], out)
+	assert.Equal(t, []int8{2, 0, 0, 120}, out)
+	backend.BufferFinalize(bOuts[0])
+
+	// Float data for an int8 parameter is rejected, not cast.
+	_, err = exec.PrepareInput(0, []float32{1, 2, 3, 4})
+	require.Error(t, err)
+
+	// Buffer with the wrong dtype is rejected by Execute.
+	bWrong := backend.BufferFromFlatData(0, []int32{1, 2, 3, 4}, shapes.Make(dtypes.Int32, 4))
+	require.Panics(t, func() { exec.Execute([]backends.Buffer{bX, bWrong}, nil) })
+	backend.BufferFinalize(bWrong)
+	backend.BufferFinalize(bX)
+	backend.BufferFinalize(bY)
+}
+
+func TestCompileExpecting(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+
+	builder := backend.Builder("compile_expecting").(*Builder)
+	x := builder.Parameter("x", shapes.Make(dtypes.Float32, 2, 3))
+	sum := builder.ReduceSum(x, 1)
+	exec := builder.CompileExpecting([]shapes.Shape{shapes.Make(dtypes.Float32, 2, 3), shapes.Make(dtypes.Float32, 2)}, x, sum)
+	exec.Finalize()
+
+	// Mismatched expectation on the second output.
+	builder = backend.Builder("compile_expecting_mismatch").(*Builder)
+	x = builder.Parameter("x", shapes.Make(dtypes.Float32, 2, 3))
+	sum = builder.ReduceSum(x, 1)
+	err := exceptions.TryCatch[error](func() {
+		builder.CompileExpecting([]shapes.Shape{shapes.Make(dtypes.Float32, 2, 3), shapes.Make(dtypes.Float32, 3)}, x, sum)
+	})
+	require.Error(t, err)
+	fmt.Printf("\tExpected error: %v\n", err)
+	assert.Contains(t, err.Error(), "output #1")
+	assert.Contains(t, err.Error(), "(Float32)[2]")
+	assert.Contains(t, err.Error(), "(Float32)[3]")
+}
+
+func TestCompileSubset(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 3)
+	compile := func(name string, indices []int) backends.Executable {
+		builder := backend.Builder(name).(*Builder)
+		x := builder.Parameter("x", shape)
+		double := builder.Add(x, x)
+		square := builder.Mul(x, x)
+		sum := builder.ReduceSum(square)
+		if indices == nil {
+			return builder.Compile(double, square, sum)
+		}
+		return builder.CompileSubset(indices, double, square, sum)
+	}
+	execute := func(exec backends.Executable) [][]float32 {
+		bIn := backend.BufferFromFlatData(0, []float32{1, 2, 3}, shape)
+		defer backend.BufferFinalize(bIn)
+		bOuts := exec.Execute([]backends.Buffer{bIn}, nil)
+		results := make([][]float32, len(bOuts))
+		for ii, bOut := range bOuts {
+			results[ii] = make([]float32, backend.BufferShape(bOut).Size())
+			backend.BufferToFlatData(bOut, results[ii])
+			backend.BufferFinalize(bOut)
+		}
+		return results
+	}
+
+	fullExec := compile("full", nil)
+	defer fullExec.Finalize()
+	fullResults := execute(fullExec)
+
+	subsetExec := compile("subset", []int{2, 0})
+	defer subsetExec.Finalize()
+	outputShapes := subsetExec.Outputs()
+	require.Len(t, outputShapes, 2)
+	assert.True(t, outputShapes[0].Equal(shapes.Make(dtypes.Float32)))
+	assert.True(t, outputShapes[1].Equal(shape))
+	subsetResults := execute(subsetExec)
+	assert.Equal(t, [][]float32{fullResults[2], fullResults[0]}, subsetResults)
+
+	// Invalid indices.
+	require.Panics(t, func() { compile("invalid", []int{3}) })
+	require.Panics(t, func() { compile("repeated", []int{1, 1}) })
+}
+
+func TestExecuteParametersErrors(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	builder := backend.Builder("parameters_errors")
+	x := builder.Parameter("x", shapes.Make(dtypes.Float32, 2))
+	y := builder.Parameter("y_scale", shapes.Make(dtypes.Float64))
+	exec := builder.Compile(builder.Mul(builder.ConvertDType(x, dtypes.Float64), y))
+	defer exec.Finalize()
+
+	bX := backend.BufferFromFlatData(0, []float32{1, 2}, shapes.Make(dtypes.Float32, 2))
+	defer backend.BufferFinalize(bX)
+	err := exceptions.TryCatch[error](func() { exec.Execute([]backends.Buffer{bX}, nil) })
+	require.Error(t, err)
+	fmt.Printf("\tExpected error: %v\n", err)
+	assert.Contains(t, err.Error(), "x: (Float32)[2]")
+	assert.Contains(t, err.Error(), "y_scale: (Float64)")
+}
+
+func TestCapabilities(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend)
+	defer backend.Finalize()
+	capabilities := backend.Capabilities()
+	fmt.Printf("\tCapabilities: %+v\n", capabilities)
+	assert.GreaterOrEqual(t, capabilities.NumDevices, 1)
+	assert.True(t, capabilities.Donation)
+	assert.True(t, capabilities.InputOutputAliasing)
+	if *flagPlugin == "cpu" {
+		assert.Equal(t, "cpu", capabilities.Platform)
+		assert.True(t, capabilities.SupportsDType(dtypes.Float32))
+		assert.True(t, capabilities.SupportsDType(dtypes.Float64))
+	}
+	assert.False(t, capabilities.SupportsDType(dtypes.InvalidDType))
+}
+
+func TestFinalizeExecutables(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend)
+	execs := make([]*Executable, 3)
+	for ii := range execs {
+		builder := backend.Builder(fmt.Sprintf("exec_#%d", ii))
+		x := builder.Parameter("x", shapes.Make(dtypes.Float32, 3))
+		execs[ii] = builder.Compile(builder.Mul(x, x)).(*Executable)
+	}
+	require.Equal(t, 3, backend.executables.len())
+
+	// Finalized executables are no longer tracked.
+	execs[0].Finalize()
+	require.Equal(t, 2, backend.executables.len())
+
+	backend.Finalize()
+	assert.Equal(t, 0, backend.executables.len())
+	for ii, exec := range execs {
+		assert.Nilf(t, exec.exec, "executable #%d was not destroyed", ii)
+	}
+}
+
+func TestExecuteToHost(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend)
+	defer backend.Finalize()
+	builder := backend.Builder("execute_to_host")
+	x := builder.Parameter("x", shapes.Make(dtypes.Float32, 2, 2))
+	sum := builder.ReduceSum(x)
+	isPositive := builder.GreaterThan(x, builder.Constant([]float32{0, 0, 0, 0}, 2, 2))
+	exec := builder.Compile(builder.Mul(x, x), sum, builder.ConvertDType(isPositive, dtypes.Int32)).(*Executable)
+	defer exec.Finalize()
+
+	bIn := backend.BufferFromFlatData(0, []float32{1, -2, 3, -4}, shapes.Make(dtypes.Float32, 2, 2))
+	defer backend.BufferFinalize(bIn)
+	outputs, err := exec.ExecuteToHost([]backends.Buffer{bIn}, nil)
+	require.NoError(t, err)
+	require.Len(t, outputs, 3)
+	assert.Equal(t, []float32{1, 4, 9, 16}, outputs[0])
+	assert.Equal(t, []float32{-2}, outputs[1])
+	assert.Equal(t, []int32{1, 0, 1, 0}, outputs[2])
+
+	// Invalid inputs are reported as errors.
+	_, err = exec.ExecuteToHost(nil, nil)
+	require.Error(t, err)
+
+	// Zero-copy views: same values, valid until release.
+	outputs, release, err := exec.ExecuteToHostViews([]backends.Buffer{bIn}, nil)
+	require.NoError(t, err)
+	require.Len(t, outputs, 3)
+	assert.Equal(t, []float32{1, 4, 9, 16}, outputs[0])
+	assert.Equal(t, []float32{-2}, outputs[1])
+	assert.Equal(t, []int32{1, 0, 1, 0}, outputs[2])
+	release()
+	release() // Safe to call more than once.
+	_, release, err = exec.ExecuteToHostViews(nil, nil)
+	require.Error(t, err)
+	assert.Nil(t, release)
+}
+
+func TestSetInputOutputAlias(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 3)
+
+	// Exponential moving average of "ema" with "x", with the output #1 (new ema) aliased to the parameter #0.
+	builder := backend.Builder("ema").(*Builder)
+	ema := builder.Parameter("ema", shape)
+	x := builder.Parameter("x", shape)
+	decay := builder.Constant([]float32{0.5, 0.5, 0.5}, 3)
+	newEMA := builder.Add(builder.Mul(ema, decay), builder.Mul(x, decay))
+	builder.SetInputOutputAlias(0, 1)
+	exec := builder.Compile(builder.ReduceSum(x), newEMA).(*Executable)
+	defer exec.Finalize()
+	assert.Equal(t, map[int]int{1: 0}, exec.OutputDonationMap())
+
+	bEMA := backend.BufferFromFlatData(0, []float32{0, 2, 4}, shape)
+	bX := backend.BufferFromFlatData(0, []float32{2, 2, 2}, shape)
+	defer backend.BufferFinalize(bX)
+	for _, want := range [][]float32{{1, 2, 3}, {1.5, 2, 2.5}} {
+		bOuts := exec.Execute([]backends.Buffer{bEMA, bX}, []bool{true, false})
+		backend.BufferFinalize(bOuts[0])
+		bEMA = bOuts[1]
+		got := make([]float32, 3)
+		backend.BufferToFlatData(bEMA, got)
+		assert.Equal(t, want, got)
+	}
+	backend.BufferFinalize(bEMA)
+
+	// Without aliases, the map is empty.
+	builder = backend.Builder("no_alias").(*Builder)
+	x = builder.Parameter("x", shape)
+	exec2 := builder.Compile(builder.Add(x, x)).(*Executable)
+	defer exec2.Finalize()
+	assert.Nil(t, exec2.OutputDonationMap())
+
+	// Invalid aliases.
+	builder = backend.Builder("invalid_alias").(*Builder)
+	x = builder.Parameter("x", shape)
+	require.Panics(t, func() { builder.SetInputOutputAlias(1, 0) })
+	builder.SetInputOutputAlias(0, 0)
+	require.Panics(t, func() { builder.SetInputOutputAlias(0, 1) })
+	require.Panics(t, func() { builder.Compile(builder.ReduceSum(x)) })
+}
+
+func TestExecuteFetch(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 3)
+	builder := backend.Builder("execute_fetch")
+	x := builder.Parameter("x", shape)
+	exec := builder.Compile(builder.Add(x, x), builder.Mul(x, x), builder.ReduceSum(x)).(*Executable)
+	defer exec.Finalize()
+
+	bIn := backend.BufferFromFlatData(0, []float32{1, 2, 3}, shape)
+	defer backend.BufferFinalize(bIn)
+	bOuts := exec.ExecuteFetch([]backends.Buffer{bIn}, []int{1}, nil)
+	require.Len(t, bOuts, 1)
+	got := make([]float32, 3)
+	backend.BufferToFlatData(bOuts[0], got)
+	assert.Equal(t, []float32{1, 4, 9}, got)
+	backend.BufferFinalize(bOuts[0])
+
+	// Invalid fetch indices.
+	require.Panics(t, func() { exec.ExecuteFetch([]backends.Buffer{bIn}, []int{3}, nil) })
+	require.Panics(t, func() { exec.ExecuteFetch([]backends.Buffer{bIn}, []int{0, 0}, nil) })
+}
+
+func TestOptimizationReport(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	builder := backend.Builder("optimization_report")
+	x := builder.Parameter("x", shapes.Make(dtypes.Float32, 1000))
+	// (1+2) is an obvious constant fold.
+	three := builder.Add(builder.Constant([]float32{1}), builder.Constant([]float32{2}))
+	three = builder.Broadcast(three, 1000)
+	large := builder.Constant(make([]float32, 1000), 1000)
+	exec := builder.Compile(builder.Add(builder.Mul(x, three), large)).(*Executable)
+
+	report, err := exec.OptimizationReport()
+	fmt.Printf("\tReport: %+v\n", report)
+	// The optimized program is not exposed, only the counts before optimization are available.
+	require.NoError(t, err)
+	assert.False(t, report.Optimized)
+	assert.Equal(t, -1, report.OptimizedNumInstructions)
+	assert.Equal(t, 3, report.NumConstants)
+	assert.Equal(t, 3, report.OpcodeCounts["constant"])
+	assert.Equal(t, 2, report.OpcodeCounts["add"])
+	assert.Equal(t, 1000, report.LargestConstantSize)
+	assert.Greater(t, report.NumInstructions, 6)
+
+	exec.Finalize()
+	_, err = exec.OptimizationReport()
+	require.Error(t, err)
+}
+
+func TestCompileWithProbes(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 3)
+	builder := backend.Builder("probes").(*Builder)
+	x := builder.Parameter("x", shape)
+	sum := builder.ReduceSum(x)
+	output := builder.Mul(x, builder.Broadcast(sum, 3))
+	exec := builder.CompileWithProbes([]backends.Op{sum}, output).(*Executable)
+	defer exec.Finalize()
+	require.Len(t, exec.Outputs(), 1)
+	require.Len(t, exec.ProbeShapes(), 1)
+	assert.True(t, exec.ProbeShapes()[0].Equal(shapes.Make(dtypes.Float32)))
+
+	bIn := backend.BufferFromFlatData(0, []float32{1, 2, 3}, shape)
+	defer backend.BufferFinalize(bIn)
+	outputs, probes := exec.ExecuteWithProbes([]backends.Buffer{bIn}, nil)
+	require.Len(t, outputs, 1)
+	require.Len(t, probes, 1)
+	gotOutput := make([]float32, 3)
+	backend.BufferToFlatData(outputs[0], gotOutput)
+	assert.Equal(t, []float32{6, 12, 18}, gotOutput)
+	gotProbe := make([]float32, 1)
+	backend.BufferToFlatData(probes[0], gotProbe)
+	assert.Equal(t, []float32{6}, gotProbe)
+	backend.BufferFinalize(outputs[0])
+	backend.BufferFinalize(probes[0])
+
+	// Execute discards the probes.
+	outputs = exec.Execute([]backends.Buffer{bIn}, nil)
+	require.Len(t, outputs, 1)
+	backend.BufferFinalize(outputs[0])
+}
+
+func TestNaNGuard(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend).WithNaNGuard(true)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 3)
+	builder := backend.Builder("nan_guard")
+	x := builder.Parameter("x", shape)
+	// log(x) is NaN for negative values.
+	exec := builder.Compile(builder.Add(x, x), builder.Log(x))
+	defer exec.Finalize()
+
+	bIn := backend.BufferFromFlatData(0, []float32{1, 2, 3}, shape)
+	bOuts := exec.Execute([]backends.Buffer{bIn}, nil)
+	for _, bOut := range bOuts {
+		backend.BufferFinalize(bOut)
+	}
+	backend.BufferFinalize(bIn)
+
+	bIn = backend.BufferFromFlatData(0, []float32{1, -2, 3}, shape)
+	defer backend.BufferFinalize(bIn)
+	err := exceptions.TryCatch[error](func() { exec.Execute([]backends.Buffer{bIn}, nil) })
+	require.Error(t, err)
+	fmt.Printf("\tExpected error: %v\n", err)
+	assert.Contains(t, err.Error(), "output #1")
+
+	// Disabled, NaNs are returned.
+	backend.WithNaNGuard(false)
+	bOuts = exec.Execute([]backends.Buffer{bIn}, nil)
+	for _, bOut := range bOuts {
+		backend.BufferFinalize(bOut)
+	}
+}
+
+func TestExecuteScan(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 2)
+
+	// Cumulative-sum RNN: state += x, and it outputs 2*state.
+	builder := backend.Builder("cumsum_rnn").(*Builder)
+	state := builder.Parameter("state", shape)
+	x := builder.Parameter("x", shape)
+	newState := builder.Add(state, x)
+	builder.SetInputOutputAlias(0, 0)
+	exec := builder.Compile(newState, builder.Add(newState, newState)).(*Executable)
+	defer exec.Finalize()
+
+	initialState := backend.BufferFromFlatData(0, []float32{0, 10}, shape)
+	defer backend.BufferFinalize(initialState)
+	stepValues := [][]float32{{1, 2}, {3, 4}, {5, 6}}
+	perStepInputs := make([][]backends.Buffer, len(stepValues))
+	for step, values := range stepValues {
+		perStepInputs[step] = []backends.Buffer{backend.BufferFromFlatData(0, values, shape)}
+		defer backend.BufferFinalize(perStepInputs[step][0])
+	}
+
+	finalState, outputs := exec.ExecuteScan(len(stepValues), []backends.Buffer{initialState}, perStepInputs)
+	require.Len(t, finalState, 1)
+	require.Len(t, outputs, 3)
+	got := make([]float32, 2)
+	backend.BufferToFlatData(finalState[0], got)
+	assert.Equal(t, []float32{9, 22}, got)
+	backend.BufferFinalize(finalState[0])
+	for step, want := range [][]float32{{2, 24}, {8, 32}, {18, 44}} {
+		require.Len(t, outputs[step], 1)
+		backend.BufferToFlatData(outputs[step][0], got)
+		assert.Equal(t, want, got)
+		backend.BufferFinalize(outputs[step][0])
+	}
+
+	// The initial state is still owned by the caller.
+	backend.BufferToFlatData(initialState, got)
+	assert.Equal(t, []float32{0, 10}, got)
+
+	// Wrong number of steps inputs.
+	require.Panics(t, func() { exec.ExecuteScan(2, []backends.Buffer{initialState}, perStepInputs) })
+}
+
+func TestIsPortable(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	builder := backend.Builder("portable").(*Builder)
+	x := builder.Parameter("x", shapes.Make(dtypes.Float32, 3))
+	exec := builder.Compile(builder.Neg(x)).(*Executable)
+	defer exec.Finalize()
+	assert.True(t, exec.IsPortable())
+}
+
+func TestByteOutputs(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend)
+	defer backend.Finalize()
+	builder := backend.Builder("bytes")
+	text := []byte("hello")
+	x := builder.Constant(text, len(text))
+	// Shift to upper case: 'a'-'A' = 32.
+	upper := builder.Sub(x, builder.Broadcast(builder.Constant([]uint8{32}), len(text)))
+	exec := builder.Compile(upper, builder.ConvertDType(upper, dtypes.Int8))
+	defer exec.Finalize()
+	require.Equal(t, dtypes.Uint8, exec.Outputs()[0].DType)
+
+	outputs := exec.Execute(nil, nil)
+	require.Len(t, outputs, 2)
+	for _, output := range outputs {
+		got, err := backend.BufferToBytes(output)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("HELLO"), got)
+		backend.BufferFinalize(output)
+	}
+
+	// Non-byte buffers are rejected.
+	bFloat := backend.BufferFromFlatData(0, []float32{1}, shapes.Make(dtypes.Float32, 1))
+	defer backend.BufferFinalize(bFloat)
+	_, err := backend.BufferToBytes(bFloat)
+	require.ErrorIs(t, err, ErrUnsupportedDType)
+}
+
+func TestHistogramProbe(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 2, 4)
+	builder := backend.Builder("histogram").(*Builder)
+	x := builder.Parameter("x", shape)
+	require.Panics(t, func() { builder.AddHistogramProbe(x, []float64{0}) })
+	require.Panics(t, func() { builder.AddHistogramProbe(x, []float64{0, 1, 1}) })
+	require.Equal(t, 0, builder.AddHistogramProbe(x, []float64{0, 1, 2, 4}))
+	doubled := builder.Add(x, x)
+	require.Equal(t, 1, builder.AddHistogramProbe(doubled, []float64{-10, 0, 10}))
+	exec := builder.Compile(doubled).(*Executable)
+	defer exec.Finalize()
+	require.Len(t, exec.Outputs(), 1)
+	require.Equal(t, [][]float64{{0, 1, 2, 4}, {-10, 0, 10}}, exec.HistogramsBinEdges())
+
+	// Bins are left-closed: 1 goes to the second bin, while 4 and -1 are out of range.
+	bIn := backend.BufferFromFlatData(0, []float32{0, 0.5, 1, 1.5, 2, 3.9, 4, -1}, shape)
+	defer backend.BufferFinalize(bIn)
+	outputs, histograms := exec.ExecuteWithHistograms([]backends.Buffer{bIn}, nil)
+	require.Len(t, outputs, 1)
+	backend.BufferFinalize(outputs[0])
+	assert.Equal(t, [][]int64{{2, 2, 2}, {1, 7}}, histograms)
+
+	// Execute discards the histograms.
+	outputs = exec.Execute([]backends.Buffer{bIn}, nil)
+	require.Len(t, outputs, 1)
+	backend.BufferFinalize(outputs[0])
+}
+
+func TestCompileTupled(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 3)
+	builder := backend.Builder("tupled").(*Builder)
+	x := builder.Parameter("x", shape)
+	exec := builder.CompileTupled(builder.Neg(x), builder.ReduceSum(x))
+	defer exec.Finalize()
+	tupleShape := shapes.MakeTuple([]shapes.Shape{shape, shapes.Make(dtypes.Float32)})
+	require.Len(t, exec.Outputs(), 1)
+	require.True(t, exec.Outputs()[0].Equal(tupleShape))
+
+	bIn := backend.BufferFromFlatData(0, []float32{1, 2, 3}, shape)
+	defer backend.BufferFinalize(bIn)
+	outputs := exec.Execute([]backends.Buffer{bIn}, nil)
+	require.Len(t, outputs, 1)
+	tuple := outputs[0].(*TupleBuffer)
+	require.Equal(t, 2, tuple.Len())
+	require.True(t, backend.BufferShape(tuple).Equal(tupleShape))
+	gotNeg := make([]float32, 3)
+	backend.BufferToFlatData(tuple.Element(0), gotNeg)
+	assert.Equal(t, []float32{-1, -2, -3}, gotNeg)
+	gotSum := make([]float32, 1)
+	backend.BufferToFlatData(tuple.Element(1), gotSum)
+	assert.Equal(t, []float32{6}, gotSum)
+
+	// Round-trip: the tuple elements are fed to another executable.
+	builder2 := backend.Builder("untuple")
+	a := builder2.Parameter("a", shape)
+	s := builder2.Parameter("s", shapes.Make(dtypes.Float32))
+	exec2 := builder2.Compile(builder2.Add(a, builder2.Broadcast(s, 3)))
+	defer exec2.Finalize()
+	outputs2 := exec2.Execute(tuple.Elements(), nil)
+	got := make([]float32, 3)
+	backend.BufferToFlatData(outputs2[0], got)
+	assert.Equal(t, []float32{5, 4, 3}, got)
+	backend.BufferFinalize(outputs2[0])
+
+	// The TupleBuffer itself is not accepted as a regular buffer: the error points to its elements.
+	err := exceptions.TryCatch[error](func() { backend.BufferToFlatData(tuple, got) })
+	require.ErrorContains(t, err, "TupleBuffer.Element")
+	err = exceptions.TryCatch[error](func() { exec2.Execute([]backends.Buffer{tuple, tuple.Element(1)}, nil) })
+	require.ErrorContains(t, err, "TupleBuffer.Element")
+
+	backend.BufferFinalize(tuple)
+	require.Panics(t, func() { tuple.Element(0) })
+}
+
+func TestBenchmark(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 100)
+	builder := backend.Builder("benchmark").(*Builder)
+	x := builder.Parameter("x", shape)
+	exec := builder.Compile(builder.Mul(x, x)).(*Executable)
+	defer exec.Finalize()
+	require.Panics(t, func() { exec.Benchmark(nil, 1) })
+
+	bIn := backend.BufferFromFlatData(0, make([]float32, 100), shape)
+	defer backend.BufferFinalize(bIn)
+	require.Panics(t, func() { exec.Benchmark([]backends.Buffer{bIn}, 0) })
+	const iterations = 10
+	total, perIter := exec.Benchmark([]backends.Buffer{bIn}, iterations)
+	assert.Greater(t, perIter, time.Duration(0))
+	assert.Equal(t, total/iterations, perIter)
+
+	// Inputs are not donated, so they can still be used.
+	got := make([]float32, 100)
+	backend.BufferToFlatData(bIn, got)
+}
+
+func TestExecuteValues(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 2, 3)
+	builder := backend.Builder("values").(*Builder)
+	x := builder.Parameter("x", shape)
+	exec := builder.Compile(
+		builder.ReduceSum(x),
+		builder.ConvertDType(builder.ReduceSum(x, 1), dtypes.Int32),
+		builder.GreaterThan(x, builder.Broadcast(builder.Constant([]float32{2}), 2, 3)),
+	).(*Executable)
+	defer exec.Finalize()
+
+	bIn := backend.BufferFromFlatData(0, []float32{1, 2, 3, 4, 5, 6}, shape)
+	defer backend.BufferFinalize(bIn)
+	values := exec.ExecuteValues([]backends.Buffer{bIn}, nil)
+	require.Len(t, values, 3)
+	assert.Equal(t, float32(21), values[0])
+	assert.Equal(t, []int32{6, 15}, values[1])
+	assert.Equal(t, [][]bool{{false, false, true}, {true, true, true}}, values[2])
+}
+
+func TestExecuteOptimizerStep(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 2)
+
+	// Gradient descent step on loss=sum((w-x)^2), with learning rate 0.25: w -= 0.25 * 2 * (w-x).
+	builder := backend.Builder("sgd").(*Builder)
+	x := builder.Parameter("x", shape)
+	w := builder.Parameter("w", shape)
+	diff := builder.Sub(w, x)
+	loss := builder.ReduceSum(builder.Mul(diff, diff))
+	newW := builder.Sub(w, builder.Mul(diff, builder.Constant([]float32{0.5, 0.5}, 2)))
+	builder.SetInputOutputAlias(1, 1)
+	exec := builder.Compile(loss, newW).(*Executable)
+	defer exec.Finalize()
+
+	bX := backend.BufferFromFlatData(0, []float32{2, 4}, shape)
+	defer backend.BufferFinalize(bX)
+	state := map[string]backends.Buffer{"w": backend.BufferFromFlatData(0, []float32{0, 0}, shape)}
+	for _, want := range [][]float32{{1, 2}, {1.5, 3}} {
+		state = exec.ExecuteOptimizerStep(state, []backends.Buffer{bX})
+		require.Len(t, state, 1)
+		got := make([]float32, 2)
+		backend.BufferToFlatData(state["w"], got)
+		assert.Equal(t, want, got)
+	}
+
+	// Unknown state name, or missing inputs.
+	require.Panics(t, func() {
+		exec.ExecuteOptimizerStep(map[string]backends.Buffer{"unknown": bX}, []backends.Buffer{bX})
+	})
+	require.Panics(t, func() { exec.ExecuteOptimizerStep(state, nil) })
+	backend.BufferFinalize(state["w"])
+}
+
+func TestPrecompileAsync(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 3)
+	build := func(b *Builder) []backends.Op {
+		x := b.Parameter("x", shape)
+		return []backends.Op{b.Mul(x, x)}
+	}
+
+	numLive := backend.executables.len()
+	pending := backend.PrecompileAsync("square", build)
+	exec, err := pending.Get()
+	require.NoError(t, err)
+	defer exec.Finalize()
+	exec2, err := pending.Get()
+	require.NoError(t, err)
+	assert.Same(t, exec, exec2)
+	assert.Equal(t, numLive+1, backend.executables.len(), "Get should not recompile")
+
+	// Same results as a synchronous compilation.
+	builder := backend.Builder("square").(*Builder)
+	syncExec := builder.Compile(build(builder)...).(*Executable)
+	defer syncExec.Finalize()
+	bX := backend.BufferFromFlatData(0, []float32{1, 2, 3}, shape)
+	defer backend.BufferFinalize(bX)
+	for _, e := range []*Executable{exec, syncExec} {
+		outputs := e.Execute([]backends.Buffer{bX}, nil)
+		got := make([]float32, 3)
+		backend.BufferToFlatData(outputs[0], got)
+		backend.BufferFinalize(outputs[0])
+		assert.Equal(t, []float32{1, 4, 9}, got)
+	}
+
+	// Errors while building are returned by Get.
+	pending = backend.PrecompileAsync("invalid", func(b *Builder) []backends.Op {
+		x := b.Parameter("x", shape)
+		return []backends.Op{b.Add(x, b.Constant([]float32{1, 2}, 2))}
+	})
+	_, err = pending.Get()
+	require.Error(t, err)
+}
+
+func TestParameterAndOutputBytes(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	builder := backend.Builder("bytes").(*Builder)
+	x := builder.Parameter("x", shapes.Make(dtypes.Float32, 32, 128))
+	y := builder.Parameter("y", shapes.Make(dtypes.Int8, 3))
+	exec := builder.Compile(builder.ReduceSum(x), builder.ConvertDType(x, dtypes.Float64), y).(*Executable)
+	defer exec.Finalize()
+	assert.Equal(t, []int64{32 * 128 * 4, 3}, exec.ParameterBytes())
+	assert.Equal(t, []int64{4, 32 * 128 * 8, 3}, exec.OutputBytes())
+}
+
+func TestStructuralHash(t *testing.T) {
+	backend := New(*flagPlugin)
+	defer backend.Finalize()
+	compile := func(name, paramName string, useMul bool) *Executable {
+		builder := backend.Builder(name)
+		x := builder.Parameter(paramName, shapes.Make(dtypes.Float32, 3))
+		one := builder.Constant([]float32{1, 1, 1}, 3)
+		var output backends.Op
+		if useMul {
+			output = builder.Mul(x, one)
+		} else {
+			output = builder.Add(x, one)
+		}
+		return builder.Compile(output).(*Executable)
+	}
+	exec0 := compile("graph_a", "x", false)
+	defer exec0.Finalize()
+	exec1 := compile("graph_b", "y", false)
+	defer exec1.Finalize()
+	exec2 := compile("graph_a", "x", true)
+	defer exec2.Finalize()
+
+	// Same computation, independently compiled with different names.
+	assert.Equal(t, exec0.StructuralHash(), exec1.StructuralHash())
+	// Different computation.
+	assert.NotEqual(t, exec0.StructuralHash(), exec2.StructuralHash())
+}
+
+func TestDeterministicReductions(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend).WithDeterministicReductions(true)
+	defer backend.Finalize()
+	const size = 1 << 20
+	builder := backend.Builder("deterministic_reductions")
+	x := builder.Parameter("x", shapes.Make(dtypes.Float32, size))
+	exec := builder.Compile(builder.ReduceSum(x)).(*Executable)
+	defer exec.Finalize()
+
+	values := make([]float32, size)
+	for ii := range values {
+		values[ii] = float32(math.Sin(float64(ii))) * 1e3
+	}
+	sum := func() uint32 {
+		input, err := exec.PrepareInput(0, values)
+		require.NoError(t, err)
+		outputs := exec.Execute([]backends.Buffer{input}, []bool{true})
+		result := make([]float32, 1)
+		backend.BufferToFlatData(outputs[0], result)
+		backend.BufferFinalize(outputs[0])
+		return math.Float32bits(result[0])
+	}
+	assert.Equal(t, sum(), sum())
+	assert.Equal(t, "true", backend.Config().Options["deterministic_reductions"])
+
+	// The environment variable with the XLA debug options is restored after compiling.
+	const userOptions = "xla_cpu_enable_fast_math: false"
+	t.Setenv(pjrt.EnvXlaDebugOptions, userOptions)
+	builder = backend.Builder("deterministic_reductions_env")
+	x = builder.Parameter("x", shapes.Make(dtypes.Float32, 3))
+	builder.Compile(builder.ReduceSum(x)).Finalize()
+	assert.Equal(t, userOptions, os.Getenv(pjrt.EnvXlaDebugOptions))
+	require.NoError(t, os.Unsetenv(pjrt.EnvXlaDebugOptions))
+	builder = backend.Builder("deterministic_reductions_unset_env")
+	x = builder.Parameter("x", shapes.Make(dtypes.Float32, 3))
+	builder.Compile(builder.ReduceSum(x)).Finalize()
+	_, found := os.LookupEnv(pjrt.EnvXlaDebugOptions)
+	assert.False(t, found)
+}
+
+func TestDeterministicDebugOptions(t *testing.T) {
+	for _, userOptions := range []string{"", "xla_cpu_enable_fast_math: false xla_gpu_autotune_level: 2"} {
+		options, err := deterministicDebugOptions(userOptions)
+		require.NoError(t, err)
+		debugOptions := &xlaprotos.DebugOptions{}
+		require.NoError(t, prototext.Unmarshal([]byte(options), debugOptions))
+		assert.True(t, debugOptions.XlaGpuDeterministicOps)
+		if userOptions != "" {
+			// User options are preserved.
+			assert.Equal(t, int32(2), debugOptions.XlaGpuAutotuneLevel)
+		}
+	}
+	_, err := deterministicDebugOptions("not a valid option")
+	require.Error(t, err)
+}
+
+// TestClone is more meaningful with -race.
+func TestClone(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend)
+	defer backend.Finalize()
+	shape := shapes.Make(dtypes.Float32, 3)
+	builder := backend.Builder("clone")
+	x := builder.Parameter("x", shape)
+	exec := builder.Compile(builder.Mul(x, x)).(*Executable)
+
+	const numGoroutines = 8
+	var wg sync.WaitGroup
+	results := make([][]float32, numGoroutines)
+	for ii := range numGoroutines {
+		clone, err := exec.Clone()
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each goroutine finalizes its own handle when done.
+			defer clone.Finalize()
+			bX := backend.BufferFromFlatData(0, []float32{1, 2, float32(ii)}, shape)
+			defer backend.BufferFinalize(bX)
+			for range 10 {
+				outputs := clone.Execute([]backends.Buffer{bX}, nil)
+				results[ii] = make([]float32, 3)
+				backend.BufferToFlatData(outputs[0], results[ii])
+				backend.BufferFinalize(outputs[0])
+			}
+		}()
+	}
+	// Finalizing the original doesn't affect the clones.
+	exec.Finalize()
+	wg.Wait()
+	for ii, got := range results {
+		assert.Equal(t, []float32{1, 4, float32(ii * ii)}, got)
+	}
+	assert.Equal(t, 0, backend.executables.len())
+
+	_, err := exec.Clone()
+	require.Error(t, err)
+}
+
+func TestBufferTracking(t *testing.T) {
+	backend := New(*flagPlugin).(*Backend)
+	defer backend.Finalize()
+	require.Nil(t, backend.LiveBuffers())
+	require.Error(t, backend.CheckLeaks(0))
+
+	backend.WithBufferTracking(true)
+	shape := shapes.Make(dtypes.Float32, 2, 3)
+	freed := backend.NewBuffer(shape, 0)
+	leaked, err := backend.NewBufferFromHost([]float32{1, 2, 3, 4, 5, 6}, shape, 0)
+	require.NoError(t, err)
+	defer backend.BufferFinalize(leaked)
+	backend.BufferFinalize(freed)
+
+	live := backend.LiveBuffers()
+	require.Len(t, live, 1)
+	require.True(t, live[0].Shape.Equal(shape))
+	require.Contains(t, live[0].Stack, "TestBufferTracking")
+
+	// Only reported if older than the threshold.
+	require.NoError(t, backend.CheckLeaks(time.Hour))
+	time.Sleep(10 * time.Millisecond)
+	err = backend.CheckLeaks(time.Millisecond)
+	require.Error(t, err)
+	fmt.Printf("CheckLeaks: %v\n", err)
+	require.ErrorContains(t, err, "1 buffer(s)")
+	require.ErrorContains(t, err, "xla_test.go")
+	require.ErrorContains(t, err, "NewBufferFromHost")
+}
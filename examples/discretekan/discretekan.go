@@ -17,8 +17,24 @@ import (
 // Univariate graph function.
 type Univariate func(x *Node) *Node
 
-// Plot univariate function for values between
+// Plot univariate function for values between -0.1 and 1.1.
+//
+// The name can be followed by the names of each function, separated by ";".
 func Plot(name string, univariateFunctions ...Univariate) {
+	must.M(gonbplotly.DisplayFig(newFigure(name, false, univariateFunctions...)))
+}
+
+// PlotLog is like Plot, but with a log-scale y-axis, for functions that span several orders of magnitude.
+//
+// Non-positive values can't be represented in log-scale: they are masked out (left as gaps in the plot),
+// as opposed to shifting all values, which would distort the shape of the functions.
+func PlotLog(name string, univariateFunctions ...Univariate) {
+	must.M(gonbplotly.DisplayFig(newFigure(name, true, univariateFunctions...)))
+}
+
+// newFigure evaluates the univariate functions and returns the figure to be plotted.
+// If logY is true, the y-axis is in log-scale and the non-positive values are masked out.
+func newFigure(name string, logY bool, univariateFunctions ...Univariate) *grob.Fig {
 	backend := backends.New()
 	numPoints := 1000
 	minX, maxX := -0.1, 1.1
@@ -31,6 +47,10 @@ func Plot(name string, univariateFunctions ...Univariate) {
 		fnNames = nameParts[1:]
 	}
 
+	yAxisType := grob.LayoutYaxisTypeLinear
+	if logY {
+		yAxisType = grob.LayoutYaxisTypeLog
+	}
 	fig := &grob.Fig{
 		Layout: &grob.Layout{
 			Title: &grob.LayoutTitle{
@@ -42,7 +62,7 @@ func Plot(name string, univariateFunctions ...Univariate) {
 			},
 			Yaxis: &grob.LayoutYaxis{
 				Showgrid: grob.True,
-				Type:     grob.LayoutYaxisTypeLinear,
+				Type:     yAxisType,
 			},
 		},
 	}
@@ -66,6 +86,10 @@ func Plot(name string, univariateFunctions ...Univariate) {
 		} else {
 			fnName = fmt.Sprintf("#%d", fnIdx)
 		}
+		var y any = outputs
+		if logY {
+			y = maskNonPositive(outputs)
+		}
 		fig.Data = append(fig.Data,
 			&grob.Scatter{
 				Name: fnName,
@@ -76,8 +100,19 @@ func Plot(name string, univariateFunctions ...Univariate) {
 				},
 				Mode: "lines",
 				X:    inputs,
-				Y:    outputs,
+				Y:    y,
 			})
 	}
-	must.M(gonbplotly.DisplayFig(fig))
+	return fig
+}
+
+// maskNonPositive returns the values with the non-positive ones replaced by nil, which plotly renders as gaps.
+func maskNonPositive(values []float64) []any {
+	masked := make([]any, len(values))
+	for ii, v := range values {
+		if v > 0 {
+			masked[ii] = v
+		}
+	}
+	return masked
 }
@@ -0,0 +1,27 @@
+package discretekan
+
+import (
+	"testing"
+
+	grob "github.com/MetalBlueberry/go-plotly/graph_objects"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFigure(t *testing.T) {
+	fn := func(x *Node) *Node { return Exp(MulScalar(x, 10)) }
+	fig := newFigure("linear", false, fn)
+	assert.Equal(t, grob.LayoutYaxisTypeLinear, fig.Layout.Yaxis.Type)
+
+	fig = newFigure("log;exp;shifted", true, fn, func(x *Node) *Node { return AddScalar(x, -0.5) })
+	assert.Equal(t, grob.LayoutYaxisTypeLog, fig.Layout.Yaxis.Type)
+	require.Len(t, fig.Data, 2)
+
+	// Non-positive values are masked out.
+	shifted := fig.Data[1].(*grob.Scatter)
+	assert.Equal(t, "shifted", string(shifted.Name))
+	y := shifted.Y.([]any)
+	assert.Nil(t, y[0])
+	assert.Greater(t, y[len(y)-1].(float64), 0.0)
+}
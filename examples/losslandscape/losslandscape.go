@@ -0,0 +1,80 @@
+// Package losslandscape samples the loss over a 2D slice of the parameter space, for visualization of
+// optimization difficulty (see "Visualizing the Loss Landscape of Neural Nets", https://arxiv.org/abs/1712.09913).
+//
+// Given a base set of parameters θ and two direction vectors δ and η (same shape as θ), it computes the loss at
+// θ + α·δ + β·η for a grid of (α, β) offsets. The result can be plotted as a heatmap or contour plot.
+package losslandscape
+
+import (
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/ml/train/losses"
+	"github.com/gomlx/gomlx/types/tensors"
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+// ModelFn maps the parameters to the predictions passed to the loss function.
+type ModelFn func(params *Node) (predictions []*Node)
+
+// Linspace returns n values evenly spaced from start to end (inclusive).
+func Linspace(start, end float64, n int) []float64 {
+	if n < 2 {
+		exceptions.Panicf("Linspace requires at least 2 points, got %d", n)
+	}
+	values := make([]float64, n)
+	for ii := range values {
+		values[ii] = start + (end-start)*float64(ii)/float64(n-1)
+	}
+	return values
+}
+
+// Sample returns the loss at params + α·dirAlpha + β·dirBeta, for each α in alphas and β in betas.
+//
+// The model maps the parameters to the predictions given to lossFn. If model is nil, the parameters are the
+// predictions themselves. If lossFn returns a non-scalar (e.g.: one loss per example) its mean is taken.
+//
+// The returned grid has one row per β (y-axis) and one column per α (x-axis), the layout expected by
+// heatmap plots: grid[betaIdx][alphaIdx].
+//
+// The computation is compiled once (with NewExec) and executed for each point of the grid.
+func Sample(backend backends.Backend, lossFn losses.LossFn, model ModelFn, labels []*tensors.Tensor,
+	params, dirAlpha, dirBeta *tensors.Tensor, alphas, betas []float64) [][]float64 {
+	if !params.Shape().Equal(dirAlpha.Shape()) || !params.Shape().Equal(dirBeta.Shape()) {
+		exceptions.Panicf("losslandscape.Sample: params (%s) and directions (%s, %s) must have the same shape",
+			params.Shape(), dirAlpha.Shape(), dirBeta.Shape())
+	}
+	if model == nil {
+		model = func(params *Node) []*Node { return []*Node{params} }
+	}
+	exec := NewExec(backend, func(inputs []*Node) *Node {
+		params, dirAlpha, dirBeta := inputs[0], inputs[1], inputs[2]
+		alpha := ConvertDType(inputs[3], params.DType())
+		beta := ConvertDType(inputs[4], params.DType())
+		labels := inputs[5:]
+		offsetParams := Add(params, Add(Mul(alpha, dirAlpha), Mul(beta, dirBeta)))
+		loss := lossFn(labels, model(offsetParams))
+		if !loss.IsScalar() {
+			loss = ReduceAllMean(loss)
+		}
+		return ConvertDType(loss, dtypes.Float64)
+	})
+	defer exec.Finalize()
+
+	args := make([]any, 5+len(labels))
+	args[0], args[1], args[2] = params, dirAlpha, dirBeta
+	for ii, label := range labels {
+		args[5+ii] = label
+	}
+	grid := make([][]float64, len(betas))
+	for betaIdx, beta := range betas {
+		grid[betaIdx] = make([]float64, len(alphas))
+		for alphaIdx, alpha := range alphas {
+			args[3], args[4] = alpha, beta
+			loss := exec.Call(args...)[0]
+			grid[betaIdx][alphaIdx] = tensors.ToScalar[float64](loss)
+			loss.FinalizeAll()
+		}
+	}
+	return grid
+}
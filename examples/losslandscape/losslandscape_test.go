@@ -0,0 +1,38 @@
+package losslandscape
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/train/losses"
+	"github.com/gomlx/gomlx/types/tensors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSample(t *testing.T) {
+	backend := graphtest.BuildTestBackend()
+	labels := tensors.FromValue([]float32{1, 2})
+	params := tensors.FromValue([]float32{1, 2})
+	dirAlpha := tensors.FromValue([]float32{1, 0})
+	dirBeta := tensors.FromValue([]float32{0, 1})
+	alphas := Linspace(-1, 1, 5)
+	betas := Linspace(-2, 2, 3)
+	grid := Sample(backend, losses.MeanSquaredError, nil, []*tensors.Tensor{labels},
+		params, dirAlpha, dirBeta, alphas, betas)
+	require.Len(t, grid, len(betas))
+	for _, row := range grid {
+		require.Len(t, row, len(alphas))
+	}
+	// MSE = (α² + β²)/2, minimum at the center.
+	assert.InDelta(t, 0.0, grid[1][2], 1e-6)
+	assert.InDelta(t, (1.0+4.0)/2, grid[0][0], 1e-6)
+	assert.InDelta(t, (0.25+4.0)/2, grid[2][3], 1e-6)
+
+	// With a model: predictions = 2*params.
+	grid = Sample(backend, losses.MeanSquaredError, func(params *Node) []*Node { return []*Node{MulScalar(params, 2)} },
+		[]*tensors.Tensor{labels}, params, dirAlpha, dirBeta, alphas, betas)
+	require.Len(t, grid, len(betas))
+	assert.InDelta(t, (1.0+4.0)/2, grid[1][2], 1e-6)
+}
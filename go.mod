@@ -24,6 +24,7 @@ require (
 	github.com/x448/float16 v0.8.4
 	golang.org/x/exp v0.0.0-20241108190413-2d47ceb2692f
 	gonum.org/v1/plot v0.14.0
+	google.golang.org/protobuf v1.35.2
 	k8s.io/klog/v2 v2.130.1
 )
 
@@ -74,6 +75,5 @@ require (
 	golang.org/x/term v0.26.0 // indirect
 	golang.org/x/text v0.20.0 // indirect
 	gonum.org/v1/gonum v0.15.1 // indirect
-	google.golang.org/protobuf v1.35.2 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
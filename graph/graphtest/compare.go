@@ -0,0 +1,101 @@
+/*
+ *	Copyright 2023 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package graphtest
+
+import (
+	"fmt"
+	"github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+	"github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/tensors"
+	"github.com/pkg/errors"
+)
+
+// BackendsComparison is the result of CompareBackends.
+type BackendsComparison struct {
+	// Match is true if all outputs of both backends match within the tolerance.
+	Match bool
+
+	// OutputIndex is the index of the first divergent output, or -1 if all outputs match.
+	OutputIndex int
+
+	// Values of the first divergent output on each of the backends, or nil if all outputs match.
+	Values [2]*tensors.Tensor
+}
+
+// String implements fmt.Stringer.
+func (c BackendsComparison) String() string {
+	if c.Match {
+		return "backends match"
+	}
+	return fmt.Sprintf("backends diverge on output #%d: %s != %s", c.OutputIndex, c.Values[0], c.Values[1])
+}
+
+// CompareBackends builds and executes graphFn on the two backends given by their configurations (see
+// backends.NewWithConfig, e.g.: "xla:cpu" and "xla:cuda"), and compares their outputs, element-wise, within delta.
+// Values of delta <= 0 means only exact equality is accepted.
+//
+// It returns the first divergent output, if any. An error is returned if the backends can't be created, or the
+// graph fails to build or execute in either of them.
+//
+// To compare against another backend, register it (usually by importing its package, like
+// `_ "github.com/gomlx/gomlx/backends/xla"`) and pass its configuration.
+func CompareBackends(config0, config1 string, graphFn func(g *graph.Graph) []*graph.Node, delta float64) (
+	comparison BackendsComparison, err error) {
+	var results [2][]*tensors.Tensor
+	for ii, config := range []string{config0, config1} {
+		results[ii], err = executeOnBackend(config, graphFn)
+		if err != nil {
+			return
+		}
+	}
+	if len(results[0]) != len(results[1]) {
+		err = errors.Errorf("CompareBackends: backend %q returned %d outputs, but backend %q returned %d",
+			config0, len(results[0]), config1, len(results[1]))
+		return
+	}
+	comparison = BackendsComparison{Match: true, OutputIndex: -1}
+	for outputIdx, output0 := range results[0] {
+		output1 := results[1][outputIdx]
+		if !output0.InDelta(output1, delta) {
+			comparison = BackendsComparison{OutputIndex: outputIdx, Values: [2]*tensors.Tensor{output0, output1}}
+			break
+		}
+	}
+	return
+}
+
+// executeOnBackend creates the backend with the given configuration, executes graphFn on it, and returns the outputs
+// as local tensors, so they remain valid after the backend is finalized.
+func executeOnBackend(config string, graphFn func(g *graph.Graph) []*graph.Node) (outputs []*tensors.Tensor, err error) {
+	err = exceptions.TryCatch[error](func() {
+		backend := backends.NewWithConfig(config)
+		defer backend.Finalize()
+		exec := graph.NewExec(backend, graphFn)
+		defer exec.Finalize()
+		outputs = exec.Call()
+		for ii, output := range outputs {
+			outputs[ii] = output.LocalClone()
+			output.FinalizeAll()
+		}
+	})
+	if err != nil {
+		err = errors.WithMessagef(err, "CompareBackends: failed on backend %q", config)
+		outputs = nil
+	}
+	return
+}
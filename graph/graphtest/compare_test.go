@@ -0,0 +1,31 @@
+package graphtest
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/gomlx/gomlx/backends/xla"
+)
+
+// TestCompareBackends is a smoke test comparing the XLA CPU backend against itself.
+// To compare against a second backend, import its package (to register it) and replace one of the configurations,
+// e.g.: "xla:cuda".
+func TestCompareBackends(t *testing.T) {
+	graphFn := func(g *Graph) []*Node {
+		x := Const(g, []float32{1, 2, 3})
+		return []*Node{Mul(x, x), ReduceAllSum(Exp(x))}
+	}
+	comparison, err := CompareBackends("xla:cpu", "xla:cpu", graphFn, 1e-6)
+	require.NoError(t, err)
+	fmt.Printf("\t%s\n", comparison)
+	assert.True(t, comparison.Match)
+	assert.Equal(t, -1, comparison.OutputIndex)
+
+	// Unknown backend.
+	_, err = CompareBackends("xla:cpu", "unknown_backend:", graphFn, 1e-6)
+	require.Error(t, err)
+}
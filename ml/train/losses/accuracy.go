@@ -0,0 +1,112 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	"slices"
+
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+// CategoricalAccuracy returns the per-example correctness (1 if correct, 0 otherwise) of the predictions, given the
+// labels in "dense" format (e.g.: one-hot encoded), with the same shape as predictions.
+// An example is correct if argmax(predictions) == argmax(labels) on the last axis.
+// It works for both probabilities or logits.
+//
+// It's not a loss, but it follows the same conventions: weights and mask can be given in the labels slice,
+// following the labels themselves, and they are applied as in ApplyWeightsAndMask. The returned shape is the
+// batch shape (predictions shape without the last axis), and the dtype is the same as predictions.
+func CategoricalAccuracy(labels, predictions []*Node) *Node {
+	predictions0 := predictions[0]
+	labels0 := labels[0]
+	if !labels0.Shape().Equal(predictions0.Shape()) {
+		Panicf("labels[0] (%s) and predictions[0] (%s) must have same shape", labels0.Shape(), predictions0.Shape())
+	}
+	weightsShape := shapes.Make(predictions0.DType(), predictions0.Shape().Dimensions[:predictions0.Rank()-1]...)
+	weights, mask := CheckLabelsForWeightsAndMask(weightsShape, labels)
+	correct := Equal(ArgMax(predictions0, -1, dtypes.Int32), ArgMax(labels0, -1, dtypes.Int32))
+	return ApplyWeightsAndMask(ConvertDType(correct, predictions0.DType()), weights, mask)
+}
+
+// SparseCategoricalAccuracy returns the per-example correctness (1 if correct, 0 otherwise) of the predictions,
+// given the labels as the indices of the true category: labels must be integer, and shaped like predictions,
+// except the last axis, which must have dimension 1 -- the same convention as SparseCategoricalCrossEntropyLogits.
+// An example is correct if argmax(predictions) == labels. It works for both probabilities or logits.
+//
+// It's not a loss, but it follows the same conventions: weights and mask can be given in the labels slice,
+// following the labels themselves, and they are applied as in ApplyWeightsAndMask. The returned shape is the
+// batch shape (predictions shape without the last axis), and the dtype is the same as predictions.
+func SparseCategoricalAccuracy(labels, predictions []*Node) *Node {
+	labels0, predictions0, weights, mask := checkSparseLabels(labels, predictions)
+	correct := Equal(ArgMax(predictions0, -1, labels0.DType()), Squeeze(labels0, -1))
+	return ApplyWeightsAndMask(ConvertDType(correct, predictions0.DType()), weights, mask)
+}
+
+// SparseTopKAccuracy returns a function that yields the per-example top-k correctness: 1 if the true label is among
+// the k largest predictions (logits or probabilities), 0 otherwise. It follows the same conventions as
+// SparseCategoricalAccuracy, including weights and mask.
+//
+// The true label is a hit if fewer than k predictions are strictly larger than its own, so ties at the k-th
+// position are counted as hits.
+func SparseTopKAccuracy(k int) func(labels, predictions []*Node) *Node {
+	if k <= 0 {
+		Panicf("SparseTopKAccuracy requires k > 0, got %d", k)
+	}
+	return func(labels, predictions []*Node) *Node {
+		labels0, predictions0, weights, mask := checkSparseLabels(labels, predictions)
+		dtype := predictions0.DType()
+		trueValues := InsertAxes(gatherTrueLogits(labels0, predictions0), -1)
+		numLarger := ReduceSum(ConvertDType(GreaterThan(predictions0, trueValues), dtypes.Int32), -1)
+		hits := LessThan(numLarger, Scalar(predictions0.Graph(), dtypes.Int32, k))
+		return ApplyWeightsAndMask(ConvertDType(hits, dtype), weights, mask)
+	}
+}
+
+// checkSparseLabels checks that labels are sparse (integer indices, with the last axis of dimension 1) and compatible
+// with predictions, and returns labels[0], predictions[0] and the optional weights and mask.
+//
+// Labels shaped as predictions without the last axis (e.g.: `[batch_size]`) are also accepted, and the returned
+// labels[0] has the last axis of dimension 1 inserted.
+func checkSparseLabels(labels, predictions []*Node) (labels0, predictions0, weights, mask *Node) {
+	predictions0 = predictions[0]
+	labels0 = labels[0]
+	labelsShape := labels0.Shape()
+	labelsRank := labelsShape.Rank()
+	predictionsShape := predictions0.Shape()
+	if !labelsShape.DType.IsInt() {
+		Panicf("labels0 indices dtype (%s), it must be integer", labelsShape.DType)
+	}
+	if labelsRank == predictionsShape.Rank()-1 && slices.Equal(labelsShape.Dimensions, predictionsShape.Dimensions[:labelsRank]) {
+		// Labels given without the trailing axis of dimension 1.
+		labels0 = InsertAxes(labels0, -1)
+		labelsShape = labels0.Shape()
+		labelsRank++
+	}
+	if labelsRank != predictionsShape.Rank() {
+		Panicf("labels0(%s) and predictions0(%s) must have the same rank, or labels0 must be shaped as predictions0 without the last axis",
+			labelsShape, predictionsShape)
+	}
+	if labelsShape.Dimensions[labelsRank-1] != 1 {
+		Panicf("labels0(%s) are expected to have the last dimension == 1, with the true/labeled category", labelsShape)
+	}
+	weightsShape := shapes.Make(predictions0.DType(), labelsShape.Dimensions[:labelsRank-1]...)
+	weights, mask = CheckLabelsForWeightsAndMask(weightsShape, labels)
+	return
+}
@@ -0,0 +1,57 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+)
+
+func TestCategoricalAccuracy(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "CategoricalAccuracy", func(g *Graph) (inputs, outputs []*Node) {
+		logits := Const(g, [][]float32{{1, 3, 2}, {5, 0, 0}, {0, 0, 1}, {-1, -2, -3}})
+		denseLabels := Const(g, [][]float32{{0, 1, 0}, {0, 0, 1}, {0, 0, 1}, {1, 0, 0}})
+		sparseLabels := Const(g, [][]int32{{1}, {2}, {2}, {0}})
+		weights := Const(g, []float32{1, 1, 2, 0.5})
+		mask := Const(g, []bool{true, true, false, true})
+		inputs = []*Node{logits, sparseLabels}
+		outputs = []*Node{
+			CategoricalAccuracy([]*Node{denseLabels}, []*Node{logits}),
+			SparseCategoricalAccuracy([]*Node{sparseLabels}, []*Node{logits}),
+			SparseCategoricalAccuracy([]*Node{sparseLabels, weights, mask}, []*Node{logits}),
+			CategoricalAccuracy([]*Node{denseLabels, mask}, []*Node{logits}),
+		}
+		return
+	}, []any{
+		[]float32{1, 0, 1, 1},
+		[]float32{1, 0, 1, 1},
+		// Masked-out example is not counted, and weights are applied.
+		[]float32{1, 0, 0, 0.5},
+		[]float32{1, 0, 0, 1},
+	}, 0)
+}
+
+func TestSparseTopKAccuracy(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "SparseTopKAccuracy", func(g *Graph) (inputs, outputs []*Node) {
+		logits := Const(g, [][]float32{
+			{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, // Top-3: 9, 8, 7.
+			{9, 8, 7, 6, 5, 4, 3, 2, 1, 0}, // Top-3: 0, 1, 2.
+			{1, 1, 1, 1, 5, 1, 1, 1, 1, 1}, // Top-3: 4 and ties.
+			{3, 0, 0, 0, 0, 0, 0, 9, 8, 0}, // Top-3: 7, 8, 0.
+		})
+		labels := Const(g, [][]int32{{7}, {3}, {4}, {0}})
+		mask := Const(g, []bool{true, true, true, false})
+		inputs = []*Node{logits, labels}
+		top3 := SparseTopKAccuracy(3)
+		outputs = []*Node{
+			top3([]*Node{labels}, []*Node{logits}),
+			top3([]*Node{labels, mask}, []*Node{logits}),
+			SparseTopKAccuracy(1)([]*Node{labels}, []*Node{logits}),
+		}
+		return
+	}, []any{
+		[]float32{1, 0, 1, 1},
+		[]float32{1, 0, 1, 0}, // Masked example is excluded.
+		[]float32{0, 0, 1, 0},
+	}, 0)
+}
@@ -0,0 +1,79 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gomlx/ml/train/optimizers"
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+const (
+	// ParamAnnealingSteps is the context hyperparameter with the number of steps over which the schedule of
+	// MakeAnnealedLoss is sampled: after that, the schedule value at the last sampled step is used.
+	//
+	// The default is 100_000.
+	ParamAnnealingSteps = "annealing_steps"
+
+	// annealingMaxSamples is the maximum number of segments in which the schedule of MakeAnnealedLoss is sampled.
+	annealingMaxSamples = 1024
+)
+
+// MakeAnnealedLoss returns a LossFn that scales the loss returned by inner by `schedule(globalStep)`, e.g.: to
+// anneal the weight of an auxiliary loss during training (curriculum learning).
+//
+// The global step is read (in the graph) from the variable optimizers.GlobalStepVariableName in the root scope of
+// ctx -- the one created and incremented by the optimizers (see optimizers.GetGlobalStepVar): so during a training
+// step it holds the number of steps already taken, starting at 0. It is created (with value 0) if it doesn't exist.
+//
+// Since schedule is a Go function, it can't be executed in the graph: instead it is sampled (on the host, when
+// the graph is built) at up to 1024 evenly spaced integer steps in [0, ParamAnnealingSteps] (default 100_000),
+// and linearly interpolated in the graph. If ParamAnnealingSteps <= 1024, it is sampled at every step, and the
+// scale is exactly schedule(globalStep). Otherwise, it is exact for schedules that are linear between the sampled
+// steps. After the last sampled step, its value is used.
+func MakeAnnealedLoss(inner LossFn, schedule func(step int64) float64, ctx *context.Context) LossFn {
+	numSteps := context.GetParamOr(ctx, ParamAnnealingSteps, int64(100_000))
+	if numSteps <= 0 {
+		Panicf("MakeAnnealedLoss requires %q > 0, got %d", ParamAnnealingSteps, numSteps)
+	}
+	stride := (numSteps + annealingMaxSamples - 1) / annealingMaxSamples
+	numSegments := (numSteps + stride - 1) / stride
+	samples := make([]float64, numSegments+1)
+	for ii := range samples {
+		samples[ii] = schedule(int64(ii) * stride)
+	}
+	return func(labels, predictions []*Node) *Node {
+		loss := inner(labels, predictions)
+		g := loss.Graph()
+		dtype := loss.DType()
+		globalStepVar := optimizers.GetGlobalStepVar(ctx.InAbsPath(context.RootScope))
+		globalStep := ConvertDType(StopGradient(globalStepVar.ValueGraph(g)), dtypes.Float32)
+
+		// Position in the samples, and linear interpolation between the sample before and after it.
+		position := ClipScalar(DivScalar(globalStep, float64(stride)), 0, float64(numSegments))
+		segment := MinScalar(Floor(position), float64(numSegments-1))
+		fraction := Sub(position, segment)
+		segmentIdx := InsertAxes(ConvertDType(segment, dtypes.Int32), -1)
+		table := Const(g, samples)
+		before := ConvertDType(Gather(table, segmentIdx), dtypes.Float32)
+		after := ConvertDType(Gather(table, AddScalar(segmentIdx, 1)), dtypes.Float32)
+		scale := Add(before, Mul(fraction, Sub(after, before)))
+		return Mul(loss, ConvertDType(scale, dtype))
+	}
+}
@@ -0,0 +1,42 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gomlx/ml/train/optimizers"
+	"github.com/gomlx/gomlx/types/tensors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeAnnealedLoss(t *testing.T) {
+	backend := graphtest.BuildTestBackend()
+	// Linear warm-up of the loss weight from 0 to 1 over the first 100 steps.
+	linear := func(step int64) float64 { return min(float64(step)/100, 1) }
+	annealedLoss := func(ctx *context.Context, step int64) float32 {
+		optimizers.GetGlobalStepVar(ctx).SetValue(tensors.FromValue(step))
+		lossFn := MakeAnnealedLoss(MeanAbsoluteError, linear, ctx)
+		exec := context.NewExec(backend, ctx, func(ctx *context.Context, labels, predictions *Node) *Node {
+			return lossFn([]*Node{labels}, []*Node{predictions})
+		})
+		defer exec.Finalize()
+		// MeanAbsoluteError is 2.
+		return exec.Call([]float32{1, 2}, []float32{3, 0})[0].Value().(float32)
+	}
+
+	// Schedule sampled at every step.
+	ctx := context.New()
+	ctx.SetParam(ParamAnnealingSteps, 200)
+	assert.InDelta(t, 0.0, annealedLoss(ctx, 0), 1e-5)
+	assert.InDelta(t, 2*0.25, annealedLoss(ctx, 25), 1e-5)
+	assert.InDelta(t, 2*0.6, annealedLoss(ctx, 60), 1e-5)
+	assert.InDelta(t, 2.0, annealedLoss(ctx, 1000), 1e-5) // After ParamAnnealingSteps.
+
+	// Schedule interpolated between the sampled steps.
+	ctx = context.New()
+	ctx.SetParam(ParamAnnealingSteps, 10_000)
+	assert.InDelta(t, 2*0.37, annealedLoss(ctx, 37), 1e-5)
+	assert.InDelta(t, 2.0, annealedLoss(ctx, 5_003), 1e-5)
+}
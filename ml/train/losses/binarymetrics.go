@@ -0,0 +1,111 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+)
+
+// DefaultBinaryThreshold is the threshold used by FBetaScore and MatthewsCorrelation to decide the predicted class,
+// if none is given.
+const DefaultBinaryThreshold = 0.5
+
+// binaryConfusion returns the (weighted) counts of true positives, false positives, false negatives and true
+// negatives, with predictions[0] thresholded at threshold.
+//
+// It follows the conventions of BinaryCrossentropy: labels[0] (0 or 1) and predictions[0] (probabilities) must
+// have the same shape, and optional weights and mask can be given as extra labels.
+func binaryConfusion(labels, predictions []*Node, threshold float64) (tp, fp, fn, tn *Node) {
+	predictions0 := predictions[0]
+	dtype := predictions0.DType()
+	labels0 := convertLabels("FBetaScore/MatthewsCorrelation", labels[0], dtype)
+	if !labels0.Shape().Equal(predictions0.Shape()) {
+		Panicf("labels[0] (%s) and predictions[0] (%s) must have same shape", labels0.Shape(), predictions0.Shape())
+	}
+	weights, mask := CheckLabelsForWeightsAndMask(labels0.Shape(), labels)
+	isPositive := GreaterOrEqual(labels0, Scalar(predictions0.Graph(), dtype, 0.5))
+	predictedPositive := GreaterOrEqual(predictions0, Scalar(predictions0.Graph(), dtype, threshold))
+	count := func(cond *Node) *Node {
+		return ReduceAllSum(ApplyWeightsAndMask(ConvertDType(cond, dtype), weights, mask))
+	}
+	tp = count(LogicalAnd(isPositive, predictedPositive))
+	fp = count(LogicalAnd(LogicalNot(isPositive), predictedPositive))
+	fn = count(LogicalAnd(isPositive, LogicalNot(predictedPositive)))
+	tn = count(LogicalAnd(LogicalNot(isPositive), LogicalNot(predictedPositive)))
+	return
+}
+
+// binaryThreshold returns the optional threshold, or DefaultBinaryThreshold.
+func binaryThreshold(metricName string, threshold []float64) float64 {
+	switch len(threshold) {
+	case 0:
+		return DefaultBinaryThreshold
+	case 1:
+		return threshold[0]
+	default:
+		Panicf("%s takes at most one threshold, got %v", metricName, threshold)
+	}
+	return 0
+}
+
+// FBetaScore returns a metric function that computes the F-beta score of binary predictions:
+//
+//	(1+beta²) * TP / ((1+beta²) * TP + beta² * FN + FP)
+//
+// beta > 1 weights recall more than precision, and beta = 1 is the F1 score. The score is 0 if there are no
+// true positives.
+//
+// The metric follows the conventions of BinaryCrossentropy: labels[0] (0 or 1) and predictions[0] (probabilities)
+// must have the same shape, and optional weights and mask can be given as extra labels. An example is predicted
+// positive if its prediction is >= threshold, which defaults to DefaultBinaryThreshold if not given.
+//
+// It's a metric, not a loss: it returns a scalar, computed over all elements.
+func FBetaScore(beta float64, threshold ...float64) func(labels, predictions []*Node) *Node {
+	if beta <= 0 {
+		Panicf("FBetaScore requires beta > 0, got %g", beta)
+	}
+	t := binaryThreshold("FBetaScore", threshold)
+	beta2 := beta * beta
+	return func(labels, predictions []*Node) *Node {
+		tp, fp, fn, _ := binaryConfusion(labels, predictions, t)
+		g, dtype := tp.Graph(), tp.DType()
+		numerator := MulScalar(tp, 1+beta2)
+		denominator := Add(Add(numerator, MulScalar(fn, beta2)), fp)
+		return Div(numerator, Max(denominator, epsilonForDType(g, dtype)))
+	}
+}
+
+// MatthewsCorrelation returns a metric function that computes the Matthews correlation coefficient (MCC) of binary
+// predictions:
+//
+//	(TP*TN - FP*FN) / sqrt((TP+FP) * (TP+FN) * (TN+FP) * (TN+FN))
+//
+// It ranges from -1 to 1, and it is 0 if any of the sums in the denominator is 0. Contrary to accuracy, it's a
+// meaningful score for imbalanced classes.
+//
+// It follows the same conventions as FBetaScore, including the optional threshold.
+func MatthewsCorrelation(threshold ...float64) func(labels, predictions []*Node) *Node {
+	t := binaryThreshold("MatthewsCorrelation", threshold)
+	return func(labels, predictions []*Node) *Node {
+		tp, fp, fn, tn := binaryConfusion(labels, predictions, t)
+		g, dtype := tp.Graph(), tp.DType()
+		numerator := Sub(Mul(tp, tn), Mul(fp, fn))
+		denominator := Sqrt(Mul(Mul(Add(tp, fp), Add(tp, fn)), Mul(Add(tn, fp), Add(tn, fn))))
+		return Div(numerator, Max(denominator, epsilonForDType(g, dtype)))
+	}
+}
@@ -0,0 +1,37 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFBetaScoreAndMatthewsCorrelation(t *testing.T) {
+	require.Panics(t, func() { FBetaScore(0) })
+	require.Panics(t, func() { MatthewsCorrelation(0.3, 0.7) })
+	graphtest.RunTestGraphFn(t, "FBetaScore and MatthewsCorrelation", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, []float32{1, 1, 1, 0, 0, 0, 0, 0})
+		predictions := Const(g, []float32{0.9, 0.8, 0.3, 0.6, 0.1, 0.7, 0.4, 0.05})
+		mask := Const(g, []bool{true, true, true, true, true, false, true, true})
+		inputs = []*Node{labels, predictions}
+		// Confusion matrix: TP=2, FN=1, FP=2, TN=3.
+		outputs = []*Node{
+			FBetaScore(1)([]*Node{labels}, []*Node{predictions}),
+			FBetaScore(2)([]*Node{labels}, []*Node{predictions}),
+			MatthewsCorrelation()([]*Node{labels}, []*Node{predictions}),
+			// Masked: TP=2, FN=1, FP=1, TN=3.
+			MatthewsCorrelation()([]*Node{labels, mask}, []*Node{predictions}),
+			// Threshold 0.85: TP=1, FN=2, FP=0, TN=5.
+			FBetaScore(1, 0.85)([]*Node{labels}, []*Node{predictions}),
+		}
+		return
+	}, []any{
+		float32(4.0 / 7.0),   // 2*TP / (2*TP + FN + FP)
+		float32(10.0 / 16.0), // 5*TP / (5*TP + 4*FN + FP)
+		float32(0.25819889),  // (6-2) / sqrt(4*3*5*4)
+		float32(5.0 / 12.0),  // (6-1) / sqrt(3*3*4*4)
+		float32(0.5),
+	}, 1e-5)
+}
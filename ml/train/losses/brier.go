@@ -0,0 +1,61 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/shapes"
+)
+
+// BrierScoreLoss returns the multiclass Brier score of the predictions, per example: `sum((p - onehot)^2)` over the
+// classes (the last axis). It measures both the accuracy and the calibration of the predicted probabilities: it
+// ranges from 0 (perfect, confident predictions) to 2, and its expectation is minimized by calibrated probabilities.
+//
+// predictions[0] must be probabilities (e.g.: the output of a Softmax), and the labels can be given either:
+//
+//   - Sparse: integer labels[0] with the index of the true class, shaped like predictions with the last axis of
+//     dimension 1, or without the last axis -- as in SparseCategoricalCrossEntropy.
+//   - Dense: float labels[0] with the same shape as predictions, e.g. one-hot encoded -- as in
+//     CategoricalCrossEntropy.
+//
+// It *does not* reduce-mean the losses, they are returned individually for each example, shaped like predictions
+// without the last axis.
+//
+// If there is an extra `labels` `*Node` with the shape of predictions without the last axis, it assumed to be
+// weights to the losses. If there is an extra `labels` `*Node` with booleans with the same dimensions as predictions
+// without the last axis, it assumed to be a mask.
+//
+// See also metrics.NewMeanBrierScore to report it as a metric.
+func BrierScoreLoss(labels, predictions []*Node) *Node {
+	var labels0, predictions0, weights, mask *Node
+	if labels[0].DType().IsInt() {
+		labels0, predictions0, weights, mask = checkSparseLabels(labels, predictions)
+		labels0 = OneHot(Squeeze(labels0, -1), predictions0.Shape().Dim(-1), predictions0.DType())
+	} else {
+		predictions0 = predictions[0]
+		labels0 = convertLabels("BrierScoreLoss", labels[0], predictions0.DType())
+		if !labels0.Shape().Equal(predictions0.Shape()) {
+			Panicf("BrierScoreLoss: dense labels[0] (%s) and predictions[0] (%s) must have the same shape",
+				labels0.Shape(), predictions0.Shape())
+		}
+		weightsShape := shapes.Make(predictions0.DType(), predictions0.Shape().Dimensions[:predictions0.Rank()-1]...)
+		weights, mask = CheckLabelsForWeightsAndMask(weightsShape, labels)
+	}
+	losses := ReduceSum(Square(Sub(predictions0, labels0)), -1)
+	return ApplyWeightsAndMask(losses, weights, mask)
+}
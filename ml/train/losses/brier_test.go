@@ -0,0 +1,51 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrierScoreLoss(t *testing.T) {
+	ctx := context.New()
+	ctx.SetParam(ParamLoss, "brier")
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+
+	graphtest.RunTestGraphFn(t, "BrierScoreLoss", func(g *Graph) (inputs, outputs []*Node) {
+		predictions := Const(g, [][]float32{{0.7, 0.2, 0.1}, {0.1, 0.3, 0.6}, {0, 1, 0}})
+		labels := Const(g, []int32{0, 2, 1})
+		denseLabels := OneHot(labels, 3, dtypes.Float32)
+		weights := Const(g, []float32{1, 2, 3})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{
+			BrierScoreLoss([]*Node{labels}, []*Node{predictions}),
+			BrierScoreLoss([]*Node{denseLabels}, []*Node{predictions}),
+			contextLossFn([]*Node{labels}, []*Node{predictions}),
+			BrierScoreLoss([]*Node{labels, weights}, []*Node{predictions}),
+		}
+		return
+	}, []any{
+		// 0.3^2+0.2^2+0.1^2, 0.1^2+0.3^2+0.4^2, and 0 for the perfect prediction.
+		[]float32{0.14, 0.26, 0},
+		[]float32{0.14, 0.26, 0},
+		[]float32{0.14, 0.26, 0},
+		[]float32{0.14, 0.52, 0},
+	}, 1e-5)
+
+	// 3 examples of class 0 and one of class 1, all with the same predictions: the mean Brier score is minimized by
+	// the calibrated prediction [0.75, 0.25].
+	graphtest.RunTestGraphFn(t, "BrierScoreLoss calibration", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, []int32{0, 0, 0, 1})
+		for _, p := range []float32{0.9, 0.75, 0.6} {
+			predictions := BroadcastToDims(Const(g, []float32{p, 1 - p}), 4, 2)
+			outputs = append(outputs, ReduceAllMean(BrierScoreLoss([]*Node{labels}, []*Node{predictions})))
+		}
+		inputs = []*Node{labels}
+		return
+	}, []any{float32(0.42), float32(0.375), float32(0.42)}, 1e-5)
+}
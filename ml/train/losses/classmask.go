@@ -0,0 +1,66 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	"slices"
+
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+// ClassMaskedCategoricalCrossEntropyLogits is like CategoricalCrossEntropyLogits, but it also takes a boolean
+// class-validity mask, shaped like the logits, as an extra label: the invalid classes (where the class mask is false)
+// of each example are excluded from the softmax normalization, as if their logits were -inf. So the predicted
+// probability mass is only spread over the valid classes, and the invalid ones don't affect the loss.
+//
+// This is different from the (example level) mask accepted by CategoricalCrossEntropyLogits, which masks out whole
+// examples: both can be given. Labels set on invalid classes are ignored.
+//
+// The class mask is the extra label with dtype Bool and the same dimensions as logits. The other extra labels are
+// handled as in CategoricalCrossEntropyLogits, that is, weights and mask shaped as logits without the last axis.
+//
+// Examples with no valid class have a loss of 0.
+func ClassMaskedCategoricalCrossEntropyLogits(labels, logits []*Node) *Node {
+	logits0 := logits[0]
+	checkLogits("ClassMaskedCategoricalCrossEntropyLogits", logits0)
+	labels0 := labels[0]
+	if !labels0.Shape().Equal(logits0.Shape()) {
+		Panicf("labels(%s) and logits(%s) must have the same shapes", labels0.Shape(), logits0.Shape())
+	}
+	classMaskShape := shapes.Make(dtypes.Bool, logits0.Shape().Dimensions...)
+	classMaskIdx := slices.IndexFunc(labels[1:], func(extra *Node) bool { return extra.Shape().Equal(classMaskShape) })
+	if classMaskIdx == -1 {
+		Panicf("ClassMaskedCategoricalCrossEntropyLogits requires a class mask shaped %s as an extra label", classMaskShape)
+	}
+	classMask := labels[classMaskIdx+1]
+	otherLabels := slices.Delete(slices.Clone(labels), classMaskIdx+1, classMaskIdx+2)
+	weightsShape := shapes.Make(logits0.DType(), labels0.Shape().Dimensions[:labels0.Rank()-1]...)
+	weights, mask := CheckLabelsForWeightsAndMask(weightsShape, otherLabels)
+
+	// Examples without any valid class use all classes in the softmax (so its gradient is not NaN), but their
+	// loss is 0 anyway.
+	noValidClass := LogicalNot(LogicalAny(classMask, -1))
+	softmaxMask := LogicalOr(classMask, BroadcastToShape(InsertAxes(noValidClass, -1), classMaskShape))
+	// The log-probabilities of the invalid classes are -inf, so they are excluded from the sum.
+	logPredictions := MaskedLogSoftmax(logits0, softmaxMask)
+	zeros := ZerosLike(logPredictions)
+	losses := ReduceSum(Where(classMask, Neg(Mul(labels0, logPredictions)), zeros), -1)
+	return ApplyWeightsAndMask(losses, weights, mask)
+}
@@ -0,0 +1,44 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassMaskedCategoricalCrossEntropyLogits(t *testing.T) {
+	require.Panics(t, func() {
+		backend := graphtest.BuildTestBackend()
+		g := NewGraph(backend, "ClassMaskedCategoricalCrossEntropyLogits")
+		defer g.Finalize()
+		logits := Zeros(g, shapes.Make(dtypes.Float32, 2, 3))
+		// Missing class mask.
+		ClassMaskedCategoricalCrossEntropyLogits([]*Node{logits}, []*Node{logits})
+	})
+	graphtest.RunTestGraphFn(t, "ClassMaskedCategoricalCrossEntropyLogits", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, [][]float32{{1, 0, 0}, {0, 0, 1}, {0, 1, 0}, {1, 0, 0}})
+		logits := Const(g, [][]float32{
+			{2, 1, 100}, // Large invalid logit doesn't take probability mass.
+			{0, 0, 0},   // Labels only on the invalid class.
+			{1, 2, 3},   // No valid class.
+			{2, 1, 100}, // Masked out example.
+		})
+		classMask := Const(g, [][]bool{{true, true, false}, {true, true, false}, {false, false, false}, {true, true, false}})
+		mask := Const(g, []bool{true, true, true, false})
+		inputs = []*Node{labels, logits, classMask}
+		loss := ClassMaskedCategoricalCrossEntropyLogits([]*Node{labels, classMask, mask}, []*Node{logits})
+		// The gradient w.r.t. the logits is `probabilities - labels` on the valid classes: the invalid classes have
+		// zero probability, hence zero gradient.
+		grad := Gradient(ReduceAllSum(loss), logits)[0]
+		outputs = []*Node{loss, grad}
+		return
+	}, []any{
+		// log(1 + exp(-1)), as if the invalid class didn't exist.
+		[]float32{0.313262, 0, 0, 0},
+		[][]float32{{-0.268941, 0.268941, 0}, {0, 0, 0}, {0, 0, 0}, {0, 0, 0}},
+	}, 1e-4)
+}
@@ -0,0 +1,46 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+)
+
+// MakeClippedLoss returns a LossFn that clips the per-example (pre-reduction) loss returned by inner to
+// [0, maxPerExample], so no single example dominates the gradient.
+//
+// The clip boundary is wrapped in StopGradient, so examples whose loss is above maxPerExample contribute
+// the constant maxPerExample, and zero gradient.
+//
+// Notice this is a form of loss-space clipping, not gradient clipping: the gradients of the examples below
+// the threshold are not changed, no matter their norm.
+//
+// The inner loss should return the per-example losses (not yet reduced): if it returns a scalar, the clipping
+// applies to the reduced loss as a whole.
+func MakeClippedLoss(inner LossFn, maxPerExample float64) LossFn {
+	if maxPerExample <= 0 {
+		Panicf("MakeClippedLoss requires maxPerExample > 0, got %g", maxPerExample)
+	}
+	return func(labels, predictions []*Node) *Node {
+		losses := inner(labels, predictions)
+		g := losses.Graph()
+		zero := ScalarZero(g, losses.DType())
+		maxLoss := StopGradient(Scalar(g, losses.DType(), maxPerExample))
+		return Clip(losses, zero, maxLoss)
+	}
+}
@@ -0,0 +1,31 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeClippedLoss(t *testing.T) {
+	require.Panics(t, func() { MakeClippedLoss(MeanSquaredError, 0) })
+
+	// Per-example squared error.
+	squaredError := func(labels, predictions []*Node) *Node {
+		return Square(Sub(predictions[0], labels[0]))
+	}
+	graphtest.RunTestGraphFn(t, "MakeClippedLoss", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, []float32{0, 0, 0})
+		predictions := Const(g, []float32{0.5, 1, 3})
+		inputs = []*Node{labels, predictions}
+		loss := MakeClippedLoss(squaredError, 2)([]*Node{labels}, []*Node{predictions})
+		grad := Gradient(ReduceAllSum(loss), predictions)[0]
+		outputs = []*Node{loss, grad}
+		return
+	}, []any{
+		[]float32{0.25, 1, 2},
+		// The example above the threshold has zero gradient.
+		[]float32{1, 2, 0},
+	}, 1e-4)
+}
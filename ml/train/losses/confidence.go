@@ -0,0 +1,68 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	"slices"
+
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+)
+
+// MakeConfidencePenaltyLoss returns a LossFn that adds to the loss returned by inner the "confidence penalty"
+// `lambda * (-entropy(softmax(logits)))`, which penalizes overconfident (low-entropy) predictions and encourages
+// smoother distributions -- it helps calibration.
+//
+// See "Regularizing Neural Networks by Penalizing Confident Output Distributions", Pereyra et al., 2017
+// (https://arxiv.org/abs/1701.06548).
+//
+// predictions[0] must be logits, and the softmax is taken over its last axis.
+//
+// If inner returns the per-example losses (with the shape of predictions[0] without the last axis), the penalty is
+// added per example. If inner returns a scalar, the mean penalty is added. Any other shape panics.
+//
+// lambda must be >= 0: if it is 0, it's the same as inner.
+func MakeConfidencePenaltyLoss(inner LossFn, lambda float64) LossFn {
+	if lambda < 0 {
+		Panicf("MakeConfidencePenaltyLoss requires lambda >= 0, got %g", lambda)
+	}
+	if lambda == 0 {
+		return inner
+	}
+	return func(labels, predictions []*Node) *Node {
+		loss := inner(labels, predictions)
+		logits := predictions[0]
+		if logits.Rank() == 0 {
+			Panicf("MakeConfidencePenaltyLoss requires predictions[0] to be logits with at least one axis, got %s",
+				logits.Shape())
+		}
+		// Negative entropy: sum(p*log(p)), per example.
+		logProbs := LogSoftmax(logits)
+		negEntropy := ReduceSum(Mul(Exp(logProbs), logProbs), -1)
+		penalty := MulScalar(ConvertDType(negEntropy, loss.DType()), lambda)
+		switch {
+		case loss.IsScalar():
+			return Add(loss, ReduceAllMean(penalty))
+		case slices.Equal(loss.Shape().Dimensions, penalty.Shape().Dimensions):
+			return Add(loss, penalty)
+		default:
+			Panicf("MakeConfidencePenaltyLoss requires inner to return a scalar or the per-example losses shaped %v, got %s",
+				penalty.Shape().Dimensions, loss.Shape())
+		}
+		return nil
+	}
+}
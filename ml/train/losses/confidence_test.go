@@ -0,0 +1,30 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeConfidencePenaltyLoss(t *testing.T) {
+	require.Panics(t, func() { MakeConfidencePenaltyLoss(CategoricalCrossEntropyLogits, -1) })
+	zeroLoss := func(labels, predictions []*Node) *Node {
+		return ZerosLike(ReduceSum(predictions[0], -1))
+	}
+	graphtest.RunTestGraphFn(t, "MakeConfidencePenaltyLoss", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, [][]float32{{1, 0}, {1, 0}, {1, 0}})
+		// Uniform, mildly confident and very confident predictions.
+		logits := Const(g, [][]float32{{0, 0}, {1, 0}, {10, 0}})
+		inputs = []*Node{labels, logits}
+		penalty := MakeConfidencePenaltyLoss(zeroLoss, 0.5)([]*Node{labels}, []*Node{logits})
+		sharper := GreaterThan(Slice(penalty, AxisRange(1)), Slice(penalty, AxisRange(0, 2)))
+		outputs = []*Node{penalty, sharper}
+		return
+	}, []any{
+		// 0.5 * sum(p*log(p)), with p = softmax(logits).
+		[]float32{-0.34657359, -0.29110155, -0.00024969},
+		[]bool{true, true},
+	}, 1e-4)
+}
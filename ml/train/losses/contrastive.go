@@ -0,0 +1,149 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/ml/context"
+)
+
+var (
+	// ParamContrastiveMargin is the name of the hyperparameter that defines the margin of the contrastive loss.
+	// It defaults to 1.0
+	//
+	// See MakeContrastiveLoss and MakeContrastiveLossFromContext.
+	ParamContrastiveMargin = "contrastive_margin"
+
+	// ParamHingeEmbeddingMargin is the name of the hyperparameter that defines the margin of the hinge embedding loss.
+	// It defaults to 1.0
+	//
+	// See MakeHingeEmbeddingLoss and MakeHingeEmbeddingLossFromContext.
+	ParamHingeEmbeddingMargin = "hinge_embedding_margin"
+)
+
+// MakeContrastiveLoss returns a pairwise contrastive loss function (Hadsell et al.), typically used to train
+// siamese networks, see "Dimensionality Reduction by Learning an Invariant Mapping",
+// http://yann.lecun.com/exdb/publis/pdf/hadsell-chopra-lecun-06.pdf
+//
+// For the returned loss function:
+//   - labels[0] is 1 for similar pairs, and 0 for dissimilar pairs. It is converted to the predictions dtype,
+//     so booleans work also.
+//   - If only predictions[0] is given, it is taken as the distance d between the pair of embeddings.
+//   - If predictions[0] and predictions[1] are given, they are taken as the pair of embeddings, shaped
+//     `[batch_size, embed_dim]`, and the Euclidean distance d is calculated over the last axis.
+//   - The loss is `y*d^2 + (1-y)*max(0, margin-d)^2`, per example. It is not reduced.
+//   - If there is an extra element in the input labels with the shape of the distances (usually simply `[bath_size]`),
+//     it is assumed to be weights tensor to be applied to the losses.
+//   - If there is an extra element in the input labels  with booleans and the same dimensions as the distances
+//     (usually simply `batch_size`), it assumed to be a mask tensor to be applied to the losses.
+func MakeContrastiveLoss(margin float64) LossFn {
+	if margin <= 0 {
+		Panicf("MakeContrastiveLoss requires margin > 0, margin=%f given", margin)
+	}
+	return func(labels, predictions []*Node) (loss *Node) {
+		var distances *Node
+		switch len(predictions) {
+		case 1:
+			distances = predictions[0]
+		case 2:
+			distances = euclideanDistance(predictions[0], predictions[1])
+		default:
+			Panicf("contrastive loss takes either the distances or the pair of embeddings as predictions, got %d predictions",
+				len(predictions))
+		}
+		labels0 := convertLabels("MakeContrastiveLoss", labels[0], distances.DType())
+		if labels0.Shape().Size() != distances.Shape().Size() {
+			Panicf("labels[0] (%s) and distances (%s) have incompatible shapes", labels0.Shape(), distances.Shape())
+		}
+		if labels0.Rank() != distances.Rank() {
+			labels0 = Reshape(labels0, distances.Shape().Dimensions...)
+		}
+		weights, mask := CheckLabelsForWeightsAndMask(distances.Shape(), labels)
+
+		similarLoss := Square(distances)
+		dissimilarLoss := Square(MaxScalar(AddScalar(Neg(distances), margin), 0))
+		loss = Add(
+			Mul(labels0, similarLoss),
+			Mul(OneMinus(labels0), dissimilarLoss))
+
+		// Apply weights and mask.
+		loss = ApplyWeightsAndMask(loss, weights, mask)
+		return loss
+	}
+}
+
+// euclideanDistance between the embeddings x and y over the last axis. It's safe for the gradient, even
+// when the distance is 0.
+func euclideanDistance(x, y *Node) *Node {
+	if !x.Shape().Equal(y.Shape()) {
+		Panicf("pair of embeddings must have the same shape, got %s and %s", x.Shape(), y.Shape())
+	}
+	g := x.Graph()
+	dtype := x.DType()
+	squaredDistances := ReduceSum(Square(Sub(x, y)), -1)
+	// Because the gradient of sqrt is infinite when distances == 0.0, we add a small epsilon where
+	// distances == 0.0, and then set them back to 0.
+	eps := epsilonForDType(g, dtype)
+	zeroMask := LessThan(squaredDistances, eps)
+	distances := Sqrt(Where(zeroMask, BroadcastToShape(eps, squaredDistances.Shape()), squaredDistances))
+	return Where(zeroMask, ZerosLike(distances), distances)
+}
+
+// MakeContrastiveLossFromContext calls MakeContrastiveLoss using the margin configured by the hyperparameter
+// ParamContrastiveMargin in the context.
+func MakeContrastiveLossFromContext(ctx *context.Context) LossFn {
+	margin := context.GetParamOr(ctx, ParamContrastiveMargin, 1.0)
+	return MakeContrastiveLoss(margin)
+}
+
+// MakeHingeEmbeddingLoss returns a hinge embedding loss function, for similarity learning. It matches
+// PyTorch's `HingeEmbeddingLoss`.
+//
+// For the returned loss function:
+//   - labels[0] is +1 for similar (positive) pairs, and -1 for dissimilar (negative) pairs.
+//   - predictions[0] is the distance d between the pairs, with the same number of elements as labels[0].
+//   - The loss is d for positive pairs, and `max(0, margin-d)` for negative pairs, per example. It is not reduced.
+//   - If there is an extra element in the input labels with the shape of the distances (usually simply `[bath_size]`),
+//     it is assumed to be weights tensor to be applied to the losses.
+//   - If there is an extra element in the input labels  with booleans and the same dimensions as the distances
+//     (usually simply `batch_size`), it assumed to be a mask tensor to be applied to the losses.
+func MakeHingeEmbeddingLoss(margin float64) LossFn {
+	return func(labels, predictions []*Node) (loss *Node) {
+		distances := predictions[0]
+		labels0 := convertLabels("MakeHingeEmbeddingLoss", labels[0], distances.DType())
+		if labels0.Shape().Size() != distances.Shape().Size() {
+			Panicf("labels[0] (%s) and predictions[0] (%s) have incompatible shapes", labels0.Shape(), distances.Shape())
+		}
+		if labels0.Rank() != distances.Rank() {
+			labels0 = Reshape(labels0, distances.Shape().Dimensions...)
+		}
+		weights, mask := CheckLabelsForWeightsAndMask(distances.Shape(), labels)
+		loss = Where(GreaterThan(labels0, ZerosLike(labels0)),
+			distances,
+			MaxScalar(AddScalar(Neg(distances), margin), 0))
+		loss = ApplyWeightsAndMask(loss, weights, mask)
+		return loss
+	}
+}
+
+// MakeHingeEmbeddingLossFromContext calls MakeHingeEmbeddingLoss using the margin configured by the hyperparameter
+// ParamHingeEmbeddingMargin in the context.
+func MakeHingeEmbeddingLossFromContext(ctx *context.Context) LossFn {
+	margin := context.GetParamOr(ctx, ParamHingeEmbeddingMargin, 1.0)
+	return MakeHingeEmbeddingLoss(margin)
+}
@@ -0,0 +1,51 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+)
+
+func TestContrastiveLoss(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "MakeContrastiveLoss", func(g *Graph) (inputs, outputs []*Node) {
+		lossFn := MakeContrastiveLoss(1.0)
+		// Distances around the margin (1.0), for similar (1) and dissimilar (0) pairs.
+		distances := Const(g, []float32{0, 1, 0.5, 1, 0.5, 1.5, 0.999})
+		labels := Const(g, []float32{1, 1, 1, 0, 0, 0, 0})
+
+		// Pairs of embeddings at distance 1.0 (exactly the margin) and at distance 0.
+		embeddings0 := Const(g, [][]float32{{0, 0}, {0, 0}, {0.3, 0.4}})
+		embeddings1 := Const(g, [][]float32{{0.6, 0.8}, {0.6, 0.8}, {0.3, 0.4}})
+		pairLabels := Const(g, []bool{true, false, false})
+		pairMask := Const(g, []bool{true, true, false})
+		inputs = []*Node{distances, labels}
+		outputs = []*Node{
+			lossFn([]*Node{labels}, []*Node{distances}),
+			lossFn([]*Node{pairLabels, pairMask}, []*Node{embeddings0, embeddings1}),
+		}
+		return
+	}, []any{
+		[]float32{0, 1, 0.25, 0, 0.25, 0, 1e-6},
+		[]float32{1, 0, 0},
+	}, 1e-4)
+}
+
+func TestHingeEmbeddingLoss(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "MakeHingeEmbeddingLoss", func(g *Graph) (inputs, outputs []*Node) {
+		lossFn := MakeHingeEmbeddingLoss(1.0)
+		distances := Const(g, []float32{0, 0.5, 1, 2, 0, 0.5, 1, 2})
+		labels := Const(g, []int32{1, 1, 1, 1, -1, -1, -1, -1})
+		weights := Const(g, []float32{1, 1, 1, 3, 1, 2, 1, 1})
+		inputs = []*Node{distances, labels}
+		outputs = []*Node{
+			lossFn([]*Node{labels}, []*Node{distances}),
+			lossFn([]*Node{labels, weights}, []*Node{distances}),
+		}
+		return
+	}, []any{
+		// Positive pairs: the distance; negative pairs: max(0, margin-d), zero at and beyond the margin.
+		[]float32{0, 0.5, 1, 2, 1, 0.5, 0, 0},
+		[]float32{0, 0.5, 1, 6, 1, 1, 0, 0},
+	}, 1e-5)
+}
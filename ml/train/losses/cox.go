@@ -0,0 +1,75 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+)
+
+// CoxPartialLikelihoodLoss returns the negative log partial likelihood of the Cox proportional-hazards model, for
+// time-to-event (survival analysis) modeling.
+//
+// labels[0] are the event (or censoring) times, labels[1] is the event indicator (true or 1 if the event was
+// observed, false or 0 if the example was censored), and predictions[0] are the risk scores (log hazard ratios).
+// They must all have the same shape, either `[batch_size]` or `[batch_size, 1]`.
+//
+// For each example i with an observed event, the loss is:
+//
+//	log(sum_{j in R(t_i)} exp(score_j)) - score_i
+//
+// Where the risk set R(t_i) is the examples still at risk at time t_i, that is, with t_j >= t_i -- so ties are
+// handled with Breslow's method. Censored examples have a loss of 0, but they take part in the risk sets of the
+// earlier events. The batch is the cohort: the risk sets only include examples of the same batch.
+//
+// It *does not* reduce the losses: ReduceAllMean of the returned losses is the negative log partial likelihood
+// divided by the batch size (and not by the number of events, as sometimes used).
+//
+// The risk sets are computed by pairwise comparison of the times (instead of sorting), so it takes O(batch_size^2)
+// memory.
+func CoxPartialLikelihoodLoss(labels, predictions []*Node) *Node {
+	if len(labels) != 2 {
+		Panicf("CoxPartialLikelihoodLoss requires 2 labels (times and event indicator), got %d", len(labels))
+	}
+	scores := predictions[0]
+	dtype := scores.DType()
+	times := convertLabels("CoxPartialLikelihoodLoss", labels[0], dtype)
+	events := labels[1]
+	for ii, node := range []*Node{times, events} {
+		if !node.Shape().EqualDimensions(scores.Shape()) {
+			Panicf("CoxPartialLikelihoodLoss requires labels[%d] (%s) to have the same dimensions as predictions[0] (%s)",
+				ii, node.Shape(), scores.Shape())
+		}
+	}
+	if scores.Rank() == 0 || scores.Rank() > 2 || (scores.Rank() == 2 && scores.Shape().Dim(1) != 1) {
+		Panicf("CoxPartialLikelihoodLoss requires predictions shaped [batch_size] or [batch_size, 1], got %s", scores.Shape())
+	}
+	n := scores.Shape().Size()
+	flatScores := Reshape(scores, n)
+	times = StopGradient(Reshape(times, n))
+	events = StopGradient(ConvertDType(Reshape(events, n), dtype))
+
+	// atRisk[i, j] is true if example j is in the risk set of example i: t_j >= t_i.
+	atRisk := GreaterOrEqual(BroadcastToDims(InsertAxes(times, 0), n, n), BroadcastToDims(InsertAxes(times, -1), n, n))
+	riskScores := BroadcastToDims(InsertAxes(flatScores, 0), n, n)
+	// Log-sum-exp over the risk sets (never empty, since each example is in its own risk set), shifted by its max.
+	maxRiskScores := StopGradient(MaskedReduceMax(riskScores, atRisk, -1))
+	shifted := Exp(Sub(riskScores, InsertAxes(maxRiskScores, -1)))
+	logSumExp := Add(Log(MaskedReduceSum(shifted, atRisk, -1)), maxRiskScores)
+	losses := Mul(events, Sub(logSumExp, flatScores))
+	return Reshape(losses, scores.Shape().Dimensions...)
+}
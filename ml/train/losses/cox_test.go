@@ -0,0 +1,36 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoxPartialLikelihoodLoss(t *testing.T) {
+	ctx := context.New()
+	ctx.SetParam(ParamLoss, TypeCox.String())
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+
+	graphtest.RunTestGraphFn(t, "CoxPartialLikelihoodLoss", func(g *Graph) (inputs, outputs []*Node) {
+		// Cohort of 5, with a censored example and a tie at time 2.
+		times := Const(g, []float32{1, 2, 3, 4, 2})
+		events := Const(g, []bool{true, false, true, true, true})
+		scores := Const(g, []float32{0.5, -0.2, 1.0, 0.1, 0.3})
+		inputs = []*Node{times, events, scores}
+		outputs = []*Node{
+			CoxPartialLikelihoodLoss([]*Node{times, events}, []*Node{scores}),
+			// Shaped [batch_size, 1], with a float event indicator.
+			contextLossFn(
+				[]*Node{InsertAxes(times, -1), InsertAxes(ConvertDType(events, scores.DType()), -1)},
+				[]*Node{InsertAxes(scores, -1)}),
+		}
+		return
+	}, []any{
+		[]float32{1.533498, 0, 0.341154, 0, 1.490432},
+		[][]float32{{1.533498}, {0}, {0.341154}, {0}, {1.490432}},
+	}, 1e-4)
+}
@@ -0,0 +1,120 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gomlx/types/xslices"
+)
+
+var (
+	// ParamDiceWeight is the name of the hyperparameter that defines the weight of the Dice component of the
+	// loss "dice_ce". It defaults to 1.0.
+	ParamDiceWeight = "dice_weight"
+
+	// ParamCrossEntropyWeight is the name of the hyperparameter that defines the weight of the cross-entropy
+	// component of the loss "dice_ce". It defaults to 1.0.
+	ParamCrossEntropyWeight = "cross_entropy_weight"
+
+	// ParamDiceSmooth is the name of the hyperparameter that defines the smoothing term of the Dice loss, added
+	// to the numerator and denominator. It defaults to 1.0.
+	ParamDiceSmooth = "dice_smooth"
+)
+
+// diceLossFromProbabilities returns the per-example soft Dice loss, averaged over the classes, given the
+// probabilities and the dense labels, both shaped `[batch_size, <spatial dimensions...>, num_classes]`.
+func diceLossFromProbabilities(labels, probabilities *Node, smooth float64) *Node {
+	// Reduce the spatial axes, if any, keeping batch and class axes.
+	spatialAxes := xslices.Iota(1, labels.Rank()-2)
+	intersection := Mul(labels, probabilities)
+	sums := Add(labels, probabilities)
+	if len(spatialAxes) > 0 {
+		intersection = ReduceSum(intersection, spatialAxes...)
+		sums = ReduceSum(sums, spatialAxes...)
+	}
+	dice := Div(AddScalar(MulScalar(intersection, 2), smooth), AddScalar(sums, smooth))
+	return OneMinus(ReduceMean(dice, -1))
+}
+
+// checkDenseSegmentationInputs checks that labels and logits have the same shape, and returns the first labels,
+// logits and the optional weights and mask, shaped `[batch_size]`.
+func checkDenseSegmentationInputs(labels, logits []*Node) (labels0, logits0, weights, mask *Node) {
+	labels0, logits0 = labels[0], logits[0]
+	if !labels0.Shape().Equal(logits0.Shape()) {
+		Panicf("labels(%s) and logits(%s) must have the same shapes", labels0.Shape(), logits0.Shape())
+	}
+	if logits0.Rank() < 2 {
+		Panicf("logits(%s) must be shaped [batch_size, <spatial dimensions...>, num_classes]", logits0.Shape())
+	}
+	weightsShape := shapes.Make(logits0.DType(), logits0.Shape().Dimensions[0])
+	weights, mask = CheckLabelsForWeightsAndMask(weightsShape, labels)
+	return
+}
+
+// MakeDiceLoss returns the soft Dice loss, `1 - (2*|y·p| + smooth) / (|y| + |p| + smooth)`, computed from the
+// logits (converted to probabilities with a softmax) and dense labels (e.g.: one-hot encoded), both shaped
+// `[batch_size, <spatial dimensions...>, num_classes]`. The Dice coefficient is computed per class (reducing
+// over the spatial dimensions) and averaged over the classes, so it returns one loss per example.
+//
+// The smooth term avoids the division by zero for empty classes. A typical value is 1.0.
+//
+// Optional weights and mask, shaped `[batch_size]`, can be given as extra labels, see CheckLabelsForWeightsAndMask.
+func MakeDiceLoss(smooth float64) LossFn {
+	return func(labels, logits []*Node) *Node {
+		labels0, logits0, weights, mask := checkDenseSegmentationInputs(labels, logits)
+		losses := diceLossFromProbabilities(labels0, Softmax(logits0), smooth)
+		return ApplyWeightsAndMask(losses, weights, mask)
+	}
+}
+
+// MakeDiceCrossEntropyLoss returns the weighted sum of the soft Dice loss (see MakeDiceLoss) and the categorical
+// cross-entropy (see CategoricalCrossEntropyLogits, averaged over the spatial dimensions), commonly used for
+// segmentation:
+//
+//	diceWeight * Dice(labels, softmax(logits)) + ceWeight * CrossEntropy(labels, logits)
+//
+// Both components share the conversion of the logits to (log-)probabilities. It returns one loss per example.
+//
+// Optional weights and mask, shaped `[batch_size]`, can be given as extra labels, see CheckLabelsForWeightsAndMask.
+func MakeDiceCrossEntropyLoss(diceWeight, ceWeight, smooth float64) LossFn {
+	if diceWeight < 0 || ceWeight < 0 {
+		Panicf("MakeDiceCrossEntropyLoss requires non-negative weights, got diceWeight=%g, ceWeight=%g", diceWeight, ceWeight)
+	}
+	return func(labels, logits []*Node) *Node {
+		labels0, logits0, weights, mask := checkDenseSegmentationInputs(labels, logits)
+		logProbabilities := LogSoftmax(logits0)
+		dice := diceLossFromProbabilities(labels0, Exp(logProbabilities), smooth)
+		crossEntropy := Neg(ReduceSum(Mul(labels0, logProbabilities), -1))
+		if crossEntropy.Rank() > 1 {
+			crossEntropy = ReduceMean(crossEntropy, xslices.Iota(1, crossEntropy.Rank()-1)...)
+		}
+		losses := Add(MulScalar(dice, diceWeight), MulScalar(crossEntropy, ceWeight))
+		return ApplyWeightsAndMask(losses, weights, mask)
+	}
+}
+
+// MakeDiceCrossEntropyLossFromContext calls MakeDiceCrossEntropyLoss using the weights and smoothing configured by
+// the hyperparameters ParamDiceWeight, ParamCrossEntropyWeight and ParamDiceSmooth in the context.
+func MakeDiceCrossEntropyLossFromContext(ctx *context.Context) LossFn {
+	diceWeight := context.GetParamOr(ctx, ParamDiceWeight, 1.0)
+	ceWeight := context.GetParamOr(ctx, ParamCrossEntropyWeight, 1.0)
+	smooth := context.GetParamOr(ctx, ParamDiceSmooth, 1.0)
+	return MakeDiceCrossEntropyLoss(diceWeight, ceWeight, smooth)
+}
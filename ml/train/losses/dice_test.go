@@ -0,0 +1,54 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeDiceCrossEntropyLoss(t *testing.T) {
+	ctx := context.New()
+	ctx.SetParams(map[string]any{ParamLoss: "dice_ce", ParamDiceWeight: 0.7, ParamCrossEntropyWeight: 0.3, ParamDiceSmooth: 1.0})
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+	require.Panics(t, func() { MakeDiceCrossEntropyLoss(-1, 1, 1) })
+
+	graphtest.RunTestGraphFn(t, "MakeDiceCrossEntropyLoss: known values", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, [][]float32{{1, 0}})
+		logits := Const(g, [][]float32{{0, 0}})
+		inputs = []*Node{labels, logits}
+		outputs = []*Node{
+			MakeDiceLoss(1)([]*Node{labels}, []*Node{logits}),
+			MakeDiceCrossEntropyLoss(1, 1, 1)([]*Node{labels}, []*Node{logits}),
+		}
+		return
+	}, []any{
+		// Dice per class: (2*0.5+1)/(1+0.5+1)=0.8 and 1/(0.5+1)=0.6667.
+		[]float32{1 - (0.8+2.0/3.0)/2},
+		// Plus cross-entropy ln(2).
+		[]float32{1 - (0.8+2.0/3.0)/2 + 0.693147},
+	}, 1e-4)
+
+	// Shaped [batch_size=2, spatial=3, num_classes=2].
+	labelsValues := [][][]float32{{{1, 0}, {0, 1}, {1, 0}}, {{0, 1}, {0, 1}, {1, 0}}}
+	logitsValues := [][][]float32{{{2, -1}, {0.5, 0.5}, {-1, 1}}, {{0, 3}, {1, 0}, {2, 2}}}
+	graphtest.RunTestGraphFn(t, "MakeDiceCrossEntropyLoss vs components", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, labelsValues)
+		logits := Const(g, logitsValues)
+		weights := Const(g, []float32{1, 2})
+		inputs = []*Node{labels, logits}
+		dice := MakeDiceLoss(1)([]*Node{labels}, []*Node{logits})
+		crossEntropy := ReduceMean(CategoricalCrossEntropyLogits([]*Node{labels}, []*Node{logits}), 1)
+		manual := Add(MulScalar(dice, 0.7), MulScalar(crossEntropy, 0.3))
+		maxDiff := func(a, b *Node) *Node { return ReduceAllMax(Abs(Sub(a, b))) }
+		outputs = []*Node{
+			maxDiff(manual, MakeDiceCrossEntropyLoss(0.7, 0.3, 1)([]*Node{labels}, []*Node{logits})),
+			maxDiff(manual, contextLossFn([]*Node{labels}, []*Node{logits})),
+			maxDiff(Mul(manual, weights), MakeDiceCrossEntropyLoss(0.7, 0.3, 1)([]*Node{labels, weights}, []*Node{logits})),
+		}
+		return
+	}, []any{float32(0), float32(0), float32(0)}, 1e-5)
+}
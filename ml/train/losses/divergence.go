@@ -0,0 +1,57 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/shapes"
+)
+
+// KLDivergenceLogitsBoth returns the Kullback-Leibler divergence KL(P||Q) between the distribution P, given by the
+// logits in labels[0], and the distribution Q, given by the logits in predictions[0]. Both are normalized with a
+// softmax over the last axis, and the divergence is calculated in log-space for numerical stability:
+// `sum(softmax(p_logits) * (log_softmax(p_logits) - log_softmax(q_logits)))`.
+//
+// This is useful for distillation (labels are the teacher's logits) and on-policy reinforcement learning.
+// Notice that the gradient flows also to the labels, use StopGradient if not desired.
+//
+// labels[0] and predictions[0] must have the same shape.
+//
+// It *does not* reduce-mean the losses, they are returned individually for each element of the batch and need
+// to be ReduceAllMean (usually the mean, but it could be the sum also) before used for training.
+//
+// If there is an extra `labels` `*Node` with the shape of logits without the last axis (usually simply `[bath_size]`),
+// it assumed to be weights to the losses.
+// If there is an extra `labels` `*Node` with booleans with the same dimensions as logits without the last axis
+// (usually simply `batch_size`), it assumed to be a mask.
+func KLDivergenceLogitsBoth(labels, predictions []*Node) *Node {
+	pLogits := labels[0]
+	qLogits := predictions[0]
+	if !pLogits.Shape().Equal(qLogits.Shape()) {
+		Panicf("KLDivergenceLogitsBoth requires labels[0] (%s) and predictions[0] (%s) logits with the same shape",
+			pLogits.Shape(), qLogits.Shape())
+	}
+	weightsShape := shapes.Make(qLogits.DType(), qLogits.Shape().Dimensions[:qLogits.Rank()-1]...)
+	weights, mask := CheckLabelsForWeightsAndMask(weightsShape, labels)
+
+	pLogProbs := LogSoftmax(pLogits)
+	qLogProbs := LogSoftmax(qLogits)
+	losses := ReduceSum(Mul(Exp(pLogProbs), Sub(pLogProbs, qLogProbs)), -1)
+	losses = ApplyWeightsAndMask(losses, weights, mask)
+	return losses
+}
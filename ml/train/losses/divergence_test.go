@@ -0,0 +1,29 @@
+package losses
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+)
+
+func TestKLDivergenceLogitsBoth(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "KLDivergenceLogitsBoth", func(g *Graph) (inputs, outputs []*Node) {
+		pLogits := Const(g, [][]float32{{1, 2, 3}, {0, 0, 0}, {-5, 10, 0}})
+		// Same logits up to an additive constant, per example.
+		qLogitsShifted := Add(pLogits, Const(g, [][]float32{{7}, {-3}, {100}}))
+		qLogits := Const(g, [][]float32{{0, 0, 0}, {0, float32(math.Log(3)), 0}, {0, 0, 0}})
+		mask := Const(g, []bool{false, true, false})
+		inputs = []*Node{pLogits, qLogits}
+		outputs = []*Node{
+			KLDivergenceLogitsBoth([]*Node{pLogits}, []*Node{qLogitsShifted}),
+			KLDivergenceLogitsBoth([]*Node{pLogits, mask}, []*Node{qLogits}),
+		}
+		return
+	}, []any{
+		[]float32{0, 0, 0},
+		// P=[1/3, 1/3, 1/3], Q=[1/5, 3/5, 1/5]: KL=(2*ln(5/3) + ln(5/9))/3
+		[]float32{0, float32((2*math.Log(5.0/3.0) + math.Log(5.0/9.0)) / 3), 0},
+	}, 1e-4)
+}
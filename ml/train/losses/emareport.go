@@ -0,0 +1,79 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+const (
+	// EMALossReportScope is the scope used by MakeEMASmoothedLossReport to store its variables.
+	EMALossReportScope = "ema_loss_report"
+
+	// EMALossVariableName is the name of the variable, under EMALossReportScope, holding the exponential moving
+	// average of the loss.
+	EMALossVariableName = "ema"
+
+	// EMALossCountVariableName is the name of the variable, under EMALossReportScope, holding the number of
+	// updates of the exponential moving average of the loss.
+	EMALossCountVariableName = "count"
+)
+
+// MakeEMASmoothedLossReport returns a LossFn that returns the loss of inner unchanged, but additionally updates
+// an exponential moving average (EMA) of the (mean) loss, stored in the context as a non-trainable variable
+// (see EMALossReportScope and EMALossVariableName), providing a ready smoothed metric of training.
+//
+// At each update: ema = decay * ema + (1 - decay) * loss. The EMA is initialized with the first loss value,
+// so it doesn't start biased towards 0. The loss is wrapped in StopGradient, so the EMA doesn't affect
+// the gradients.
+//
+// The EMA is only updated when the context is in training mode (see context.Context.IsTraining), so evaluations
+// don't affect it. To keep more than one EMA, use different scopes for ctx.
+//
+// Use GetEMALossVar to read the current value.
+func MakeEMASmoothedLossReport(inner LossFn, decay float64, ctx *context.Context) LossFn {
+	if decay < 0 || decay >= 1 {
+		Panicf("MakeEMASmoothedLossReport requires 0 <= decay < 1, got %g", decay)
+	}
+	return func(labels, predictions []*Node) (loss *Node) {
+		loss = inner(labels, predictions)
+		g := loss.Graph()
+		if !ctx.IsTraining(g) {
+			return loss
+		}
+		value := ConvertDType(ReduceAllMean(StopGradient(loss)), dtypes.Float32)
+		emaVar := GetEMALossVar(ctx)
+		countVar := ctx.In(EMALossReportScope).Checked(false).
+			VariableWithValue(EMALossCountVariableName, int64(0)).SetTrainable(false)
+		ema := emaVar.ValueGraph(g)
+		count := countVar.ValueGraph(g)
+		updated := Add(MulScalar(ema, decay), MulScalar(value, 1-decay))
+		emaVar.SetValueGraph(Where(Equal(count, ZerosLike(count)), value, updated))
+		countVar.SetValueGraph(AddScalar(count, 1))
+		return loss
+	}
+}
+
+// GetEMALossVar returns the variable holding the exponential moving average of the loss maintained by
+// MakeEMASmoothedLossReport, creating it (with value 0) if it doesn't exist yet.
+func GetEMALossVar(ctx *context.Context) *context.Variable {
+	return ctx.In(EMALossReportScope).Checked(false).
+		VariableWithValue(EMALossVariableName, float32(0)).SetTrainable(false)
+}
@@ -0,0 +1,46 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeEMASmoothedLossReport(t *testing.T) {
+	require.Panics(t, func() { MakeEMASmoothedLossReport(MeanAbsoluteError, 1, context.New()) })
+
+	backend := graphtest.BuildTestBackend()
+	ctx := context.New()
+	lossFn := MakeEMASmoothedLossReport(MeanAbsoluteError, 0.9, ctx)
+	newExec := func(training bool) *context.Exec {
+		return context.NewExec(backend, ctx, func(ctx *context.Context, labels, predictions *Node) *Node {
+			ctx.SetTraining(labels.Graph(), training)
+			return lossFn([]*Node{labels}, []*Node{predictions})
+		})
+	}
+	trainExec, evalExec := newExec(true), newExec(false)
+	defer trainExec.Finalize()
+	defer evalExec.Finalize()
+	ema := func() float32 { return GetEMALossVar(ctx).Value().Value().(float32) }
+
+	// Loss is returned unchanged, and the EMA is initialized with the first value.
+	loss := trainExec.Call([]float32{0}, []float32{1})[0]
+	assert.Equal(t, float32(1), loss.Value())
+	assert.Equal(t, float32(1), ema())
+
+	// Losses alternate between 3 and 1: the EMA approaches their mean.
+	for ii := range 100 {
+		trainExec.Call([]float32{0}, []float32{float32(3 - 2*(ii%2))})
+	}
+	assert.InDelta(t, 2.0, ema(), 0.1)
+
+	// Evaluation doesn't change the EMA.
+	before := ema()
+	loss = evalExec.Call([]float32{0}, []float32{100})[0]
+	assert.Equal(t, float32(100), loss.Value())
+	assert.Equal(t, before, ema())
+}
@@ -0,0 +1,123 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// FDivKind selects the f-divergence computed by MakeFDivergenceLoss.
+type FDivKind int
+
+//go:generate enumer -type=FDivKind -trimprefix=FDivKind -transform=snake -values -text -json -yaml fdivergence.go
+
+const (
+	// FDivKindKL is the Kullback-Leibler divergence KL(P||Q), with f(t) = t*log(t).
+	FDivKindKL FDivKind = iota
+
+	// FDivKindReverseKL is the reverse Kullback-Leibler divergence KL(Q||P), with f(t) = -log(t).
+	FDivKindReverseKL
+
+	// FDivKindPearson is the Pearson χ² divergence, with f(t) = (t-1)².
+	FDivKindPearson
+
+	// FDivKindHellinger is the squared Hellinger distance, with f(t) = (√t-1)².
+	FDivKindHellinger
+
+	// FDivKindTotalVariation is the total variation distance, with f(t) = ½|t-1|.
+	FDivKindTotalVariation
+)
+
+// ParamFDivKind is the name of the hyperparameter that defines the FDivKind used by MakeFDivergenceLoss, when
+// ParamLoss is "f_divergence". It can be given as a FDivKind or as its string (e.g.: "reverse_kl").
+// It defaults to FDivKindKL.
+var ParamFDivKind = "f_div_kind"
+
+// MakeFDivergenceLoss returns a loss that computes the f-divergence D_f(P||Q) = sum_x(q(x) * f(p(x)/q(x))) of the
+// kind given, between the distribution P, given by the logits in labels[0], and the distribution Q, given by the
+// logits in predictions[0]. Both are normalized with a softmax over the last axis, as in KLDivergenceLogitsBoth
+// -- which is the same as FDivKindKL.
+//
+// The divergences are computed directly from the generator f, in log-space where possible, not through the
+// variational lower bound given by the convex conjugate f*, since both distributions are available.
+//
+// labels[0] and predictions[0] must have the same shape, and the returned losses are shaped as predictions[0]
+// without the last axis. Weights and mask are taken from the extra labels, as in KLDivergenceLogitsBoth.
+func MakeFDivergenceLoss(kind FDivKind) LossFn {
+	if !kind.IsAFDivKind() {
+		Panicf("MakeFDivergenceLoss: invalid FDivKind %s", kind)
+	}
+	return func(labels, predictions []*Node) *Node {
+		pLogits := labels[0]
+		qLogits := predictions[0]
+		if !pLogits.Shape().Equal(qLogits.Shape()) {
+			Panicf("MakeFDivergenceLoss requires labels[0] (%s) and predictions[0] (%s) logits with the same shape",
+				pLogits.Shape(), qLogits.Shape())
+		}
+		weightsShape := shapes.Make(qLogits.DType(), qLogits.Shape().Dimensions[:qLogits.Rank()-1]...)
+		weights, mask := CheckLabelsForWeightsAndMask(weightsShape, labels)
+
+		pLogProbs := LogSoftmax(pLogits)
+		qLogProbs := LogSoftmax(qLogits)
+		var perClass *Node
+		switch kind {
+		case FDivKindKL:
+			perClass = Mul(Exp(pLogProbs), Sub(pLogProbs, qLogProbs))
+		case FDivKindReverseKL:
+			perClass = Mul(Exp(qLogProbs), Sub(qLogProbs, pLogProbs))
+		case FDivKindPearson:
+			// q * (p/q - 1)².
+			perClass = Mul(Exp(qLogProbs), Square(AddScalar(Exp(Sub(pLogProbs, qLogProbs)), -1)))
+		case FDivKindHellinger:
+			perClass = Square(Sub(Exp(MulScalar(pLogProbs, 0.5)), Exp(MulScalar(qLogProbs, 0.5))))
+		case FDivKindTotalVariation:
+			perClass = MulScalar(Abs(Sub(Exp(pLogProbs), Exp(qLogProbs))), 0.5)
+		}
+		losses := ReduceSum(perClass, -1)
+		return ApplyWeightsAndMask(losses, weights, mask)
+	}
+}
+
+// MakeFDivergenceLossFromContext calls MakeFDivergenceLoss with the FDivKind configured by the hyperparameter
+// ParamFDivKind in the context.
+//
+// It returns an error if ParamFDivKind is not a valid FDivKind.
+func MakeFDivergenceLossFromContext(ctx *context.Context) (LossFn, error) {
+	kind := FDivKindKL
+	if value, found := ctx.GetParam(ParamFDivKind); found && value != nil {
+		switch v := value.(type) {
+		case FDivKind:
+			kind = v
+		case string:
+			var err error
+			kind, err = FDivKindString(v)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "invalid hyperparameter %q", ParamFDivKind)
+			}
+		default:
+			return nil, errors.Errorf("hyperparameter %q must be a FDivKind or a string, got %T", ParamFDivKind, value)
+		}
+	}
+	if !kind.IsAFDivKind() {
+		return nil, errors.Errorf("hyperparameter %q set to invalid FDivKind %s", ParamFDivKind, kind)
+	}
+	return MakeFDivergenceLoss(kind), nil
+}
@@ -0,0 +1,51 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeFDivergenceLoss(t *testing.T) {
+	require.Panics(t, func() { MakeFDivergenceLoss(FDivKind(-1)) })
+	ctx := context.New()
+	ctx.SetParams(map[string]any{ParamLoss: "f_divergence", ParamFDivKind: "reverse_kl"})
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+	ctx.SetParam(ParamFDivKind, "unknown")
+	_, err = LossFromContext(ctx)
+	require.Error(t, err)
+
+	graphtest.RunTestGraphFn(t, "MakeFDivergenceLoss", func(g *Graph) (inputs, outputs []*Node) {
+		// P = [0.5, 0.5] and Q = [0.25, 0.75], given as logits.
+		pLogits := Const(g, [][]float32{{0, 0}, {1, 2}})
+		qLogits := Const(g, [][]float32{{0, 1.0986123}, {1, 2}})
+		weights := Const(g, []float32{2, 1})
+		inputs = []*Node{pLogits, qLogits}
+		labels, predictions := []*Node{pLogits}, []*Node{qLogits}
+		outputs = []*Node{
+			ReduceAllMax(Abs(Sub(
+				MakeFDivergenceLoss(FDivKindKL)([]*Node{pLogits, weights}, predictions),
+				KLDivergenceLogitsBoth([]*Node{pLogits, weights}, predictions)))),
+			MakeFDivergenceLoss(FDivKindReverseKL)(labels, predictions),
+			contextLossFn(labels, predictions),
+			MakeFDivergenceLoss(FDivKindPearson)(labels, predictions),
+			MakeFDivergenceLoss(FDivKindHellinger)(labels, predictions),
+			MakeFDivergenceLoss(FDivKindTotalVariation)(labels, predictions),
+		}
+		return
+	}, []any{
+		float32(0),
+		// 0.25*log(0.25/0.5) + 0.75*log(0.75/0.5); identical distributions have divergence 0.
+		[]float32{0.130812, 0},
+		[]float32{0.130812, 0},
+		// (0.5-0.25)²/0.25 + (0.5-0.75)²/0.75.
+		[]float32{0.333333, 0},
+		// (√0.5-√0.25)² + (√0.5-√0.75)².
+		[]float32{0.0681483, 0},
+		[]float32{0.25, 0},
+	}, 1e-4)
+}
@@ -0,0 +1,280 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	"math"
+	"slices"
+
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ParamFocalLossGamma is the name of the hyperparameter that defines the focusing parameter gamma of the
+	// focal losses. It defaults to 2.0.
+	//
+	// See MakeSparseFocalCrossEntropyLogits.
+	ParamFocalLossGamma = "focal_loss_gamma"
+
+	// ParamFocalLossAlpha is the name of the hyperparameter that defines the balancing factor alpha of the
+	// focal losses. It defaults to 1.0.
+	//
+	// See MakeSparseFocalCrossEntropyLogits.
+	ParamFocalLossAlpha = "focal_loss_alpha"
+
+	// ParamClassWeights is the name of the hyperparameter that defines the per-class weights of the class-balanced
+	// losses. The value is a comma-separated list of numbers, one per class (e.g.: "0.25,1,1"), or a []float64.
+	// It defaults to no class weighting.
+	//
+	// See MakeBalancedFocalCrossEntropyLogits.
+	ParamClassWeights = "class_weights"
+
+	// ParamClassBalancedBeta is the name of the hyperparameter that defines the beta used to compute the effective
+	// number of samples of each class, in [0, 1). It defaults to 0.999.
+	//
+	// See MakeClassBalancedFocalLoss.
+	ParamClassBalancedBeta = "class_balanced_beta"
+
+	// ParamClassCounts is the name of the hyperparameter with the number of training samples of each class.
+	// The value is a comma-separated list of integers, one per class (e.g.: "1000,50,3"), or a []int64.
+	// There is no default, it must be set.
+	//
+	// See MakeClassBalancedFocalLoss.
+	ParamClassCounts = "class_counts"
+)
+
+// focalModulation returns the focal loss modulating factor `(1-p)^gamma`, given log(p).
+func focalModulation(logProbabilities *Node, gamma float64) *Node {
+	oneMinusP := OneMinus(Exp(logProbabilities))
+	// Clamp rounding errors, since p can be slightly larger than 1.
+	oneMinusP = Max(oneMinusP, ZerosLike(oneMinusP))
+	return Pow(oneMinusP, Scalar(logProbabilities.Graph(), logProbabilities.DType(), gamma))
+}
+
+// checkFocalParams panics if gamma or alpha are invalid.
+func checkFocalParams(name string, gamma, alpha float64) {
+	if gamma < 0 {
+		Panicf("%s requires gamma >= 0, got %g", name, gamma)
+	}
+	if alpha <= 0 {
+		Panicf("%s requires alpha > 0, got %g", name, alpha)
+	}
+}
+
+// MakeFocalCategoricalCrossEntropyLogits returns the focal loss (Lin et al., "Focal Loss for Dense Object
+// Detection", https://arxiv.org/abs/1708.02002) for multi-class classification, computed from the logits and
+// "dense" labels (e.g.: one-hot encoded), as in CategoricalCrossEntropyLogits:
+//
+//	-alpha * sum_c(labels_c * (1-p_c)^gamma * log(p_c)), with p = softmax(logits).
+//
+// gamma >= 0 down-weights the loss of well classified examples (gamma = 0 is the plain cross-entropy), and
+// alpha > 0 scales the loss. Weights and mask are taken from the extra labels, as in CategoricalCrossEntropyLogits.
+//
+// See MakeSparseFocalCrossEntropyLogits for sparse labels.
+func MakeFocalCategoricalCrossEntropyLogits(gamma, alpha float64) LossFn {
+	checkFocalParams("MakeFocalCategoricalCrossEntropyLogits", gamma, alpha)
+	return func(labels, logits []*Node) *Node {
+		logits0 := logits[0]
+		labels0 := labels[0]
+		checkLogits("MakeFocalCategoricalCrossEntropyLogits", logits0)
+		if !labels0.Shape().Equal(logits0.Shape()) {
+			Panicf("labels(%s) and logits(%s) must have the same shapes", labels0.Shape(), logits0.Shape())
+		}
+		weightsShape := shapes.Make(logits0.DType(), labels0.Shape().Dimensions[:labels0.Rank()-1]...)
+		weights, mask := CheckLabelsForWeightsAndMask(weightsShape, labels)
+		if mask != nil {
+			expandedMask := BroadcastToShape(InsertAxes(mask, -1), logits0.Shape())
+			logits0 = Where(expandedMask, logits0, ZerosLike(logits0))
+		}
+		logProbabilities := LogSoftmax(logits0)
+		perClass := Mul(labels0, logProbabilities)
+		if gamma != 0 {
+			perClass = Mul(perClass, focalModulation(logProbabilities, gamma))
+		}
+		losses := MulScalar(ReduceSum(perClass, -1), -alpha)
+		return ApplyWeightsAndMask(losses, weights, mask)
+	}
+}
+
+// MakeBalancedFocalCrossEntropyLogits returns the class-balanced focal loss for multi-class classification, the
+// standard loss for multi-class detection, computed from the logits and "dense" labels (e.g.: one-hot encoded),
+// as in MakeFocalCategoricalCrossEntropyLogits:
+//
+//	-sum_c(classWeights_c * labels_c * (1-p_c)^gamma * log(p_c)), with p = softmax(logits).
+//
+// log(p) is computed with LogSoftmax, as in CategoricalCrossEntropyLogits, so it's stable for large logits.
+//
+// classWeights must have one non-negative weight per class (the last axis of logits), e.g.: the inverse class
+// frequency. If empty, all classes have weight 1, and it's the same as MakeFocalCategoricalCrossEntropyLogits with
+// alpha = 1. Weights and mask are taken from the extra labels, as in CategoricalCrossEntropyLogits.
+func MakeBalancedFocalCrossEntropyLogits(gamma float64, classWeights []float64) LossFn {
+	checkFocalParams("MakeBalancedFocalCrossEntropyLogits", gamma, 1)
+	for ii, w := range classWeights {
+		if w < 0 {
+			Panicf("MakeBalancedFocalCrossEntropyLogits requires class weights >= 0, got %g for class #%d", w, ii)
+		}
+	}
+	if len(classWeights) == 0 {
+		return MakeFocalCategoricalCrossEntropyLogits(gamma, 1)
+	}
+	classWeights = slices.Clone(classWeights)
+	return func(labels, logits []*Node) *Node {
+		logits0 := logits[0]
+		if logits0.Rank() == 0 || logits0.Shape().Dim(-1) != len(classWeights) {
+			Panicf("MakeBalancedFocalCrossEntropyLogits with %d class weights requires the last axis of logits to have the same dimension, got %s",
+				len(classWeights), logits0.Shape())
+		}
+		weightedLabels := Mul(convertLabels("MakeBalancedFocalCrossEntropyLogits", labels[0], logits0.DType()),
+			Const(logits0.Graph(), shapes.CastAsDType(classWeights, logits0.DType())))
+		focalLabels := append([]*Node{weightedLabels}, labels[1:]...)
+		return MakeFocalCategoricalCrossEntropyLogits(gamma, 1)(focalLabels, logits)
+	}
+}
+
+// MakeBalancedFocalCrossEntropyLogitsFromContext calls MakeBalancedFocalCrossEntropyLogits using the gamma and the
+// class weights configured by the hyperparameters ParamFocalLossGamma and ParamClassWeights in the context.
+//
+// It returns an error if ParamClassWeights can't be parsed.
+func MakeBalancedFocalCrossEntropyLogitsFromContext(ctx *context.Context) (LossFn, error) {
+	gamma := context.GetParamOr(ctx, ParamFocalLossGamma, 2.0)
+	classWeights, err := getFloatsParam(ctx, ParamClassWeights)
+	if err != nil {
+		return nil, err
+	}
+	return MakeBalancedFocalCrossEntropyLogits(gamma, classWeights), nil
+}
+
+// ClassBalancedWeights returns the class weights of the "Class-Balanced Loss" (Cui et al., "Class-Balanced Loss
+// Based on Effective Number of Samples", https://arxiv.org/abs/1901.05555), given the number of training samples of
+// each class:
+//
+//	weight_c = (1 - beta) / (1 - beta^classCounts_c)
+//
+// That is, the inverse of the effective number of samples of the class. As in the paper, the weights are normalized
+// to sum to the number of classes. beta = 0 gives uniform weights (all 1), and beta close to 1 approaches
+// the inverse class frequency.
+//
+// beta must be in [0, 1), and the class counts must be > 0.
+func ClassBalancedWeights(beta float64, classCounts []int64) []float64 {
+	if beta < 0 || beta >= 1 {
+		Panicf("ClassBalancedWeights requires beta in [0, 1), got %g", beta)
+	}
+	weights := make([]float64, len(classCounts))
+	var sum float64
+	for ii, count := range classCounts {
+		if count <= 0 {
+			Panicf("ClassBalancedWeights requires class counts > 0, got %d for class #%d", count, ii)
+		}
+		weights[ii] = (1 - beta) / (1 - math.Pow(beta, float64(count)))
+		sum += weights[ii]
+	}
+	for ii := range weights {
+		weights[ii] *= float64(len(weights)) / sum
+	}
+	return weights
+}
+
+// MakeClassBalancedFocalLoss returns the class-balanced focal loss for long-tailed multi-class classification:
+// MakeBalancedFocalCrossEntropyLogits with the class weights computed from the number of training samples of each
+// class by ClassBalancedWeights.
+//
+// beta must be in [0, 1) (typical values are 0.99 to 0.9999), gamma >= 0 is the focal loss focusing parameter, and
+// classCounts must have one count (> 0) per class (the last axis of logits). The labels are "dense" (e.g.: one-hot
+// encoded), as in CategoricalCrossEntropyLogits.
+func MakeClassBalancedFocalLoss(beta, gamma float64, classCounts []int64) LossFn {
+	if len(classCounts) == 0 {
+		Panicf("MakeClassBalancedFocalLoss requires the class counts")
+	}
+	return MakeBalancedFocalCrossEntropyLogits(gamma, ClassBalancedWeights(beta, classCounts))
+}
+
+// MakeClassBalancedFocalLossFromContext calls MakeClassBalancedFocalLoss using the beta, gamma and class counts
+// configured by the hyperparameters ParamClassBalancedBeta, ParamFocalLossGamma and ParamClassCounts in the context.
+//
+// It returns an error if ParamClassCounts is not set or can't be parsed.
+func MakeClassBalancedFocalLossFromContext(ctx *context.Context) (LossFn, error) {
+	beta := context.GetParamOr(ctx, ParamClassBalancedBeta, 0.999)
+	gamma := context.GetParamOr(ctx, ParamFocalLossGamma, 2.0)
+	var classCounts []int64
+	if value, found := ctx.GetParam(ParamClassCounts); found {
+		if counts, ok := value.([]int64); ok {
+			classCounts = slices.Clone(counts)
+		} else {
+			counts, err := getFloatsParam(ctx, ParamClassCounts)
+			if err != nil {
+				return nil, err
+			}
+			classCounts = make([]int64, len(counts))
+			for ii, count := range counts {
+				if count != math.Trunc(count) {
+					return nil, errors.Errorf("hyperparameter %q must hold integer counts, got %g for class #%d",
+						ParamClassCounts, count, ii)
+				}
+				classCounts[ii] = int64(count)
+			}
+		}
+	}
+	if len(classCounts) == 0 {
+		return nil, errors.Errorf("hyperparameter %q must be set with the number of samples per class", ParamClassCounts)
+	}
+	if beta < 0 || beta >= 1 {
+		return nil, errors.Errorf("invalid hyperparameter %q=%g, it must be in [0, 1)", ParamClassBalancedBeta, beta)
+	}
+	for ii, count := range classCounts {
+		if count <= 0 {
+			return nil, errors.Errorf("hyperparameter %q must hold counts > 0, got %d for class #%d", ParamClassCounts, count, ii)
+		}
+	}
+	return MakeClassBalancedFocalLoss(beta, gamma, classCounts), nil
+}
+
+// MakeSparseFocalCrossEntropyLogits returns the focal loss for multi-class classification, like
+// MakeFocalCategoricalCrossEntropyLogits, but with "sparse" labels, as in SparseCategoricalCrossEntropyLogits:
+//
+//	-alpha * (1-p_t)^gamma * log(p_t), with p_t the softmax probability of the true label.
+//
+// It gathers the logit of the true label, so it doesn't materialize the one-hot encoding of the labels -- important
+// for very large number of classes.
+func MakeSparseFocalCrossEntropyLogits(gamma, alpha float64) LossFn {
+	checkFocalParams("MakeSparseFocalCrossEntropyLogits", gamma, alpha)
+	return func(labels, logits []*Node) *Node {
+		labels0, logits0, weights, mask := checkSparseLabels(labels, logits)
+		checkLogits("MakeSparseFocalCrossEntropyLogits", logits0)
+		if mask != nil {
+			expandedMask := BroadcastToShape(InsertAxes(mask, -1), logits0.Shape())
+			logits0 = Where(expandedMask, logits0, ZerosLike(logits0))
+		}
+		logPt := Sub(gatherTrueLogits(labels0, logits0), logSumExpLastAxis(logits0))
+		losses := MulScalar(logPt, -alpha)
+		if gamma != 0 {
+			losses = Mul(losses, focalModulation(logPt, gamma))
+		}
+		return ApplyWeightsAndMask(losses, weights, mask)
+	}
+}
+
+// MakeSparseFocalCrossEntropyLogitsFromContext calls MakeSparseFocalCrossEntropyLogits using the gamma and alpha
+// configured by the hyperparameters ParamFocalLossGamma and ParamFocalLossAlpha in the context.
+func MakeSparseFocalCrossEntropyLogitsFromContext(ctx *context.Context) LossFn {
+	gamma := context.GetParamOr(ctx, ParamFocalLossGamma, 2.0)
+	alpha := context.GetParamOr(ctx, ParamFocalLossAlpha, 1.0)
+	return MakeSparseFocalCrossEntropyLogits(gamma, alpha)
+}
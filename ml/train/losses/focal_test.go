@@ -0,0 +1,127 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSparseFocalCrossEntropyLogits(t *testing.T) {
+	ctx := context.New()
+	ctx.SetParams(map[string]any{ParamLoss: "sparse_focal_logits", ParamFocalLossGamma: 2.0, ParamFocalLossAlpha: 0.5})
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+	require.Panics(t, func() { MakeSparseFocalCrossEntropyLogits(-1, 1) })
+	require.Panics(t, func() { MakeSparseFocalCrossEntropyLogits(2, 0) })
+
+	graphtest.RunTestGraphFn(t, "MakeSparseFocalCrossEntropyLogits", func(g *Graph) (inputs, outputs []*Node) {
+		logits := Const(g, [][]float32{{1, 2, 0.5}, {0.1, 0.1, 3}, {0, 0, 0}})
+		labels := Const(g, [][]int32{{1}, {0}, {2}})
+		denseLabels := OneHot(Squeeze(labels, -1), 3, dtypes.Float32)
+		inputs = []*Node{labels, logits}
+		outputs = []*Node{
+			MakeSparseFocalCrossEntropyLogits(2, 0.5)([]*Node{labels}, []*Node{logits}),
+			contextLossFn([]*Node{labels}, []*Node{logits}),
+			MakeFocalCategoricalCrossEntropyLogits(2, 0.5)([]*Node{denseLabels}, []*Node{logits}),
+			// With gamma=0 and alpha=1 it is the usual sparse cross-entropy.
+			MakeSparseFocalCrossEntropyLogits(0, 1)([]*Node{labels}, []*Node{logits}),
+			SparseCategoricalCrossEntropyLogits([]*Node{labels}, []*Node{logits}),
+		}
+		return
+	}, []any{
+		[]float32{0.032039, 1.356968, 0.244136},
+		[]float32{0.032039, 1.356968, 0.244136},
+		[]float32{0.032039, 1.356968, 0.244136},
+		[]float32{0.464369, 3.004402, 1.098612},
+		[]float32{0.464369, 3.004402, 1.098612},
+	}, 1e-4)
+
+	graphtest.RunTestGraphFn(t, "MakeSparseFocalCrossEntropyLogits: weights and mask", func(g *Graph) (inputs, outputs []*Node) {
+		logits := Const(g, [][]float32{{1, 2, 0.5}, {0.1, 0.1, 3}, {0, 0, 0}})
+		labels := Const(g, [][]int32{{1}, {0}, {2}})
+		denseLabels := OneHot(Squeeze(labels, -1), 3, dtypes.Float32)
+		weights := Const(g, []float32{2, 1, 1})
+		mask := Const(g, []bool{true, false, true})
+		inputs = []*Node{labels, logits, weights, mask}
+		outputs = []*Node{
+			MakeSparseFocalCrossEntropyLogits(2, 0.5)([]*Node{labels, weights, mask}, []*Node{logits}),
+			MakeFocalCategoricalCrossEntropyLogits(2, 0.5)([]*Node{denseLabels, weights, mask}, []*Node{logits}),
+		}
+		return
+	}, []any{
+		[]float32{0.064078, 0, 0.244136},
+		[]float32{0.064078, 0, 0.244136},
+	}, 1e-4)
+}
+
+func TestBalancedFocalCrossEntropyLogits(t *testing.T) {
+	ctx := context.New()
+	ctx.SetParams(map[string]any{ParamLoss: "balanced_focal", ParamFocalLossGamma: 2.0, ParamClassWeights: "0.5, 2, 3"})
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+	ctx.SetParam(ParamClassWeights, "0.5,x")
+	_, err = LossFromContext(ctx)
+	require.Error(t, err)
+	require.Panics(t, func() { MakeBalancedFocalCrossEntropyLogits(2, []float64{1, -1}) })
+
+	graphtest.RunTestGraphFn(t, "MakeBalancedFocalCrossEntropyLogits", func(g *Graph) (inputs, outputs []*Node) {
+		logits := Const(g, [][]float32{{1, 2, 0.5}, {0.1, 0.1, 3}, {0, 0, 0}, {100, -100, 0}})
+		labels := Const(g, [][]float32{{0, 1, 0}, {1, 0, 0}, {0, 0, 1}, {1, 0, 0}})
+		inputs = []*Node{labels, logits}
+		outputs = []*Node{
+			MakeBalancedFocalCrossEntropyLogits(2, []float64{0.5, 2, 3})([]*Node{labels}, []*Node{logits}),
+			contextLossFn([]*Node{labels}, []*Node{logits}),
+			// Without class weights, it's the focal loss with alpha=1.
+			MakeBalancedFocalCrossEntropyLogits(2, nil)([]*Node{labels}, []*Node{logits}),
+		}
+		return
+	}, []any{
+		// Reference focal loss (gamma=2, alpha=1) of each example, times the weight of its class.
+		[]float32{2 * 0.064078, 0.5 * 2.713936, 3 * 0.488272, 0},
+		[]float32{2 * 0.064078, 0.5 * 2.713936, 3 * 0.488272, 0},
+		[]float32{0.064078, 2.713936, 0.488272, 0},
+	}, 1e-4)
+}
+
+func TestClassBalancedFocalLoss(t *testing.T) {
+	// Weights (1-beta)/(1-beta^n), normalized to sum to the number of classes.
+	weights := ClassBalancedWeights(0.99, []int64{1000, 100, 10})
+	require.InDeltaSlice(t, []float64{0.230147, 0.363011, 2.406842}, weights, 1e-5)
+	require.InDeltaSlice(t, []float64{1, 1, 1}, ClassBalancedWeights(0, []int64{1000, 100, 10}), 1e-9)
+	require.Panics(t, func() { ClassBalancedWeights(1, []int64{1, 2}) })
+	require.Panics(t, func() { ClassBalancedWeights(0.9, []int64{1, 0}) })
+
+	ctx := context.New()
+	ctx.SetParams(map[string]any{ParamLoss: TypeClassBalancedFocal.String(), ParamClassBalancedBeta: 0.99,
+		ParamFocalLossGamma: 2.0, ParamClassCounts: "1000,100,10"})
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+	ctx.SetParam(ParamClassCounts, "1000,10.5")
+	_, err = LossFromContext(ctx)
+	require.Error(t, err)
+	ctx.SetParam(ParamClassCounts, nil)
+	_, err = LossFromContext(ctx)
+	require.Error(t, err)
+
+	graphtest.RunTestGraphFn(t, "MakeClassBalancedFocalLoss", func(g *Graph) (inputs, outputs []*Node) {
+		logits := Const(g, [][]float32{{1, 2, 0.5}, {0.1, 0.1, 3}, {0, 0, 0}, {100, -100, 0}})
+		labels := Const(g, [][]float32{{0, 1, 0}, {1, 0, 0}, {0, 0, 1}, {1, 0, 0}})
+		inputs = []*Node{labels, logits}
+		outputs = []*Node{
+			MakeClassBalancedFocalLoss(0.99, 2, []int64{1000, 100, 10})([]*Node{labels}, []*Node{logits}),
+			contextLossFn([]*Node{labels}, []*Node{logits}),
+			// beta=0 gives uniform weights: the focal loss with alpha=1.
+			MakeClassBalancedFocalLoss(0, 2, []int64{1000, 100, 10})([]*Node{labels}, []*Node{logits}),
+		}
+		return
+	}, []any{
+		// Reference focal loss (gamma=2, alpha=1) of each example, times the weight of its class.
+		[]float32{0.363011 * 0.064078, 0.230147 * 2.713936, 2.406842 * 0.488272, 0},
+		[]float32{0.363011 * 0.064078, 0.230147 * 2.713936, 2.406842 * 0.488272, 0},
+		[]float32{0.064078, 2.713936, 0.488272, 0},
+	}, 1e-4)
+}
@@ -0,0 +1,80 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/shapes"
+)
+
+// boxCoordinates splits boxes shaped `[..., 4]`, in (x1, y1, x2, y2) format, into its coordinates, each shaped `[...]`.
+func boxCoordinates(boxes *Node) (x1, y1, x2, y2 *Node) {
+	coord := func(ii int) *Node {
+		return Squeeze(SliceAxis(boxes, -1, AxisElem(ii)), -1)
+	}
+	return coord(0), coord(1), coord(2), coord(3)
+}
+
+// boxArea returns the area of the boxes given by their coordinates, taken to be 0 for degenerate boxes (x2 < x1 or
+// y2 < y1).
+func boxArea(x1, y1, x2, y2 *Node) *Node {
+	width := Max(Sub(x2, x1), ZerosLike(x1))
+	height := Max(Sub(y2, y1), ZerosLike(y1))
+	return Mul(width, height)
+}
+
+// MakeGIoULoss returns a loss for bounding box regression that computes `1 - GIoU` (the Generalized Intersection
+// over Union, Rezatofighi et al., https://arxiv.org/abs/1902.09630) between the boxes in labels[0] and
+// predictions[0]:
+//
+//	GIoU = IoU - (area(C) - area(A ∪ B)) / area(C)
+//
+// Where C is the smallest box enclosing both A and B. Contrary to `1 - IoU`, it has a gradient even when the boxes
+// don't overlap. The loss ranges from 0 (identical boxes) to 2 (far away disjoint boxes).
+//
+// labels[0] and predictions[0] are boxes shaped `[..., 4]` in (x1, y1, x2, y2) format, and the returned losses
+// are shaped `[...]`. Degenerate boxes (x2 < x1 or y2 < y1) are taken to have zero area, and the divisions are
+// guarded by an epsilon, so the loss is always finite. Optional weights and mask shaped `[...]` can be given as
+// extra labels, e.g.: to mask out padding (invalid) boxes.
+func MakeGIoULoss() LossFn {
+	return func(labels, predictions []*Node) *Node {
+		predictions0 := predictions[0]
+		dtype := predictions0.DType()
+		labels0 := convertLabels("MakeGIoULoss", labels[0], dtype)
+		if !labels0.Shape().Equal(predictions0.Shape()) {
+			Panicf("labels[0] (%s) and predictions[0] (%s) must have same shape", labels0.Shape(), predictions0.Shape())
+		}
+		if predictions0.Rank() == 0 || predictions0.Shape().Dim(-1) != 4 {
+			Panicf("MakeGIoULoss requires boxes shaped [..., 4], got %s", predictions0.Shape())
+		}
+		weightsShape := shapes.Make(dtype, predictions0.Shape().Dimensions[:predictions0.Rank()-1]...)
+		weights, mask := CheckLabelsForWeightsAndMask(weightsShape, labels)
+		epsilon := epsilonForDType(predictions0.Graph(), dtype)
+
+		ax1, ay1, ax2, ay2 := boxCoordinates(labels0)
+		bx1, by1, bx2, by2 := boxCoordinates(predictions0)
+		areaA := boxArea(ax1, ay1, ax2, ay2)
+		areaB := boxArea(bx1, by1, bx2, by2)
+		intersection := boxArea(Max(ax1, bx1), Max(ay1, by1), Min(ax2, bx2), Min(ay2, by2))
+		union := Sub(Add(areaA, areaB), intersection)
+		iou := Div(intersection, Max(union, epsilon))
+		enclosing := boxArea(Min(ax1, bx1), Min(ay1, by1), Max(ax2, bx2), Max(ay2, by2))
+		giou := Sub(iou, Div(Sub(enclosing, union), Max(enclosing, epsilon)))
+		return ApplyWeightsAndMask(OneMinus(giou), weights, mask)
+	}
+}
@@ -0,0 +1,41 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeGIoULoss(t *testing.T) {
+	ctx := context.New()
+	ctx.SetParam(ParamLoss, "giou")
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+
+	graphtest.RunTestGraphFn(t, "MakeGIoULoss", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, [][]float32{{0, 0, 2, 2}, {0, 0, 2, 2}, {0, 0, 1, 1}, {0, 0, 2, 2}, {1, 1, 1, 1}})
+		predictions := Const(g, [][]float32{
+			{0, 0, 2, 2}, // Identical.
+			{1, 1, 3, 3}, // Overlapping.
+			{2, 0, 3, 1}, // Disjoint.
+			{0, 0, 2, 2},
+			{1, 1, 1, 1}, // Degenerate (zero area).
+		})
+		mask := Const(g, []bool{true, true, true, false, true})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{
+			MakeGIoULoss()([]*Node{labels, mask}, []*Node{predictions}),
+			contextLossFn([]*Node{labels}, []*Node{predictions}),
+		}
+		return
+	}, []any{
+		// Overlapping: IoU = 1/7, enclosing area 9, union 7: 1 - (1/7 - 2/9).
+		// Disjoint: IoU = 0, enclosing area 3, union 2: 1 - (0 - 1/3).
+		// Degenerate: IoU = 0, enclosing area 0: 1.
+		[]float32{0, 1.079365, 1.333333, 0, 1},
+		[]float32{0, 1.079365, 1.333333, 0, 1},
+	}, 1e-4)
+}
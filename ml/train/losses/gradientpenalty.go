@@ -0,0 +1,60 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gomlx/types/xslices"
+)
+
+// MakeGradientPenaltyLoss returns the gradient penalty term of WGAN-GP (Gulrajani et al., "Improved Training of
+// Wasserstein GANs", https://arxiv.org/abs/1704.00028), per example:
+//
+//	lambda * (||grad||_2 - 1)^2
+//
+// Contrary to the other losses, it doesn't use predictions[0]: it requires predictions[1] to be the gradient of the
+// critic output w.r.t. its (interpolated) inputs, shaped `[batch_size, ...]` -- typically computed with
+// graph.Gradient of the sum of the critic outputs. The norm is taken over all axes but the first, and the
+// returned penalty is shaped `[batch_size]`: it should be added to the critic loss.
+//
+// labels[0] is not used, but optional weights and mask shaped `[batch_size]` can be given as extra labels.
+func MakeGradientPenaltyLoss(lambda float64) LossFn {
+	if lambda < 0 {
+		Panicf("MakeGradientPenaltyLoss requires lambda >= 0, got %g", lambda)
+	}
+	return func(labels, predictions []*Node) *Node {
+		if len(predictions) < 2 {
+			Panicf("MakeGradientPenaltyLoss requires predictions[1] to be the gradient of the critic w.r.t. its inputs, got only %d predictions",
+				len(predictions))
+		}
+		grad := predictions[1]
+		if grad.Rank() < 2 {
+			Panicf("MakeGradientPenaltyLoss requires the gradient (predictions[1]) to be shaped [batch_size, ...], got %s",
+				grad.Shape())
+		}
+		dtype := grad.DType()
+		weightsShape := shapes.Make(dtype, grad.Shape().Dim(0))
+		weights, mask := CheckLabelsForWeightsAndMask(weightsShape, labels)
+		// The epsilon keeps the gradient of the norm finite if grad is 0.
+		normSquare := L2NormSquare(grad, xslices.Iota(1, grad.Rank()-1)...)
+		norm := Sqrt(Add(normSquare, epsilonForDType(grad.Graph(), dtype)))
+		losses := MulScalar(Square(AddScalar(norm, -1)), lambda)
+		return ApplyWeightsAndMask(losses, weights, mask)
+	}
+}
@@ -0,0 +1,37 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeGradientPenaltyLoss(t *testing.T) {
+	require.Panics(t, func() { MakeGradientPenaltyLoss(-1) })
+	graphtest.RunTestGraphFn(t, "MakeGradientPenaltyLoss", func(g *Graph) (inputs, outputs []*Node) {
+		// Linear critic whose gradient w.r.t. its inputs has norm 1.
+		x := Const(g, [][]float32{{1, 2}, {-3, 0.5}})
+		w := Const(g, []float32{0.6, 0.8})
+		critic := ReduceSum(Mul(x, w), -1)
+		unitGrad := Gradient(ReduceAllSum(critic), x)[0]
+
+		grads := Const(g, [][][]float32{{{0.6, 0.8}}, {{0, 2}}, {{0, 0}}})
+		mask := Const(g, []bool{true, true, false})
+		labels := Const(g, []float32{0, 0, 0})
+		inputs = []*Node{x, grads}
+		lossFn := MakeGradientPenaltyLoss(10)
+		outputs = []*Node{
+			lossFn([]*Node{ZerosLike(critic)}, []*Node{critic, unitGrad}),
+			lossFn([]*Node{labels}, []*Node{labels, grads}),
+			lossFn([]*Node{labels, mask}, []*Node{labels, grads}),
+		}
+		return
+	}, []any{
+		[]float32{0, 0},
+		// The epsilon under the square root makes the penalty of a zero gradient slightly less than lambda.
+		[]float32{0, 10, 9.99368},
+		[]float32{0, 10, 0},
+	}, 1e-3)
+}
@@ -0,0 +1,42 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoricalHingeLoss(t *testing.T) {
+	ctx := context.New()
+	ctx.SetParam(ParamLoss, "categorical_hinge")
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+
+	graphtest.RunTestGraphFn(t, "CategoricalHingeLoss", func(g *Graph) (inputs, outputs []*Node) {
+		predictions := Const(g, [][]float32{{2, 1.5, -1}, {0.5, 3, 1}, {0, 2.5, 1}})
+		labels := Const(g, []int32{0, 2, 1})
+		denseLabels := OneHot(labels, 3, dtypes.Float32)
+		weights := Const(g, []float32{1, 2, 3})
+		mask := Const(g, []bool{true, false, true})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{
+			CategoricalHingeLoss([]*Node{labels}, []*Node{predictions}),
+			CategoricalHingeLoss([]*Node{denseLabels}, []*Node{predictions}),
+			contextLossFn([]*Node{labels}, []*Node{predictions}),
+			CategoricalHingeLoss([]*Node{labels, weights}, []*Node{predictions}),
+			CategoricalHingeLoss([]*Node{denseLabels, mask}, []*Node{predictions}),
+		}
+		return
+	}, []any{
+		// The margin uses the highest wrong score: 1+1.5-2, 1+3-1 (not 1+0.5-1), and max(0, 1+1-2.5).
+		[]float32{0.5, 3, 0},
+		[]float32{0.5, 3, 0},
+		[]float32{0.5, 3, 0},
+		[]float32{0.5, 6, 0},
+		[]float32{0.5, 0, 0},
+	}, 1e-5)
+}
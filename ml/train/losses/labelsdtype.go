@@ -0,0 +1,44 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+// StrictLabelsDType makes the losses that convert the labels to the dtype of the predictions (e.g.:
+// BinaryCrossentropy, from integer or bool labels) panic instead, if the dtypes differ.
+//
+// It's off by default, for compatibility: the labels are silently converted. With mixed-precision training, the
+// conversion may lose information or hide bugs -- e.g.: float32 labels compared against bfloat16 predictions --
+// and turning it on makes the mismatch an error. It only affects graphs built after it is set.
+var StrictLabelsDType = false
+
+// convertLabels converts labels to dtype, the dtype of the predictions of the loss lossName, or panics if they
+// differ and StrictLabelsDType is set.
+func convertLabels(lossName string, labels *Node, dtype dtypes.DType) *Node {
+	if labels.DType() == dtype {
+		return labels
+	}
+	if StrictLabelsDType {
+		Panicf("%s: labels dtype %s doesn't match the predictions dtype %s, and losses.StrictLabelsDType is set: "+
+			"convert the labels explicitly", lossName, labels.DType(), dtype)
+	}
+	return ConvertDType(labels, dtype)
+}
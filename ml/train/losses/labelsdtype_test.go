@@ -0,0 +1,28 @@
+package losses
+
+import (
+	"testing"
+
+	"github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictLabelsDType(t *testing.T) {
+	backend := graphtest.BuildTestBackend()
+	lossFn := func(labels, predictions *Node) *Node {
+		return BinaryCrossentropyLogits([]*Node{labels}, []*Node{predictions})
+	}
+	labels, logits := []int32{1, 0}, []float32{2, -1}
+
+	// By default, the labels are converted.
+	require.NotPanics(t, func() { ExecOnce(backend, lossFn, labels, logits) })
+
+	StrictLabelsDType = true
+	defer func() { StrictLabelsDType = false }()
+	err := exceptions.TryCatch[error](func() { ExecOnce(backend, lossFn, labels, logits) })
+	require.ErrorContains(t, err, "BinaryCrossentropyLogits: labels dtype Int32 doesn't match the predictions dtype Float32")
+	// Matching dtypes are fine.
+	require.NotPanics(t, func() { ExecOnce(backend, lossFn, []float32{1, 0}, logits) })
+}
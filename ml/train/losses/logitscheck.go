@@ -0,0 +1,92 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	"strings"
+
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/tensors"
+	"k8s.io/klog/v2"
+)
+
+// CheckLogits enables a debugging check, in the categorical losses that take logits (e.g.:
+// CategoricalCrossEntropyLogits), that the predictions don't look like probabilities: that is, all non-negative
+// and summing to 1 over the last axis. Passing probabilities to a logits loss is a common mistake, that
+// "double-softmaxes" the predictions, and makes training slow and the model badly calibrated.
+//
+// It's off by default, since it adds a reduction of the predictions to the graph. It only affects graphs built
+// after it is set.
+//
+// The check adds a logged node (see Node.SetLogged) with the result of the check: to warn or panic when it
+// fails, set the node logger of the executor with NewLogitsCheckLogger. Otherwise, the result is printed
+// by the executor's default node logger.
+var CheckLogits = false
+
+// logitsCheckPrefix prefixes the messages of the nodes logged by checkLogits.
+const logitsCheckPrefix = "losses.CheckLogits: "
+
+// checkLogits adds the CheckLogits check on the logits, if enabled.
+func checkLogits(lossName string, logits *Node) {
+	if !CheckLogits {
+		return
+	}
+	g := logits.Graph()
+	dtype := logits.DType()
+	nonNegative := LogicalAll(GreaterOrEqual(logits, ZerosLike(logits)))
+	deviationFromOne := Abs(AddScalar(ReduceSum(logits, -1), -1))
+	sumToOne := LogicalAll(LessThan(deviationFromOne, Scalar(g, dtype, 1e-3)))
+	looksLikeProbabilities := LogicalAnd(nonNegative, sumToOne)
+	looksLikeProbabilities.SetLoggedf("%s%s predictions look like probabilities (non-negative and summing to 1), "+
+		"but logits are expected", logitsCheckPrefix, lossName)
+}
+
+// NewLogitsCheckLogger returns a graph.LoggerFn that handles the nodes logged by the CheckLogits check: if a check
+// fails it panics, if panicOnFailure is set, or logs a warning otherwise. Checks that pass are not logged.
+//
+// Other logged nodes are passed to next, if it is not nil -- usually the graph.DefaultNodeLogger.
+//
+// Example:
+//
+//	losses.CheckLogits = true
+//	exec.SetNodeLogger(losses.NewLogitsCheckLogger(graph.DefaultNodeLogger, true))
+func NewLogitsCheckLogger(next LoggerFn, panicOnFailure bool) LoggerFn {
+	return func(g *Graph, messages []string, values []*tensors.Tensor, nodes []NodeId) {
+		var otherMessages []string
+		var otherValues []*tensors.Tensor
+		var otherNodes []NodeId
+		for ii, msg := range messages {
+			if !strings.HasPrefix(msg, logitsCheckPrefix) {
+				otherMessages = append(otherMessages, msg)
+				otherValues = append(otherValues, values[ii])
+				otherNodes = append(otherNodes, nodes[ii])
+				continue
+			}
+			if !tensors.ToScalar[bool](values[ii]) {
+				continue
+			}
+			if panicOnFailure {
+				Panicf("graph %q: %s", g.Name(), msg)
+			}
+			klog.Warningf("graph %q: %s", g.Name(), msg)
+		}
+		if next != nil {
+			next(g, otherMessages, otherValues, otherNodes)
+		}
+	}
+}
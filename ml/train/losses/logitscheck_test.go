@@ -0,0 +1,32 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/types/tensors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLogits(t *testing.T) {
+	CheckLogits = true
+	defer func() { CheckLogits = false }()
+	backend := graphtest.BuildTestBackend()
+	exec := NewExec(backend, func(labels, logits *Node) *Node {
+		return CategoricalCrossEntropyLogits([]*Node{labels}, []*Node{logits})
+	})
+	defer exec.Finalize()
+	var otherMessages []string
+	exec.SetNodeLogger(NewLogitsCheckLogger(func(_ *Graph, messages []string, _ []*tensors.Tensor, _ []NodeId) {
+		otherMessages = append(otherMessages, messages...)
+	}, true))
+
+	labels := [][]float32{{0, 1, 0}, {1, 0, 0}}
+	// Logits pass the check.
+	require.NotPanics(t, func() { exec.Call(labels, [][]float32{{-1, 2, 0.5}, {3, 0, -2}}) })
+	// Probabilities are flagged.
+	require.Panics(t, func() { exec.Call(labels, [][]float32{{0.2, 0.7, 0.1}, {0.5, 0.25, 0.25}}) })
+	// The check's messages are not passed along.
+	require.Empty(t, otherMessages)
+}
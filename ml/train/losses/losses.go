@@ -21,12 +21,15 @@
 package losses
 
 import (
+	"slices"
+	"strconv"
 	"strings"
 
 	. "github.com/gomlx/exceptions"
 	. "github.com/gomlx/gomlx/graph"
 	"github.com/gomlx/gomlx/ml/context"
 	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gomlx/types/xslices"
 	"github.com/gomlx/gopjrt/dtypes"
 	"github.com/pkg/errors"
 )
@@ -40,10 +43,29 @@ import (
 //     That means that the loss function is free to return a loss per example or an already reduced scalar loss.
 //
 // Most of the predefined losses in package `gomlx/ml/train/losses` assume labels and predictions are
-// both of length one. For multi-head models, it's very easy to write a small custom LossFn that splits
-// the slice and send each label/prediction pair to a predefined loss.
+// both of length one. For multi-head models, MakeMultiHeadLoss splits the slices and sends each head's
+// labels/predictions to its loss -- or it's very easy to write a small custom LossFn that does it.
 type LossFn func(labels, predictions []*Node) (loss *Node)
 
+// AssertBatchPreserved panics if loss is not a per-example loss for a batch of batchSize examples -- that is, if it
+// has been reduced to a scalar, or if its first axis doesn't have dimension batchSize.
+//
+// It's an opt-in debugging helper for custom LossFn implementations: a common mistake is to reduce the wrong axis
+// and return a scalar prematurely. It returns the loss unchanged, so it can be used inline:
+//
+//	return losses.AssertBatchPreserved(myLoss, labels[0].Shape().Dim(0))
+func AssertBatchPreserved(loss *Node, batchSize int) *Node {
+	if loss.IsScalar() {
+		Panicf("loss was reduced to a scalar (shape %s), but a per-example loss with batch size %d was expected",
+			loss.Shape(), batchSize)
+	}
+	if loss.Shape().Dim(0) != batchSize {
+		Panicf("loss shape %s doesn't preserve the batch axis: expected the first axis to have dimension %d",
+			loss.Shape(), batchSize)
+	}
+	return loss
+}
+
 const (
 	Epsilon16 = 1e-4
 	Epsilon32 = 1e-7
@@ -72,11 +94,23 @@ var (
 	// See enumeration Type for accepted loss types.
 	//
 	// Some losses may have extra parameters, also read from the context hyperparameters -- e.g.:
-	// MakeHuberLossFromContext and MakeAdaptivePowerLossFromContext.
+	// MakeHuberLossFromContext, MakeAdaptivePowerLossFromContext and MakeContrastiveLossFromContext.
+	//
+	// Multiple losses can be combined by listing them separated by commas, e.g.: "mse,mae". The coefficients
+	// of each loss are then given by ParamLossWeights.
 	ParamLoss = "loss"
+
+	// ParamLossWeights defines the coefficients of each loss, when ParamLoss lists multiple losses separated by commas.
+	// The value is a comma-separated list of numbers, one per loss (e.g.: "0.3,0.7"), or a []float64.
+	//
+	// It defaults to equal weights, that sum to 1.
+	ParamLossWeights = "loss_weights"
 )
 
-// Type of loss, an enumeration of losses supported by
+// Type of loss, an enumeration of losses supported by LossFromContext.
+//
+// The string values (used in ParamLoss) are the snake-case names without the "Type" prefix, e.g.:
+// TypeCategoricalCrossLogits is "categorical_cross_logits". See TypeStrings for the full list.
 type Type int
 
 //go:generate enumer -type=Type -trimprefix=Type -transform=snake -values -text -json -yaml losses.go
@@ -97,20 +131,74 @@ const (
 	// TypeBinCross represents BinaryCrossentropy.
 	TypeBinCross
 
-	// TypeBinCrossLogits represents BinaryCrossentropyLogits.
+	// TypeBinCrossLogits represents BinaryCrossentropyLogits, or MakeBinaryCrossentropyLogits if ParamPosWeight
+	// is set.
 	TypeBinCrossLogits
 
-	// TypeSparseCross represents CategoricalCrossEntropy.
+	// TypeCategoricalCross represents CategoricalCrossEntropy, or MakeCategoricalCrossEntropy if ParamCCEClipEpsilon
+	// is set.
 	TypeCategoricalCross
 
-	// TypeBinCrossLogits represents CategoricalCrossEntropyLogits.
+	// TypeCategoricalCrossLogits represents CategoricalCrossEntropyLogits, or MakeSoftmaxCrossEntropyLogits if
+	// ParamTemperature or ParamLabelSmoothing are set.
 	TypeCategoricalCrossLogits
 
-	// TypeSparseCrossLogits represents SparseCategoricalCrossEntropyLogits
+	// TypeSparseCrossLogits represents SparseCategoricalCrossEntropyLogits.
 	TypeSparseCrossLogits
 
-	// TypeTriplet
+	// TypeTriplet represents the triplet loss, see MakeTripletLossFromContext.
 	TypeTriplet
+
+	// TypeCoral represents CoralLoss, for ordinal regression.
+	TypeCoral
+
+	// TypeContrastive represents the pairwise contrastive loss, see MakeContrastiveLoss.
+	TypeContrastive
+
+	// TypeKLLogitsBoth represents KLDivergenceLogitsBoth.
+	TypeKLLogitsBoth
+
+	// TypeHingeEmbedding represents the hinge embedding loss, see MakeHingeEmbeddingLoss.
+	TypeHingeEmbedding
+
+	// TypeTweedie represents the Tweedie deviance loss, see MakeTweedieLoss.
+	TypeTweedie
+
+	// TypeSparseFocalLogits represents the focal loss with sparse labels, see MakeSparseFocalCrossEntropyLogits.
+	TypeSparseFocalLogits
+
+	// TypeDiceCE represents the weighted sum of the Dice loss and the categorical cross-entropy, see
+	// MakeDiceCrossEntropyLoss.
+	TypeDiceCE
+
+	// TypeSparseCross represents SparseCategoricalCrossEntropy.
+	TypeSparseCross
+
+	// TypeBalancedFocal represents the class-balanced focal loss from logits, see
+	// MakeBalancedFocalCrossEntropyLogits.
+	TypeBalancedFocal
+
+	// TypeFDivergence represents the f-divergence selected by ParamFDivKind, see MakeFDivergenceLoss.
+	TypeFDivergence
+
+	// TypeGiou represents the Generalized IoU loss for bounding boxes, see MakeGIoULoss.
+	TypeGiou
+
+	// TypeSoftSpearman represents the listwise soft Spearman rank correlation loss, see MakeSoftSpearmanLoss.
+	TypeSoftSpearman
+
+	// TypeCox represents the Cox proportional-hazards partial likelihood loss, see CoxPartialLikelihoodLoss.
+	TypeCox
+
+	// TypeClassBalancedFocal represents the class-balanced (by the effective number of samples) focal loss from
+	// logits, see MakeClassBalancedFocalLoss.
+	TypeClassBalancedFocal
+
+	// TypeBrier represents the multiclass Brier score, see BrierScoreLoss.
+	TypeBrier
+
+	// TypeCategoricalHinge represents the multiclass (Crammer-Singer SVM) hinge loss, see CategoricalHingeLoss.
+	TypeCategoricalHinge
 )
 
 // LossFromContext takes the value from the ParamLoss hyperparameter as a string and
@@ -118,15 +206,98 @@ const (
 //
 // Useful for projects where more than one loss matches the problem underlying optimization goal.
 //
+// If ParamLoss lists more than one loss, separated by commas (e.g.: "mse,mae"), the returned loss is the weighted
+// sum of the ReduceAllMean of each loss, with coefficients given by ParamLossWeights.
+//
+// Besides the built-in losses (see Type), it accepts the names of losses registered with Register.
+//
+// If ParamLossAccumDType is set, the loss is computed in that dtype, see MakeHigherPrecisionLoss.
+//
 // It returns an error if the configured loss is unknown.
 func LossFromContext(ctx *context.Context) (LossFn, error) {
 	lossName := context.GetParamOr(ctx, ParamLoss, "mae")
-	lossType, err := TypeString(lossName)
+	var lossFn LossFn
+	var err error
+	if strings.Contains(lossName, ",") {
+		lossFn, err = combinedLossFromContext(ctx, lossName)
+	} else {
+		lossFn, err = lossFromName(ctx, lossName)
+	}
 	if err != nil {
-		err = errors.Wrapf(err, "invalid value %q for hyperparameter %q, known losses are: \"%s\"",
-			lossName, ParamLoss, strings.Join(TypeStrings(), "\", \""))
 		return nil, err
 	}
+	return higherPrecisionFromContext(ctx, lossFn)
+}
+
+// combinedLossFromContext implements LossFromContext for a comma-separated list of losses.
+func combinedLossFromContext(ctx *context.Context, lossNames string) (LossFn, error) {
+	parts := strings.Split(lossNames, ",")
+	lossFns := make([]LossFn, len(parts))
+	for ii, part := range parts {
+		part = strings.TrimSpace(part)
+		var err error
+		lossFns[ii], err = lossFromName(ctx, part)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid loss #%d in hyperparameter %q=%q", ii, ParamLoss, lossNames)
+		}
+	}
+
+	// Coefficients of each loss.
+	lossWeights, err := getFloatsParam(ctx, ParamLossWeights)
+	if err != nil {
+		return nil, err
+	}
+	if lossWeights == nil {
+		lossWeights = make([]float64, len(parts))
+		for ii := range lossWeights {
+			lossWeights[ii] = 1.0 / float64(len(parts))
+		}
+	} else if len(lossWeights) != len(parts) {
+		return nil, errors.Errorf("hyperparameter %q has %d values, but %d losses are given in %q=%q",
+			ParamLossWeights, len(lossWeights), len(parts), ParamLoss, lossNames)
+	}
+
+	return func(labels, predictions []*Node) (loss *Node) {
+		for ii, lossFn := range lossFns {
+			partial := MulScalar(ReduceAllMean(lossFn(labels, predictions)), lossWeights[ii])
+			if loss == nil {
+				loss = partial
+			} else {
+				loss = Add(loss, partial)
+			}
+		}
+		return loss
+	}, nil
+}
+
+// getFloatsParam returns the hyperparameter key as a list of floats. Its value can be either a comma-separated
+// string of numbers (e.g.: "0.3,0.7") or a []float64. It returns nil if the hyperparameter is not set.
+func getFloatsParam(ctx *context.Context, key string) ([]float64, error) {
+	value, found := ctx.GetParam(key)
+	if !found || value == nil {
+		return nil, nil
+	}
+	switch v := value.(type) {
+	case string:
+		parts := strings.Split(v, ",")
+		values := make([]float64, len(parts))
+		for ii, part := range parts {
+			var err error
+			values[ii], err = strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse value #%d of hyperparameter %q=%q", ii, key, v)
+			}
+		}
+		return values, nil
+	case []float64:
+		return slices.Clone(v), nil
+	default:
+		return nil, errors.Errorf("hyperparameter %q must be a comma-separated string or []float64, got %T", key, value)
+	}
+}
+
+// lossFromType returns the loss function for the given type, configured from the context if needed.
+func lossFromType(ctx *context.Context, lossType Type) (LossFn, error) {
 	switch lossType {
 	case TypeMAE:
 		return MeanAbsoluteError, nil
@@ -139,15 +310,66 @@ func LossFromContext(ctx *context.Context) (LossFn, error) {
 	case TypeBinCross:
 		return BinaryCrossentropy, nil
 	case TypeBinCrossLogits:
+		if posWeight := context.GetParamOr(ctx, ParamPosWeight, 1.0); posWeight != 1.0 {
+			return MakeBinaryCrossentropyLogits(posWeight), nil
+		}
 		return BinaryCrossentropyLogits, nil
 	case TypeCategoricalCross:
+		if value, found := ctx.GetParam(ParamCCEClipEpsilon); found && value != nil {
+			clipEpsilon := context.GetParamOr(ctx, ParamCCEClipEpsilon, 0.0)
+			if clipEpsilon >= 0.5 {
+				return nil, errors.Errorf("invalid hyperparameter %q=%g, it must be < 0.5", ParamCCEClipEpsilon, clipEpsilon)
+			}
+			return MakeCategoricalCrossEntropy(clipEpsilon), nil
+		}
 		return CategoricalCrossEntropy, nil
 	case TypeCategoricalCrossLogits:
+		temperature := context.GetParamOr(ctx, ParamTemperature, 1.0)
+		labelSmoothing := context.GetParamOr(ctx, ParamLabelSmoothing, 0.0)
+		if temperature != 1.0 || labelSmoothing != 0.0 {
+			if temperature <= 0 || labelSmoothing < 0 || labelSmoothing >= 1 {
+				return nil, errors.Errorf("invalid hyperparameters %q=%g (must be > 0) or %q=%g (must be in [0, 1))",
+					ParamTemperature, temperature, ParamLabelSmoothing, labelSmoothing)
+			}
+			return MakeSoftmaxCrossEntropyLogits(temperature, labelSmoothing), nil
+		}
 		return CategoricalCrossEntropyLogits, nil
 	case TypeSparseCrossLogits:
 		return SparseCategoricalCrossEntropyLogits, nil
 	case TypeTriplet:
 		return MakeTripletLossFromContext(ctx), nil
+	case TypeCoral:
+		return CoralLoss, nil
+	case TypeContrastive:
+		return MakeContrastiveLossFromContext(ctx), nil
+	case TypeKLLogitsBoth:
+		return KLDivergenceLogitsBoth, nil
+	case TypeHingeEmbedding:
+		return MakeHingeEmbeddingLossFromContext(ctx), nil
+	case TypeTweedie:
+		return MakeTweedieLossFromContext(ctx), nil
+	case TypeSparseFocalLogits:
+		return MakeSparseFocalCrossEntropyLogitsFromContext(ctx), nil
+	case TypeDiceCE:
+		return MakeDiceCrossEntropyLossFromContext(ctx), nil
+	case TypeSparseCross:
+		return SparseCategoricalCrossEntropy, nil
+	case TypeBalancedFocal:
+		return MakeBalancedFocalCrossEntropyLogitsFromContext(ctx)
+	case TypeFDivergence:
+		return MakeFDivergenceLossFromContext(ctx)
+	case TypeGiou:
+		return MakeGIoULoss(), nil
+	case TypeSoftSpearman:
+		return MakeSoftSpearmanLoss(), nil
+	case TypeCox:
+		return CoxPartialLikelihoodLoss, nil
+	case TypeClassBalancedFocal:
+		return MakeClassBalancedFocalLossFromContext(ctx)
+	case TypeBrier:
+		return BrierScoreLoss, nil
+	case TypeCategoricalHinge:
+		return CategoricalHingeLoss, nil
 	default:
 		return nil, errors.Errorf("Unknown loss type %q set for hyperparameter %q, known losses are \"%s\"",
 			lossType, ParamLoss, strings.Join(TypeStrings(), "\", \""))
@@ -158,28 +380,74 @@ func LossFromContext(ctx *context.Context) (LossFn, error) {
 //
 // labels and predictions must have the same shape.
 //
+// For complex dtypes (e.g.: complex-valued spectrograms), the squared magnitude of the difference, |y-ŷ|^2, is used,
+// and the loss is real (float32 for complex64, float64 for complex128), as are the optional weights.
+//
 // If there is an extra element in the input labels with the shape of the labels[0] (usually simply `[bath_size]`),
 // it is assumed to be weights tensor to be applied to the losses.
 // If there is an extra element in the input labels  with booleans and the same dimensions as `labels[0]` (usually
 // simply `batch_size`), it assumed to be a mask tensor to be applied to the losses.
 func MeanSquaredError(labels, predictions []*Node) (loss *Node) {
+	loss, _ = squaredErrors(labels, predictions)
+	loss = ReduceAllMean(loss)
+	return loss
+}
+
+// MakeMeanSquaredError returns a MeanSquaredError loss function, where the squared errors are weighted per
+// output channel (the last axis) by channelWeights, before the final reduce-mean.
+//
+// This is useful for multi-output regression, where targets have different scales. It's distinct from the
+// per-example weights (and mask) that may be given as extra labels, which are also supported, as in MeanSquaredError.
+//
+// len(channelWeights) must match the dimension of the last axis of labels and predictions.
+// If channelWeights is empty, it's the same as MeanSquaredError.
+func MakeMeanSquaredError(channelWeights []float64) LossFn {
+	if len(channelWeights) == 0 {
+		return MeanSquaredError
+	}
+	return func(labels, predictions []*Node) (loss *Node) {
+		predictions0 := predictions[0]
+		if predictions0.Rank() == 0 || predictions0.Shape().Dim(-1) != len(channelWeights) {
+			Panicf("MakeMeanSquaredError with %d channel weights requires the last axis of predictions[0] to have the same dimension, got %s",
+				len(channelWeights), predictions0.Shape())
+		}
+		loss, _ = squaredErrors(labels, predictions)
+		weights := Const(predictions0.Graph(), shapes.CastAsDType(channelWeights, loss.DType()))
+		loss = Mul(loss, ExpandLeftToRank(weights, loss.Rank()))
+		loss = ReduceAllMean(loss)
+		return loss
+	}
+}
+
+// squaredErrors returns the weighted and masked squared errors, per element, along with the mask used (or nil).
+// It implements MeanSquaredError and MeanSquaredErrorSumCount.
+func squaredErrors(labels, predictions []*Node) (losses, mask *Node) {
 	predictions0 := predictions[0]
 	labels0 := labels[0]
 	if !labels0.Shape().Equal(predictions0.Shape()) {
 		Panicf("labels[0] (%s) and predictions[0] (%s) must have same shape", labels0.Shape(), predictions0.Shape())
 	}
-	weights, mask := CheckLabelsForWeightsAndMask(labels0.Shape(), labels)
-	loss = Sub(labels0, predictions0)
-	loss = Mul(loss, loss)
-
-	if weights != nil {
-		loss = Mul(loss, weights)
+	weights, mask := CheckLabelsForWeightsAndMask(errorsShape(labels0), labels)
+	losses = Sub(labels0, predictions0)
+	if losses.DType().IsComplex() {
+		// Squared magnitude |y-ŷ|^2: Mul(diff, diff) would be the complex square instead.
+		losses = Add(Square(Real(losses)), Square(Imag(losses)))
+	} else {
+		losses = Mul(losses, losses)
 	}
-	if mask != nil {
-		loss = Where(mask, loss, ZerosLike(loss))
+
+	losses = ApplyWeightsAndMask(losses, weights, mask)
+	return
+}
+
+// errorsShape returns the shape of the per-element errors of labels0, as returned by squaredErrors: it is the shape of
+// labels0, except for complex dtypes, whose errors are real.
+func errorsShape(labels0 *Node) shapes.Shape {
+	shape := labels0.Shape()
+	if shape.DType.IsComplex() {
+		shape = shapes.Make(shape.DType.RealDType(), shape.Dimensions...)
 	}
-	loss = ReduceAllMean(loss)
-	return loss
+	return shape
 }
 
 // CheckLabelsForWeightsAndMask in the labels slice of tensors -- it is assumed that labels[0] are the actual labels, so
@@ -192,18 +460,38 @@ func MeanSquaredError(labels, predictions []*Node) (loss *Node) {
 //
 // If there is an extra `labels` `*Node` with the shape of `weightsShape`, it is assumed to be weights.
 // If there is an extra `labels` `*Node` with booleans with the same dimension as `weightsShape`, it is assumed to be a mask.
+//
+// Weights with the dtype of `weightsShape` but with a shape broadcast-compatible with it are also accepted, and are
+// explicitly broadcast to `weightsShape`:
+//
+//   - A scalar: the same weight for every element.
+//   - A suffix of the dimensions of `weightsShape`: broadcast over the leading axes -- e.g.: per-class weights shaped
+//     `[num_classes]` for losses shaped `[batch_size, num_classes]`.
+//   - A prefix of the dimensions of `weightsShape`: broadcast over the trailing axes -- e.g.: per-example weights
+//     shaped `[batch_size]` for losses shaped `[batch_size, num_classes]`.
+//
+// If both a suffix and a prefix match (e.g.: weights shaped `[4]` for a `weightsShape` of `[4, 4]`), it's ambiguous
+// whether the weights are per-example or per-class, and it panics: broadcast the weights explicitly in that case.
 func CheckLabelsForWeightsAndMask(weightsShape shapes.Shape, labels []*Node) (weights, mask *Node) {
 	maskShape := shapes.Make(dtypes.Bool, weightsShape.Dimensions...)
 	// We skip labels[0] because that contains the actual labels.
 	for ii, extra := range labels[1:] {
 		if weights == nil && extra.Shape().Equal(weightsShape) {
 			weights = extra
-		} else if mask == nil && extra.Shape().Equal(maskShape) {
+			continue
+		}
+		if weights == nil {
+			if broadcastWeights := broadcastWeightsToShape(extra, weightsShape); broadcastWeights != nil {
+				weights = broadcastWeights
+				continue
+			}
+		}
+		if mask == nil && extra.Shape().Equal(maskShape) {
 			mask = extra
-		} else {
-			Panicf("labels ([]*Node) provided by the dataset to the loss function has extra tensors whose use is unknown: labels[%d].shape=%s "+
-				"-- label weights shape would be %s, labels mask shape would be %s", ii+1, extra.Shape(), weightsShape, maskShape)
+			continue
 		}
+		Panicf("labels ([]*Node) provided by the dataset to the loss function has extra tensors whose use is unknown: labels[%d].shape=%s "+
+			"-- label weights shape would be %s, labels mask shape would be %s", ii+1, extra.Shape(), weightsShape, maskShape)
 	}
 	if weights != nil && mask != nil {
 		weights = Where(mask, weights, ZerosLike(weights))
@@ -211,6 +499,50 @@ func CheckLabelsForWeightsAndMask(weightsShape shapes.Shape, labels []*Node) (we
 	return
 }
 
+// broadcastWeightsToShape returns the weights broadcast to weightsShape, if they are broadcast-compatible (see
+// CheckLabelsForWeightsAndMask), or nil otherwise.
+func broadcastWeightsToShape(weights *Node, weightsShape shapes.Shape) *Node {
+	if weights.DType() != weightsShape.DType || weights.Rank() >= weightsShape.Rank() {
+		return nil
+	}
+	if weights.IsScalar() {
+		return BroadcastToShape(weights, weightsShape)
+	}
+	dims, rank := weightsShape.Dimensions, weights.Rank()
+	isSuffix := slices.Equal(weights.Shape().Dimensions, dims[len(dims)-rank:])
+	isPrefix := slices.Equal(weights.Shape().Dimensions, dims[:rank])
+	if isSuffix && isPrefix {
+		Panicf("weights shaped %s are ambiguous for losses shaped %s: they match both the leading and the trailing axes, "+
+			"broadcast them explicitly to %s", weights.Shape(), weightsShape, weightsShape)
+	}
+	if isSuffix {
+		// Suffix: broadcast over the leading axes.
+		return BroadcastToShape(weights, weightsShape)
+	}
+	if isPrefix {
+		// Prefix: broadcast over the trailing axes.
+		return BroadcastToShape(Reshape(weights, append(slices.Clone(dims[:rank]), xslices.SliceWithValue(len(dims)-rank, 1)...)...), weightsShape)
+	}
+	return nil
+}
+
+// ApplyWeightsAndMask applies the optional weights and mask to the losses, with the same semantics used by all
+// the losses in this package: losses are multiplied by weights, and set to zero where mask is false.
+//
+// weights and mask can be nil, in which case they are ignored. Usually they are obtained with
+// CheckLabelsForWeightsAndMask, and they must have the same dimensions as losses.
+//
+// It's exported for custom losses, so they behave the same as the predefined ones.
+func ApplyWeightsAndMask(losses, weights, mask *Node) *Node {
+	if weights != nil {
+		losses = Mul(losses, weights)
+	}
+	if mask != nil {
+		losses = Where(mask, losses, ZerosLike(losses))
+	}
+	return losses
+}
+
 // MeanAbsoluteError returns the mean absolute error between labels and predictions.
 // It uses only the first element of each.
 //
@@ -221,22 +553,24 @@ func CheckLabelsForWeightsAndMask(weightsShape shapes.Shape, labels []*Node) (we
 // If there is an extra `labels` `*Node` with booleans and the same dimensions as `labels[0]` (usually simply `batch_size`),
 // it assumed to be a mask tensor to be applied to the losses.
 func MeanAbsoluteError(labels, predictions []*Node) (loss *Node) {
+	loss, _ = absoluteErrors(labels, predictions)
+	loss = ReduceAllMean(loss)
+	return
+}
+
+// absoluteErrors returns the weighted and masked absolute errors, per element, along with the mask used (or nil).
+// It implements MeanAbsoluteError and MeanAbsoluteErrorSumCount.
+func absoluteErrors(labels, predictions []*Node) (losses, mask *Node) {
 	predictions0 := predictions[0]
 	labels0 := labels[0]
 	if !labels0.Shape().Equal(predictions0.Shape()) {
 		Panicf("labels[0] (%s) and predictions[0] (%s) must have same shape", labels0.Shape(), predictions0.Shape())
 	}
 
-	loss = Abs(Sub(labels0, predictions0))
+	losses = Abs(Sub(labels0, predictions0))
 
 	weights, mask := CheckLabelsForWeightsAndMask(labels0.Shape(), labels)
-	if weights != nil {
-		loss = Mul(loss, weights)
-	}
-	if mask != nil {
-		loss = Where(mask, loss, ZerosLike(loss))
-	}
-	loss = ReduceAllMean(loss)
+	losses = ApplyWeightsAndMask(losses, weights, mask)
 	return
 }
 
@@ -256,7 +590,7 @@ func MeanAbsoluteError(labels, predictions []*Node) (loss *Node) {
 // it assumed to be a mask tensor to be applied to the losses.
 func BinaryCrossentropy(labels, predictions []*Node) *Node {
 	predictions0 := predictions[0]
-	labels0 := ConvertDType(labels[0], predictions0.DType())
+	labels0 := convertLabels("BinaryCrossentropy", labels[0], predictions0.DType())
 	if !labels0.Shape().Equal(predictions0.Shape()) {
 		Panicf("labels[0] (%s) and predictions[0] (%s) must have same shape", labels0.Shape(), predictions0.Shape())
 	}
@@ -265,12 +599,7 @@ func BinaryCrossentropy(labels, predictions []*Node) *Node {
 		Mul(OneMinus(labels0), Log(OneMinus(predictions0)))))
 
 	weights, mask := CheckLabelsForWeightsAndMask(labels0.Shape(), labels)
-	if weights != nil {
-		losses = Mul(losses, weights)
-	}
-	if mask != nil {
-		losses = Where(mask, losses, ZerosLike(losses))
-	}
+	losses = ApplyWeightsAndMask(losses, weights, mask)
 	return losses
 }
 
@@ -295,7 +624,7 @@ func BinaryCrossentropy(labels, predictions []*Node) *Node {
 // it assumed to be a mask tensor to be applied to the losses.
 func BinaryCrossentropyLogits(labels, logits []*Node) *Node {
 	logits0 := logits[0]
-	labels0 := ConvertDType(labels[0], logits0.DType())
+	labels0 := convertLabels("BinaryCrossentropyLogits", labels[0], logits0.DType())
 	if logits0.Shape().Size() != labels0.Shape().Size() {
 		Panicf("labels[0] (%s) and logits[0] (%s) have incompatible shapes", labels0.Shape(), logits0.Shape())
 	}
@@ -308,18 +637,61 @@ func BinaryCrossentropyLogits(labels, logits []*Node) *Node {
 	losses := Add(Sub(maxPart, prodPart), logPart)
 
 	weights, mask := CheckLabelsForWeightsAndMask(labels0.Shape(), labels)
-	if weights != nil {
-		losses = Mul(losses, weights)
+	losses = ApplyWeightsAndMask(losses, weights, mask)
+	return losses
+}
+
+// MakeBinaryCrossentropyLogits returns a BinaryCrossentropyLogits loss function, where the loss of the positive
+// examples is scaled by posWeight, that is, the loss is `-(posWeight * y * log(sigmoid(x)) + (1-y) * log(1-sigmoid(x)))`.
+//
+// A posWeight > 1 increases the recall, and a posWeight < 1 increases the precision. It is typically used for
+// imbalanced binary classification, e.g.: with posWeight set to the ratio of negative to positive examples.
+// It is equivalent to TensorFlow's `weighted_cross_entropy_with_logits` and PyTorch's `pos_weight`.
+//
+// This is distinct from the per-example weights, which are also supported as an extra labels tensor,
+// as in BinaryCrossentropyLogits.
+func MakeBinaryCrossentropyLogits(posWeight float64) LossFn {
+	if posWeight <= 0 {
+		Panicf("MakeBinaryCrossentropyLogits requires posWeight > 0, got %g", posWeight)
 	}
-	if mask != nil {
-		losses = Where(mask, losses, ZerosLike(losses))
+	return func(labels, logits []*Node) *Node {
+		logits0 := logits[0]
+		labels0 := convertLabels("MakeBinaryCrossentropyLogits", labels[0], logits0.DType())
+		if logits0.Shape().Size() != labels0.Shape().Size() {
+			Panicf("labels[0] (%s) and logits[0] (%s) have incompatible shapes", labels0.Shape(), logits0.Shape())
+		}
+		if logits0.Rank() != labels0.Rank() {
+			labels0 = Reshape(labels0, logits0.Shape().Dimensions...)
+		}
+		// Stable formula: (1-y)*x + (1+(posWeight-1)*y) * (log(1+exp(-|x|)) + max(-x, 0))
+		logSigmoidNeg := Add(Log1P(Exp(Neg(Abs(logits0)))), Max(Neg(logits0), ZerosLike(logits0)))
+		positiveScale := OnePlus(MulScalar(labels0, posWeight-1))
+		losses := Add(Mul(OneMinus(labels0), logits0), Mul(positiveScale, logSigmoidNeg))
+
+		weights, mask := CheckLabelsForWeightsAndMask(labels0.Shape(), labels)
+		losses = ApplyWeightsAndMask(losses, weights, mask)
+		return losses
 	}
-	return losses
 }
 
+var (
+	// ParamCCEClipEpsilon is the name of the hyperparameter that defines the clipping of the predictions of the
+	// "categorical_cross" loss, see MakeCategoricalCrossEntropy. If set to a value <= 0, clipping is disabled.
+	// If not set, it uses the default epsilon for the dtype, see CategoricalCrossEntropy.
+	ParamCCEClipEpsilon = "cce_clip_epsilon"
+
+	// ParamPosWeight is the name of the hyperparameter that defines the weight of the positive examples for the
+	// "bin_cross_logits" loss, see MakeBinaryCrossentropyLogits. It defaults to 1.0.
+	ParamPosWeight = "pos_weight"
+)
+
 // SparseCategoricalCrossEntropyLogits returns the cross-entropy loss of the logits, given the labels.
 // The labels are provided in "sparse" format, that is, integer numbers from 0 to logits dimension-1.
-// labels and logits must have the same rank, and labels last dimension must be 1.
+// labels and logits must have the same rank, and labels last dimension must be 1 -- or labels can be given without
+// this last axis, that is, shaped as logits without the last axis (e.g.: `[batch_size]`).
+//
+// It is calculated as `logsumexp(logits) - logits[label]`, gathering the logit of the true label directly,
+// so it doesn't materialize the one-hot encoding of the labels -- important for very large vocabularies.
 //
 // It *does not* reduce-mean the losses, they are returned individually for each element of the batch and need
 // to be ReduceAllMean (usually the mean, but it could be the sum also) before used for training.
@@ -327,28 +699,74 @@ func BinaryCrossentropyLogits(labels, logits []*Node) *Node {
 // If there is an extra `labels` `*Node` with the shape of logits without the last axis, it assumed to be weights to the losses.
 // If there is an extra `labels` `*Node` with booleans with the same dimensions as logits without the last axis, it assumed to be a mask.
 func SparseCategoricalCrossEntropyLogits(labels, logits []*Node) *Node {
-	logits0 := logits[0]
-	labels0 := labels[0]
-	labelsShape := labels0.Shape()
-	labelsRank := labelsShape.Rank()
-	logitsShape := logits0.Shape()
-	logitsRank := logitsShape.Rank()
-	if !labelsShape.DType.IsInt() {
-		Panicf("labels0 indices dtype (%s), it must be integer", labelsShape.DType)
-	}
-	if labelsRank != logitsRank {
-		Panicf("labels0(%s) and logits0(%s) must have the same rank", labelsShape, logitsShape)
-	}
-	if labelsShape.Dimensions[labelsRank-1] != 1 {
-		Panicf("labels0(%s) are expected to have the last dimension == 1, with the true/labeled category", labelsShape)
+	checkLogits("SparseCategoricalCrossEntropyLogits", logits[0])
+	labels0, logits0, weights, mask := checkSparseLabels(labels, logits)
+	return sparseCategoricalCrossEntropyLogitsImpl(labels0, logits0, weights, mask)
+}
+
+// sparseCategoricalCrossEntropyLogitsImpl implements SparseCategoricalCrossEntropyLogits, by gathering the logits
+// of the true labels. labels must be shaped like logits, except the last axis with dimension 1.
+func sparseCategoricalCrossEntropyLogitsImpl(labels, logits, weights, mask *Node) *Node {
+	logitsShape := logits.Shape()
+	if mask != nil {
+		expandedMask := BroadcastToShape(InsertAxes(mask, -1), logitsShape)
+		logits = Where(expandedMask, logits, ZerosLike(logits))
 	}
-	weightsShape := shapes.Make(logits0.DType(), labelsShape.Dimensions[:labelsRank-1]...)
-	weights, mask := CheckLabelsForWeightsAndMask(weightsShape, labels)
 
-	// Remove last dimension, it will be re-added by OneHot
-	reducedLabels := Reshape(labels0, labels0.Shape().Dimensions[:labelsRank-1]...)
-	labelsValues := OneHot(reducedLabels, logitsShape.Dimensions[logitsRank-1], logitsShape.DType)
-	return categoricalCrossEntropyLogitsImpl(labelsValues, logits0, weights, mask)
+	trueLogits := gatherTrueLogits(labels, logits)
+	losses := Sub(logSumExpLastAxis(logits), trueLogits)
+	losses = ApplyWeightsAndMask(losses, weights, mask)
+	return losses
+}
+
+// logSumExpLastAxis returns log(sum(exp(logits))) over the last axis, computed in a numerically stable way.
+func logSumExpLastAxis(logits *Node) *Node {
+	logitsShape := logits.Shape()
+	batchDims := logitsShape.Dimensions[:logitsShape.Rank()-1]
+	// The max is treated as a constant, since it cancels out.
+	maxLogits := StopGradient(ReduceAndKeep(logits, ReduceMax, -1))
+	return Add(
+		Log(ReduceSum(Exp(Sub(logits, maxLogits)), -1)),
+		Reshape(maxLogits, batchDims...))
+}
+
+// gatherTrueLogits returns the logits of the true labels, shaped like logits without the last axis.
+// labels must be shaped like logits, except the last axis with dimension 1.
+//
+// It flattens the batch dimensions, and gathers with indices (example, label).
+func gatherTrueLogits(labels, logits *Node) *Node {
+	g := logits.Graph()
+	logitsShape := logits.Shape()
+	batchDims := logitsShape.Dimensions[:logitsShape.Rank()-1]
+	numExamples := logitsShape.Size() / logitsShape.Dim(-1)
+	flatLogits := Reshape(logits, numExamples, logitsShape.Dim(-1))
+	flatLabels := ConvertDType(Reshape(labels, numExamples, 1), dtypes.Int32)
+	exampleIndices := Iota(g, shapes.Make(dtypes.Int32, numExamples, 1), 0)
+	trueLogits := Gather(flatLogits, Concatenate([]*Node{exampleIndices, flatLabels}, -1))
+	return Reshape(trueLogits, batchDims...)
+}
+
+// SparseCategoricalCrossEntropy returns the cross-entropy loss of the predictions, given the labels.
+// The predictions are probabilities (e.g.: the output of a Softmax), and the labels are provided in "sparse" format,
+// that is, integer numbers from 0 to predictions dimension-1. labels and predictions must have the same rank, and
+// labels last dimension must be 1 -- or labels can be shaped as predictions without the last axis.
+//
+// It is calculated as `-log(predictions[label])`, gathering the probability of the true label directly,
+// clipped to [epsilon, 1-epsilon] to avoid infinities. See SparseCategoricalCrossEntropyLogits for the version
+// on logits, which is numerically more stable.
+//
+// It *does not* reduce-mean the losses, they are returned individually for each element of the batch and need
+// to be ReduceAllMean (usually the mean, but it could be the sum also) before used for training.
+//
+// If there is an extra `labels` `*Node` with the shape of predictions without the last axis, it assumed to be weights to the losses.
+// If there is an extra `labels` `*Node` with booleans with the same dimensions as predictions without the last axis, it assumed to be a mask.
+func SparseCategoricalCrossEntropy(labels, predictions []*Node) *Node {
+	labels0, predictions0, weights, mask := checkSparseLabels(labels, predictions)
+	g := predictions0.Graph()
+	epsilon := epsilonForDType(g, predictions0.DType())
+	trueProbabilities := Clip(gatherTrueLogits(labels0, predictions0), epsilon, OneMinus(epsilon))
+	losses := Neg(Log(trueProbabilities))
+	return ApplyWeightsAndMask(losses, weights, mask)
 }
 
 // CategoricalCrossEntropyLogits returns the cross-entropy loss of the logits, given the labels.
@@ -366,35 +784,133 @@ func SparseCategoricalCrossEntropyLogits(labels, logits []*Node) *Node {
 // TODO: implement faster version with logits, see https://github.com/tensorflow/tensorflow/blob/359c3cdfc5fabac82b3c70b3b6de2b0a8c16874f/tensorflow/python/ops/nn_ops.py#L4051
 func CategoricalCrossEntropyLogits(labels, logits []*Node) *Node {
 	logits0 := logits[0]
+	checkLogits("CategoricalCrossEntropyLogits", logits0)
 	labels0 := labels[0]
 	weightsShape := shapes.Make(logits0.DType(), labels0.Shape().Dimensions[:labels0.Rank()-1]...)
 	weights, mask := CheckLabelsForWeightsAndMask(weightsShape, labels)
 	return categoricalCrossEntropyLogitsImpl(labels0, logits0, weights, mask)
 }
 
+// MakeCategoricalCrossEntropyLogits returns a CategoricalCrossEntropyLogits loss function that sums the per-class
+// cross-entropy `-labels * log(softmax(logits))` over reduceAxes, instead of only over the last (class) axis.
+//
+// This gives control over which axes are kept in dense prediction tasks -- e.g.: for logits shaped
+// `[batch_size, height, width, num_classes]`, reduceAxes `[-1]` (the default, if reduceAxes is empty) returns the
+// losses shaped `[batch_size, height, width]`, per spatial location, while reduceAxes `[1, 2, 3]` returns the losses
+// summed over the image, shaped `[batch_size]`.
+//
+// The softmax is always taken over the last axis, which must be included in reduceAxes. Negative axes are counted
+// from the end. The returned losses have the shape of logits with the reduceAxes removed, and that is also the
+// shape of the optional weights and mask that can be given as extra labels.
+func MakeCategoricalCrossEntropyLogits(reduceAxes []int) LossFn {
+	if len(reduceAxes) == 0 {
+		return CategoricalCrossEntropyLogits
+	}
+	return func(labels, logits []*Node) *Node {
+		logits0 := logits[0]
+		checkLogits("MakeCategoricalCrossEntropyLogits", logits0)
+		labels0 := labels[0]
+		rank := labels0.Rank()
+		axes := make([]int, 0, len(reduceAxes))
+		for _, axis := range reduceAxes {
+			adjusted := axis
+			if adjusted < 0 {
+				adjusted += rank
+			}
+			if adjusted < 0 || adjusted >= rank {
+				Panicf("MakeCategoricalCrossEntropyLogits: invalid reduce axis %d for labels shaped %s", axis, labels0.Shape())
+			}
+			if !slices.Contains(axes, adjusted) {
+				axes = append(axes, adjusted)
+			}
+		}
+		if !slices.Contains(axes, rank-1) {
+			Panicf("MakeCategoricalCrossEntropyLogits: reduceAxes %v must include the last (class) axis", reduceAxes)
+		}
+		slices.Sort(axes)
+		var weightsDims []int
+		for axis, dim := range labels0.Shape().Dimensions {
+			if !slices.Contains(axes, axis) {
+				weightsDims = append(weightsDims, dim)
+			}
+		}
+		weightsShape := shapes.Make(logits0.DType(), weightsDims...)
+		weights, mask := CheckLabelsForWeightsAndMask(weightsShape, labels)
+		return categoricalCrossEntropyLogitsImpl(labels0, logits0, weights, mask, axes...)
+	}
+}
+
 // categoricalCrossEntropyLogitsImpl implements CategoricalCrossEntropyLogits.
-func categoricalCrossEntropyLogitsImpl(labels, logits, weights, mask *Node) *Node {
+//
+// reduceAxes must be sorted and non-negative, and if empty, only the last axis is reduced.
+func categoricalCrossEntropyLogitsImpl(labels, logits, weights, mask *Node, reduceAxes ...int) *Node {
 	shape := labels.Shape()
 	if !shape.Equal(logits.Shape()) {
 		Panicf("labels(%s) and logits(%s) must have the same shapes", shape, logits.Shape())
 	}
+	if len(reduceAxes) == 0 {
+		reduceAxes = []int{shape.Rank() - 1}
+	}
 	var expandedMask *Node
 	if mask != nil {
-		expandedMask = BroadcastToShape(InsertAxes(mask, -1), logits.Shape())
+		// Insert the reduced axes in the mask, so it can be broadcast to the logits shape.
+		expandedDims := slices.Clone(shape.Dimensions)
+		for _, axis := range reduceAxes {
+			expandedDims[axis] = 1
+		}
+		expandedMask = BroadcastToShape(Reshape(mask, expandedDims...), logits.Shape())
 		logits = Where(expandedMask, logits, ZerosLike(logits))
 	}
 	logPredictions := LogSoftmax(logits)
-	losses := ReduceSum(Neg(Mul(labels, logPredictions)), -1)
+	losses := ReduceSum(Neg(Mul(labels, logPredictions)), reduceAxes...)
 	// Losses will usually be shaped `[batch_size]` now.
-	if weights != nil {
-		losses = Mul(losses, weights)
+	losses = ApplyWeightsAndMask(losses, weights, mask)
+	return losses
+}
+
+// MakeSoftmaxCrossEntropyLogits returns a CategoricalCrossEntropyLogits loss function, with the logits divided by
+// temperature and the labels smoothed by labelSmoothing, that is, the labels become
+// `labels * (1 - labelSmoothing) + labelSmoothing / numClasses`.
+//
+// A temperature > 1 softens the predicted distribution, typically used for distillation, and label smoothing
+// prevents the model from becoming over-confident. With temperature = 1 and labelSmoothing = 0 it's the same as
+// CategoricalCrossEntropyLogits.
+//
+// It panics if temperature <= 0, or if labelSmoothing is not in the range [0, 1).
+func MakeSoftmaxCrossEntropyLogits(temperature, labelSmoothing float64) LossFn {
+	if temperature <= 0 {
+		Panicf("MakeSoftmaxCrossEntropyLogits requires temperature > 0, got %g", temperature)
 	}
-	if mask != nil {
-		losses = Where(mask, losses, ZerosLike(losses))
+	if labelSmoothing < 0 || labelSmoothing >= 1 {
+		Panicf("MakeSoftmaxCrossEntropyLogits requires 0 <= labelSmoothing < 1, got %g", labelSmoothing)
+	}
+	return func(labels, logits []*Node) *Node {
+		logits0 := logits[0]
+		checkLogits("MakeSoftmaxCrossEntropyLogits", logits0)
+		labels0 := labels[0]
+		weightsShape := shapes.Make(logits0.DType(), labels0.Shape().Dimensions[:labels0.Rank()-1]...)
+		weights, mask := CheckLabelsForWeightsAndMask(weightsShape, labels)
+		if temperature != 1 {
+			logits0 = DivScalar(logits0, temperature)
+		}
+		if labelSmoothing > 0 {
+			numClasses := float64(labels0.Shape().Dim(-1))
+			labels0 = AddScalar(MulScalar(labels0, 1-labelSmoothing), labelSmoothing/numClasses)
+		}
+		return categoricalCrossEntropyLogitsImpl(labels0, logits0, weights, mask)
 	}
-	return losses
 }
 
+var (
+	// ParamTemperature is the name of the hyperparameter that defines the temperature dividing the logits for the
+	// "categorical_cross_logits" loss, see MakeSoftmaxCrossEntropyLogits. It defaults to 1.0.
+	ParamTemperature = "temperature"
+
+	// ParamLabelSmoothing is the name of the hyperparameter that defines the label smoothing for the
+	// "categorical_cross_logits" loss, see MakeSoftmaxCrossEntropyLogits. It defaults to 0.0.
+	ParamLabelSmoothing = "label_smoothing"
+)
+
 // CategoricalCrossEntropy returns the cross-entropy loss of the predictions, given the labels.
 // The labels are provided in "dense" format, they should have the exact same shape as predictions, and be set 1 for
 // the true (labeled) category, and 0 for the others (one-hot encoding) -- or any other distribution that sums to 1.
@@ -410,27 +926,44 @@ func categoricalCrossEntropyLogitsImpl(labels, logits, weights, mask *Node) *Nod
 func CategoricalCrossEntropy(labels, predictions []*Node) *Node {
 	weightsShape := shapes.Make(predictions[0].DType(), labels[0].Shape().Dimensions[:labels[0].Rank()-1]...)
 	weights, mask := CheckLabelsForWeightsAndMask(weightsShape, labels)
-	return categoricalCrossEntropyImpl(labels[0], predictions[0], weights, mask)
+	epsilon := epsilonForDType(predictions[0].Graph(), labels[0].DType())
+	return categoricalCrossEntropyImpl(labels[0], predictions[0], weights, mask, epsilon)
 }
 
-// categoricalCrossEntropyImpl implements CategoricalCrossEntropy.
-func categoricalCrossEntropyImpl(labels, predictions, weights, mask *Node) *Node {
-	g := predictions.Graph()
+// MakeCategoricalCrossEntropy returns a CategoricalCrossEntropy loss where the predictions are clipped to
+// [clipEpsilon, 1-clipEpsilon], instead of using the default epsilon for the dtype, before taking the log.
+//
+// If clipEpsilon <= 0, the clipping is disabled, and the loss is exactly `-sum(labels * log(predictions))`.
+// Notice that without clipping, a prediction of 0 for a category with a non-zero label yields +Inf loss, and its
+// gradient (and a 0 label in the product `0 * log(0)`) yields NaN, which quickly spreads through training.
+// Only disable it if the predictions are guaranteed to be strictly positive.
+func MakeCategoricalCrossEntropy(clipEpsilon float64) LossFn {
+	if clipEpsilon >= 0.5 {
+		Panicf("MakeCategoricalCrossEntropy requires clipEpsilon < 0.5, got %g", clipEpsilon)
+	}
+	return func(labels, predictions []*Node) *Node {
+		weightsShape := shapes.Make(predictions[0].DType(), labels[0].Shape().Dimensions[:labels[0].Rank()-1]...)
+		weights, mask := CheckLabelsForWeightsAndMask(weightsShape, labels)
+		var epsilon *Node
+		if clipEpsilon > 0 {
+			epsilon = Scalar(predictions[0].Graph(), labels[0].DType(), clipEpsilon)
+		}
+		return categoricalCrossEntropyImpl(labels[0], predictions[0], weights, mask, epsilon)
+	}
+}
+
+// categoricalCrossEntropyImpl implements CategoricalCrossEntropy. If epsilon is nil, predictions are not clipped.
+func categoricalCrossEntropyImpl(labels, predictions, weights, mask, epsilon *Node) *Node {
 	shape := labels.Shape()
-	dtype := labels.DType()
 	if !shape.Equal(predictions.Shape()) {
 		Panicf("labels(%s) and predictions(%s) must different shapes", shape, predictions.Shape())
 	}
-	epsilon := epsilonForDType(g, dtype)
-	predictions = Clip(predictions, epsilon, OneMinus(epsilon))
+	if epsilon != nil {
+		predictions = Clip(predictions, epsilon, OneMinus(epsilon))
+	}
 	losses := ReduceSum(Neg(Mul(labels, Log(predictions))), -1)
 	// Losses will usually be shaped `[batch_size]` now, ready to apply weights multiplication and/or a mask.
-	if weights != nil {
-		losses = Mul(losses, weights)
-	}
-	if mask != nil {
-		losses = Where(mask, losses, ZerosLike(losses))
-	}
+	losses = ApplyWeightsAndMask(losses, weights, mask)
 	return losses
 }
 
@@ -475,12 +1008,7 @@ func MakeHuberLoss(delta float64) LossFn {
 		)
 
 		// Apply weights and mask.
-		if weights != nil {
-			loss = Mul(loss, weights)
-		}
-		if mask != nil {
-			loss = Where(mask, loss, ZerosLike(loss))
-		}
+		loss = ApplyWeightsAndMask(loss, weights, mask)
 		return loss
 	}
 }
@@ -531,21 +1059,7 @@ func MakeAdaptivePowerLoss(powerNear, powerFar, middleDelta, sharpness float64)
 			lnDelta := Log(Max(normalizedDelta, epsilonForDType(g, dtype)))
 			powerDiffOverSharpness := (powerNear - powerFar) / sharpness
 			scaledLnDelta := MulScalar(lnDelta, powerDiffOverSharpness)
-
-			// version1 is stable (not infinite) for positive scaledLnDelta.
-			version1 := AddScalar(
-				MulScalar(
-					Inverse(OnePlus(Exp(Neg(scaledLnDelta)))),
-					powerFar-powerNear),
-				powerNear)
-			// version2 is stable (not infinite) for negative scaledLnDelta)
-			version2 := AddScalar(
-				MulScalar(
-					Inverse(OnePlus(Exp(scaledLnDelta))),
-					powerNear-powerFar),
-				powerFar)
-			power := Where(GreaterThan(scaledLnDelta, ScalarZero(g, dtype)),
-				version1, version2)
+			power := adaptivePowerExponent(scaledLnDelta, Scalar(g, dtype, powerNear), Scalar(g, dtype, powerFar))
 
 			// NaNs would filter out through the Where if we allow, so we treat the calculated power as a constant
 			// for the purpose of the loss.
@@ -556,16 +1070,34 @@ func MakeAdaptivePowerLoss(powerNear, powerFar, middleDelta, sharpness float64)
 		}
 
 		// Apply weights and mask.
-		if weights != nil {
-			loss = Mul(loss, weights)
-		}
-		if mask != nil {
-			loss = Where(mask, loss, ZerosLike(loss))
-		}
+		loss = ApplyWeightsAndMask(loss, weights, mask)
 		return loss
 	}
 }
 
+// adaptivePowerExponent returns the exponent to use in the adaptive power loss, given
+// scaledLnDelta = ln(delta/middleDelta) * (powerNear-powerFar) / sharpness.
+//
+// It's a sigmoid transition from powerNear to powerFar, computed with two versions: one stable for positive
+// scaledLnDelta, and one stable for negative. Each version has its input clamped to its stable side, so the branch
+// not selected by the Where doesn't overflow, and its gradient (even if multiplied by zero) doesn't become NaN.
+func adaptivePowerExponent(scaledLnDelta, powerNear, powerFar *Node) *Node {
+	zero := ScalarZero(scaledLnDelta.Graph(), scaledLnDelta.DType())
+	// version1 is stable (not infinite) for positive scaledLnDelta.
+	version1 := Add(
+		Mul(
+			Inverse(OnePlus(Exp(Neg(Max(scaledLnDelta, zero))))),
+			Sub(powerFar, powerNear)),
+		powerNear)
+	// version2 is stable (not infinite) for negative scaledLnDelta)
+	version2 := Add(
+		Mul(
+			Inverse(OnePlus(Exp(Min(scaledLnDelta, zero)))),
+			Sub(powerNear, powerFar)),
+		powerFar)
+	return Where(GreaterThan(scaledLnDelta, zero), version1, version2)
+}
+
 var (
 	// ParamAdaptivePowerLossNear is the name of the hyperparameter that defines the AdaptivePowerLoss.
 	// It defaults to 2.0
@@ -603,3 +1135,54 @@ func MakeAdaptivePowerLossFromContext(ctx *context.Context) LossFn {
 	sharpness := context.GetParamOr(ctx, ParamAdaptivePowerLossSharpness, 1.0)
 	return MakeAdaptivePowerLoss(powerNear, powerFar, middleDelta, sharpness)
 }
+
+const (
+	// AdaptivePowerLossScope is the scope used by MakeLearnableAdaptivePowerLoss to store its variables.
+	AdaptivePowerLossScope = "adaptive_power_loss"
+)
+
+// MakeLearnableAdaptivePowerLoss is like MakeAdaptivePowerLoss, but powerNear and powerFar are trainable
+// variables, stored in the context under the scope AdaptivePowerLossScope (variables "power_near" and "power_far"),
+// and optimized jointly with the model.
+//
+// The exponents are initialized with the hyperparameters ParamAdaptivePowerLossNear and ParamAdaptivePowerLossFar
+// (defaults 2.0 and 1.0), and middleDelta and sharpness are fixed, taken from ParamAdaptivePowerLossMiddleDelta and
+// ParamAdaptivePowerLossSharpness (defaults to 1.0).
+//
+// Notice the exponents are not constrained: the loss can always be reduced by growing them (for |delta| < 1)
+// or shrinking them (for |delta| > 1). So they should be clamped to a reasonable range (e.g.: [0.5, 4]) after each
+// training step, or regularized.
+func MakeLearnableAdaptivePowerLoss(ctx *context.Context) LossFn {
+	initialNear := context.GetParamOr(ctx, ParamAdaptivePowerLossNear, 2.0)
+	initialFar := context.GetParamOr(ctx, ParamAdaptivePowerLossFar, 1.0)
+	middleDelta := context.GetParamOr(ctx, ParamAdaptivePowerLossMiddleDelta, 1.0)
+	sharpness := context.GetParamOr(ctx, ParamAdaptivePowerLossSharpness, 1.0)
+	ctx = ctx.In(AdaptivePowerLossScope).Checked(false)
+	return func(labels, predictions []*Node) (loss *Node) {
+		predictions0 := predictions[0]
+		g := predictions0.Graph()
+		dtype := predictions0.DType()
+		labels0 := labels[0]
+		if !labels0.Shape().Equal(predictions0.Shape()) {
+			Panicf("labels[0] (%s) and predictions[0] (%s) must have same shape", labels0.Shape(), predictions0.Shape())
+		}
+		weights, mask := CheckLabelsForWeightsAndMask(labels0.Shape(), labels)
+		powerNear := ctx.VariableWithValue("power_near", shapes.CastAsDType(initialNear, dtype)).ValueGraph(g)
+		powerFar := ctx.VariableWithValue("power_far", shapes.CastAsDType(initialFar, dtype)).ValueGraph(g)
+
+		// The exponent only gets gradients w.r.t. the variables: like in MakeAdaptivePowerLoss, delta is
+		// treated as a constant for the purpose of the exponent.
+		delta := Abs(Sub(labels0, predictions0))
+		epsilon := epsilonForDType(g, dtype)
+		lnDelta := StopGradient(Log(Max(DivScalar(delta, middleDelta), epsilon)))
+		scaledLnDelta := MulScalar(Mul(lnDelta, Sub(powerNear, powerFar)), 1/sharpness)
+		power := adaptivePowerExponent(scaledLnDelta, powerNear, powerFar)
+
+		// Pow's gradient w.r.t. the exponent is delta^power*ln(delta), which is NaN for delta == 0. So we use
+		// exp(power*ln(delta)) with a safe logarithm, and select 0 explicitly.
+		loss = Exp(Mul(power, Log(Max(delta, epsilon))))
+		loss = Where(GreaterThan(delta, ZerosLike(delta)), loss, ZerosLike(loss))
+		loss = ApplyWeightsAndMask(loss, weights, mask)
+		return loss
+	}
+}
@@ -18,10 +18,16 @@ package losses
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	. "github.com/gomlx/gomlx/graph"
 	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gomlx/types/xslices"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	_ "github.com/gomlx/gomlx/backends/xla"
@@ -89,6 +95,21 @@ func TestMeanSquaredError(t *testing.T) {
 		}, float32(5.0*1.0+1.0*4.0)/3, true)
 }
 
+func TestMeanSquaredErrorComplex(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "MeanSquaredError complex", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, []complex64{1 + 1i, 2 - 1i})
+		predictions := Const(g, []complex64{0, 2 + 1i})
+		weights := Const(g, []float32{1, 0.5})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{
+			// |1+1i|^2 = 2 and |-2i|^2 = 4: the complex square would be 2i and -4 instead.
+			MeanSquaredError([]*Node{labels}, []*Node{predictions}),
+			MeanSquaredError([]*Node{labels, weights}, []*Node{predictions}),
+		}
+		return
+	}, []any{float32(3), float32(2)}, 1e-5)
+}
+
 func TestMeanAbsoluteError(t *testing.T) {
 	testSomeFunc[float32](t, "MeanAbsoluteErrorWithWeightsAndMask",
 		func(g *Graph) (input, output *Node) {
@@ -160,6 +181,68 @@ func TestCategoricalCrossEntropy(t *testing.T) {
 		}, []float32{0, 10.0, 0}, true)
 }
 
+func TestMakeCategoricalCrossEntropyLogits(t *testing.T) {
+	require.Panics(t, func() {
+		backend := graphtest.BuildTestBackend()
+		g := NewGraph(backend, "MakeCategoricalCrossEntropyLogits")
+		defer g.Finalize()
+		logits := Zeros(g, shapes.Make(dtypes.Float32, 2, 3))
+		MakeCategoricalCrossEntropyLogits([]int{0})([]*Node{logits}, []*Node{logits})
+	})
+	graphtest.RunTestGraphFn(t, "MakeCategoricalCrossEntropyLogits", func(g *Graph) (inputs, outputs []*Node) {
+		// Shaped [batch=2, positions=2, classes=3].
+		labels := Const(g, [][][]float32{{{1, 0, 0}, {0, 1, 0}}, {{0, 0, 1}, {1, 0, 0}}})
+		logits := Const(g, [][][]float32{{{2, 1, 0}, {0.5, 1.5, -1}}, {{-1, 0, 3}, {0, 0, 0}}})
+		mask := Const(g, []bool{true, false})
+		inputs = []*Node{labels, logits}
+		perPosition := CategoricalCrossEntropyLogits([]*Node{labels}, []*Node{logits})
+		outputs = []*Node{
+			// Default: same as CategoricalCrossEntropyLogits, shaped [2, 2].
+			ReduceAllMax(Abs(Sub(
+				MakeCategoricalCrossEntropyLogits(nil)([]*Node{labels}, []*Node{logits}), perPosition))),
+			// Reducing also the positions: shaped [2].
+			ReduceAllMax(Abs(Sub(
+				MakeCategoricalCrossEntropyLogits([]int{1, -1})([]*Node{labels}, []*Node{logits}),
+				ReduceSum(perPosition, 1)))),
+			// Mask shaped [2], for the custom axes.
+			MakeCategoricalCrossEntropyLogits([]int{1, 2})([]*Node{labels, mask}, []*Node{logits}),
+		}
+		return
+	}, []any{
+		float32(0),
+		float32(0),
+		[]float32{0.779145, 0},
+	}, 1e-4)
+}
+
+func TestMakeCategoricalCrossEntropy(t *testing.T) {
+	require.Panics(t, func() { MakeCategoricalCrossEntropy(0.5) })
+	ctx := context.New()
+	ctx.SetParams(map[string]any{ParamLoss: "categorical_cross", ParamCCEClipEpsilon: 0.0})
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+
+	graphtest.RunTestGraphFn(t, "MakeCategoricalCrossEntropy", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, [][]float32{{0, 1, 0}, {0, 0, 1}})
+		predictions := Const(g, [][]float32{{0.05, 0.9, 0.05}, {0.1, 0.8, 0.1}})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{
+			// Unclipped reference: -sum(y*log(p)).
+			Neg(ReduceSum(Mul(labels, Log(predictions)), -1)),
+			MakeCategoricalCrossEntropy(0)([]*Node{labels}, []*Node{predictions}),
+			contextLossFn([]*Node{labels}, []*Node{predictions}),
+			// Clipped to [0.2, 0.8].
+			MakeCategoricalCrossEntropy(0.2)([]*Node{labels}, []*Node{predictions}),
+		}
+		return
+	}, []any{
+		[]float32{0.10536, 2.30259},
+		[]float32{0.10536, 2.30259},
+		[]float32{0.10536, 2.30259},
+		[]float32{0.22314, 1.60944},
+	}, 1e-4)
+}
+
 func TestHuberLoss(t *testing.T) {
 	graphtest.RunTestGraphFn(t, "MakeHuberLoss", func(g *Graph) (inputs, outputs []*Node) {
 		inputs = []*Node{
@@ -221,3 +304,374 @@ func TestAdaptivePowerLoss(t *testing.T) {
 			-1,     // L1 region: gradient is constant +/- 1 (while absolute error is +/- 2).
 		}}, 1e-2)
 }
+
+func TestSparseCategoricalCrossEntropyLogitsGather(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "SparseCategoricalCrossEntropyLogits vs one-hot", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, [][][]int32{{{3}, {0}}, {{1}, {4}}})
+		logits := Const(g, [][][]float32{
+			{{1, -2, 3, 0.5, 7}, {-1, 0, 0, 2, 1}},
+			{{100, 101, 99, 0, -100}, {0.1, 0.2, 0.3, 0.4, 0.5}}})
+		weights := Const(g, [][]float32{{1, 2}, {0.5, 1}})
+		mask := Const(g, [][]bool{{true, true}, {true, false}})
+		oneHotLabels := OneHot(Squeeze(labels, -1), 5, dtypes.Float32)
+		inputs = []*Node{labels, logits}
+		outputs = []*Node{
+			SparseCategoricalCrossEntropyLogits([]*Node{labels, weights, mask}, []*Node{logits}),
+			CategoricalCrossEntropyLogits([]*Node{oneHotLabels, weights, mask}, []*Node{logits}),
+		}
+		return
+	}, []any{
+		[][]float32{{6.52217, 2 * 3.52374}, {0.5 * 0.40761, 0}},
+		[][]float32{{6.52217, 2 * 3.52374}, {0.5 * 0.40761, 0}},
+	}, 1e-3)
+}
+
+func TestSparseLabelsWithoutTrailingAxis(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "Sparse labels [batch] and [batch, 1]", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, []int32{3, 0, 1})
+		logits := Const(g, [][]float32{{1, -2, 3, 0.5, 7}, {-1, 0, 0, 2, 1}, {100, 101, 99, 0, -100}})
+		mask := Const(g, []bool{true, true, false})
+		inputs = []*Node{labels, logits}
+		expandedLabels := InsertAxes(labels, -1)
+		outputs = []*Node{
+			ReduceAllMax(Abs(Sub(
+				SparseCategoricalCrossEntropyLogits([]*Node{labels, mask}, []*Node{logits}),
+				SparseCategoricalCrossEntropyLogits([]*Node{expandedLabels, mask}, []*Node{logits})))),
+			ReduceAllMax(Abs(Sub(
+				SparseCategoricalCrossEntropy([]*Node{labels}, []*Node{Softmax(logits)}),
+				SparseCategoricalCrossEntropy([]*Node{expandedLabels}, []*Node{Softmax(logits)})))),
+			SparseCategoricalAccuracy([]*Node{labels}, []*Node{logits}),
+		}
+		return
+	}, []any{float32(0), float32(0), []float32{0, 0, 1}}, 1e-5)
+
+	// Incompatible ranks are still an error.
+	backend := graphtest.BuildTestBackend()
+	g := NewGraph(backend, "SparseLabelsIncompatible")
+	logits := Zeros(g, shapes.Make(dtypes.Float32, 2, 3, 5))
+	require.Panics(t, func() {
+		SparseCategoricalCrossEntropyLogits([]*Node{Zeros(g, shapes.Make(dtypes.Int32, 2))}, []*Node{logits})
+	})
+	require.Panics(t, func() {
+		SparseCategoricalCrossEntropyLogits([]*Node{Zeros(g, shapes.Make(dtypes.Int32, 2, 4))}, []*Node{logits})
+	})
+	g.Finalize()
+}
+
+func TestSparseCategoricalCrossEntropy(t *testing.T) {
+	ctx := context.New()
+	ctx.SetParam(ParamLoss, "sparse_cross")
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+
+	graphtest.RunTestGraphFn(t, "SparseCategoricalCrossEntropy vs logits", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, [][][]int32{{{3}, {0}}, {{1}, {4}}})
+		logits := Const(g, [][][]float32{
+			{{1, -2, 3, 0.5, 7}, {-1, 0, 0, 2, 1}},
+			{{100, 101, 99, 0, -100}, {0.1, 0.2, 0.3, 0.4, 0.5}}})
+		probabilities := Softmax(logits)
+		weights := Const(g, [][]float32{{1, 2}, {0.5, 1}})
+		mask := Const(g, [][]bool{{true, true}, {true, false}})
+		inputs = []*Node{labels, logits}
+		outputs = []*Node{
+			SparseCategoricalCrossEntropyLogits([]*Node{labels, weights, mask}, []*Node{logits}),
+			SparseCategoricalCrossEntropy([]*Node{labels, weights, mask}, []*Node{probabilities}),
+			contextLossFn([]*Node{labels, weights, mask}, []*Node{probabilities}),
+		}
+		return
+	}, []any{
+		[][]float32{{6.52217, 2 * 3.52374}, {0.5 * 0.40761, 0}},
+		[][]float32{{6.52217, 2 * 3.52374}, {0.5 * 0.40761, 0}},
+		[][]float32{{6.52217, 2 * 3.52374}, {0.5 * 0.40761, 0}},
+	}, 1e-3)
+}
+
+func TestMakeMeanSquaredError(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "MakeMeanSquaredError", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, [][]float32{{1, 10}, {2, 20}, {3, 30}})
+		predictions := Const(g, [][]float32{{2, 11}, {2, 23}, {5, 30}})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{
+			MeanSquaredError([]*Node{labels}, []*Node{predictions}),
+			MakeMeanSquaredError([]float64{1, 1})([]*Node{labels}, []*Node{predictions}),
+			MakeMeanSquaredError([]float64{1, 0})([]*Node{labels}, []*Node{predictions}),
+			MakeMeanSquaredError([]float64{0, 2})([]*Node{labels}, []*Node{predictions}),
+		}
+		return
+	}, []any{
+		float32(1+4+1+9) / 6,
+		float32(1+4+1+9) / 6, // Uniform channel weights reproduce MeanSquaredError.
+		float32(1+4) / 6,     // Second channel zeroed.
+		float32(2*(1+9)) / 6, // First channel zeroed.
+	}, 1e-4)
+}
+
+func TestAssertBatchPreserved(t *testing.T) {
+	backend := graphtest.BuildTestBackend()
+	g := NewGraph(backend, "AssertBatchPreserved")
+	labels := Const(g, [][]float32{{1, 0}, {0, 1}, {1, 0}})
+	logits := Const(g, [][]float32{{1, 2}, {3, 4}, {5, 6}})
+	perExample := CategoricalCrossEntropyLogits([]*Node{labels}, []*Node{logits})
+	require.NotPanics(t, func() { AssertBatchPreserved(perExample, 3) })
+	require.Panics(t, func() { AssertBatchPreserved(ReduceAllMean(perExample), 3) })
+	require.Panics(t, func() { AssertBatchPreserved(perExample, 4) })
+	g.Finalize()
+}
+
+func TestMakeBinaryCrossentropyLogits(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "MakeBinaryCrossentropyLogits", func(g *Graph) (inputs, outputs []*Node) {
+		logits := Const(g, []float64{5, 1e-6, 0, 0, -1e-6, -5, 30, -30})
+		labels := Const(g, []float64{1, 0, 1, 0, 0, 1, 1, 1})
+		inputs = []*Node{labels, logits}
+		outputs = []*Node{
+			BinaryCrossentropyLogits([]*Node{labels}, []*Node{logits}),
+			MakeBinaryCrossentropyLogits(1)([]*Node{labels}, []*Node{logits}),
+			Sub(
+				MakeBinaryCrossentropyLogits(3)([]*Node{labels}, []*Node{logits}),
+				MulScalar(BinaryCrossentropyLogits([]*Node{labels}, []*Node{logits}), 3)),
+		}
+		return
+	}, []any{
+		[]float64{0.00671535, 0.69314768, 0.69314718, 0.69314718, 0.69314668, 5.00671535, 0, 30},
+		[]float64{0.00671535, 0.69314768, 0.69314718, 0.69314718, 0.69314668, 5.00671535, 0, 30},
+		// Positive examples have their loss scaled by 3, negative ones (labels=0) are unchanged.
+		[]float64{0, -2 * 0.69314768, 0, -2 * 0.69314718, -2 * 0.69314668, 0, 0, 0},
+	}, 1e-5)
+}
+
+func TestApplyWeightsAndMask(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "ApplyWeightsAndMask", func(g *Graph) (inputs, outputs []*Node) {
+		losses := Const(g, []float32{1, 2, 3, 4})
+		weights := Const(g, []float32{0.5, 1, 2, 3})
+		mask := Const(g, []bool{true, true, false, true})
+		inputs = []*Node{losses}
+		outputs = []*Node{
+			ApplyWeightsAndMask(losses, nil, nil),
+			ApplyWeightsAndMask(losses, weights, nil),
+			ApplyWeightsAndMask(losses, nil, mask),
+			ApplyWeightsAndMask(losses, weights, mask),
+		}
+		return
+	}, []any{
+		[]float32{1, 2, 3, 4},
+		[]float32{0.5, 2, 6, 12},
+		[]float32{1, 2, 0, 4},
+		[]float32{0.5, 2, 0, 12},
+	}, 1e-5)
+
+	// Builtin losses with weights and mask must match applying ApplyWeightsAndMask to the unweighted losses.
+	builtins := map[string]LossFn{
+		"BinaryCrossentropyLogits": BinaryCrossentropyLogits,
+		"BinaryCrossentropy": func(labels, predictions []*Node) *Node {
+			return BinaryCrossentropy(labels, []*Node{Sigmoid(predictions[0])})
+		},
+		"Huber":     MakeHuberLoss(1.0),
+		"APL":       MakeAdaptivePowerLoss(2, 1, 1, 1),
+		"Contrast":  MakeContrastiveLoss(1.0),
+		"PosWeight": MakeBinaryCrossentropyLogits(2.0),
+	}
+	for name, lossFn := range builtins {
+		graphtest.RunTestGraphFn(t, name, func(g *Graph) (inputs, outputs []*Node) {
+			labels := Const(g, []float32{1, 0, 1, 0})
+			predictions := Const(g, []float32{0.3, -2, 0.7, 1.5})
+			weights := Const(g, []float32{0.5, 1, 2, 3})
+			mask := Const(g, []bool{true, true, false, true})
+			inputs = []*Node{labels, predictions}
+			outputs = []*Node{Sub(
+				lossFn([]*Node{labels, weights, mask}, []*Node{predictions}),
+				ApplyWeightsAndMask(lossFn([]*Node{labels}, []*Node{predictions}), weights, mask))}
+			return
+		}, []any{[]float32{0, 0, 0, 0}}, 1e-5)
+	}
+}
+
+func TestCheckLabelsForWeightsAndMaskBroadcast(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "CheckLabelsForWeightsAndMask broadcast", func(g *Graph) (inputs, outputs []*Node) {
+		weightsShape := shapes.Make(dtypes.Float32, 2, 3)
+		labels := Zeros(g, weightsShape)
+		scalar := Const(g, float32(2))
+		perClass := Const(g, []float32{1, 2, 3})
+		perExample := Const(g, []float32{0.5, 4})
+		inputs = []*Node{scalar, perClass, perExample}
+		for _, w := range inputs {
+			weights, _ := CheckLabelsForWeightsAndMask(weightsShape, []*Node{labels, w})
+			outputs = append(outputs, weights)
+		}
+		return
+	}, []any{
+		[][]float32{{2, 2, 2}, {2, 2, 2}},
+		[][]float32{{1, 2, 3}, {1, 2, 3}},
+		[][]float32{{0.5, 0.5, 0.5}, {4, 4, 4}},
+	}, 1e-5)
+
+	// Builtin loss with per-class weights must match the explicitly broadcast weights.
+	graphtest.RunTestGraphFn(t, "BinaryCrossentropyLogits per-class weights", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, [][]float32{{1, 0, 1}, {0, 0, 1}})
+		predictions := Const(g, [][]float32{{0.3, -2, 0.7}, {1.5, 0.1, -1}})
+		perClass := Const(g, []float32{1, 2, 3})
+		mask := Const(g, [][]bool{{true, false, true}, {true, true, true}})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{ReduceAllMax(Abs(Sub(
+			BinaryCrossentropyLogits([]*Node{labels, perClass, mask}, []*Node{predictions}),
+			BinaryCrossentropyLogits([]*Node{labels, BroadcastToDims(perClass, 2, 3), mask}, []*Node{predictions}))))}
+		return
+	}, []any{float32(0)}, 1e-5)
+
+	// Incompatible shapes are not accepted as weights.
+	backend := graphtest.BuildTestBackend()
+	g := NewGraph(backend, "CheckLabelsForWeightsAndMask incompatible")
+	labels := Zeros(g, shapes.Make(dtypes.Float32, 2, 3))
+	require.Panics(t, func() {
+		CheckLabelsForWeightsAndMask(labels.Shape(), []*Node{labels, Const(g, []float32{1, 2, 3, 4})})
+	})
+	require.Panics(t, func() {
+		CheckLabelsForWeightsAndMask(labels.Shape(), []*Node{labels, Const(g, []float64{1, 2, 3})})
+	})
+
+	// Weights matching both the leading and the trailing axes are ambiguous.
+	square := Zeros(g, shapes.Make(dtypes.Float32, 4, 4))
+	require.Panics(t, func() {
+		CheckLabelsForWeightsAndMask(square.Shape(), []*Node{square, Const(g, []float32{1, 2, 3, 4})})
+	})
+	g.Finalize()
+}
+
+func TestCombinedLossFromContext(t *testing.T) {
+	ctx := context.New()
+	ctx.SetParam(ParamLoss, "mse,mae")
+	equalLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+
+	ctx = context.New()
+	ctx.SetParams(map[string]any{ParamLoss: "mse, mae", ParamLossWeights: "2, 0.5"})
+	weightedLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+
+	ctx = context.New()
+	ctx.SetParams(map[string]any{ParamLoss: "mse,mae", ParamLossWeights: "1"})
+	_, err = LossFromContext(ctx)
+	require.Error(t, err)
+
+	ctx = context.New()
+	ctx.SetParam(ParamLoss, "mse,unknown")
+	_, err = LossFromContext(ctx)
+	require.Error(t, err)
+
+	graphtest.RunTestGraphFn(t, "CombinedLoss", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, []float32{1, 2, 3})
+		predictions := Const(g, []float32{2, 4, 3})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{
+			equalLossFn([]*Node{labels}, []*Node{predictions}),
+			weightedLossFn([]*Node{labels}, []*Node{predictions}),
+		}
+		return
+	}, []any{
+		// MSE = 5/3, MAE = 1.
+		float32(0.5*5.0/3.0 + 0.5*1.0),
+		float32(2*5.0/3.0 + 0.5*1.0),
+	}, 1e-4)
+}
+
+func TestLossFromContextAllTypes(t *testing.T) {
+	require.Len(t, TypeStrings(), len(TypeValues()))
+	// Hyperparameters required by some losses, without a default.
+	requiredParams := map[Type]map[string]any{
+		TypeClassBalancedFocal: {ParamClassCounts: []int64{10, 90}},
+	}
+	for lossType, params := range requiredParams {
+		ctx := context.New()
+		ctx.SetParam(ParamLoss, lossType.String())
+		_, err := LossFromContext(ctx)
+		for name := range params {
+			require.ErrorContainsf(t, err, name, "loss %q should require hyperparameter %q", lossType, name)
+		}
+	}
+	for _, lossName := range TypeStrings() {
+		ctx := context.New()
+		ctx.SetParam(ParamLoss, lossName)
+		lossType, err := TypeString(lossName)
+		require.NoError(t, err)
+		for name, value := range requiredParams[lossType] {
+			ctx.SetParam(name, value)
+		}
+		lossFn, err := LossFromContext(ctx)
+		require.NoErrorf(t, err, "loss %q not handled by LossFromContext", lossName)
+		require.NotNilf(t, lossFn, "loss %q returned a nil LossFn", lossName)
+	}
+}
+
+func TestMakeLearnableAdaptivePowerLoss(t *testing.T) {
+	backend := graphtest.BuildTestBackend()
+	ctx := context.New()
+	ctx.SetParam(ParamAdaptivePowerLossNear, 3.0)
+	ctx.SetParam(ParamAdaptivePowerLossMiddleDelta, 10.0)
+	lossFn := MakeLearnableAdaptivePowerLoss(ctx)
+	exec := context.NewExec(backend, ctx, func(ctx *context.Context, labels, predictions *Node) []*Node {
+		g := predictions.Graph()
+		loss := lossFn([]*Node{labels}, []*Node{predictions})
+		scopedCtx := ctx.In(AdaptivePowerLossScope)
+		powerNear := scopedCtx.GetVariable("power_near").ValueGraph(g)
+		powerFar := scopedCtx.GetVariable("power_far").ValueGraph(g)
+		grads := Gradient(ReduceAllSum(loss), predictions, powerNear, powerFar)
+		return append([]*Node{loss}, grads...)
+	})
+	defer exec.Finalize()
+	outputs := exec.Call([]float32{1, 1, 1, 1, 1}, []float32{1, 1.1, 0.9, 11, -999})
+
+	// Variables created with the initial values from the hyperparameters.
+	scopedCtx := ctx.In(AdaptivePowerLossScope)
+	require.NotNil(t, scopedCtx.GetVariable("power_near"))
+	require.NotNil(t, scopedCtx.GetVariable("power_far"))
+	assert.Equal(t, float32(3), scopedCtx.GetVariable("power_near").Value().Value())
+	assert.Equal(t, float32(1), scopedCtx.GetVariable("power_far").Value().Value())
+
+	// Same loss and gradients w.r.t. the predictions as MakeAdaptivePowerLoss(3, 1, 10, 1).
+	assert.True(t, xslices.SlicesInDelta(outputs[0].Value(), []float32{0, 0.001, 0.001, 100, 1001.38275}, 1e-2),
+		"loss=%v", outputs[0].Value())
+	assert.True(t, xslices.SlicesInDelta(outputs[1].Value(), []float32{0, 0.03, -0.03, 20, -1}, 1e-2),
+		"gradient=%v", outputs[1].Value())
+
+	// The exponents participate in the graph: they get finite, non-zero gradients.
+	for ii, name := range []string{"power_near", "power_far"} {
+		grad := outputs[2+ii].Value().(float32)
+		fmt.Printf("\tgradient(%s)=%g\n", name, grad)
+		assert.Falsef(t, math.IsNaN(float64(grad)) || math.IsInf(float64(grad), 0), "gradient of %s is %g", name, grad)
+		assert.NotZerof(t, grad, "gradient of %s is zero", name)
+	}
+}
+
+func TestMakeSoftmaxCrossEntropyLogits(t *testing.T) {
+	ctx := context.New()
+	ctx.SetParams(map[string]any{ParamLoss: "categorical_cross_logits", ParamTemperature: 2.0, ParamLabelSmoothing: 0.1})
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+
+	ctx.SetParam(ParamTemperature, 0.0)
+	_, err = LossFromContext(ctx)
+	require.Error(t, err)
+	require.Panics(t, func() { MakeSoftmaxCrossEntropyLogits(1, 1) })
+	require.Panics(t, func() { MakeSoftmaxCrossEntropyLogits(-1, 0) })
+
+	graphtest.RunTestGraphFn(t, "MakeSoftmaxCrossEntropyLogits", func(g *Graph) (inputs, outputs []*Node) {
+		logits := Const(g, [][]float32{{1, 2, 3}, {0, 0, 0}})
+		labels := Const(g, [][]float32{{0, 0, 1}, {1, 0, 0}})
+		inputs = []*Node{labels, logits}
+		outputs = []*Node{
+			CategoricalCrossEntropyLogits([]*Node{labels}, []*Node{logits}),
+			MakeSoftmaxCrossEntropyLogits(1, 0)([]*Node{labels}, []*Node{logits}),
+			MakeSoftmaxCrossEntropyLogits(2, 0)([]*Node{labels}, []*Node{logits}),
+			MakeSoftmaxCrossEntropyLogits(1, 0.1)([]*Node{labels}, []*Node{logits}),
+			MakeSoftmaxCrossEntropyLogits(2, 0.1)([]*Node{labels}, []*Node{logits}),
+			contextLossFn([]*Node{labels}, []*Node{logits}),
+		}
+		return
+	}, []any{
+		[]float32{0.40761, 1.09861},
+		[]float32{0.40761, 1.09861},
+		[]float32{0.68027, 1.09861},
+		[]float32{0.50761, 1.09861},
+		[]float32{0.73027, 1.09861},
+		[]float32{0.73027, 1.09861},
+	}, 1e-4)
+}
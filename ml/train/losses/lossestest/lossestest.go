@@ -0,0 +1,74 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+// Package lossestest holds test utilities for loss functions (see package losses).
+package lossestest
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/train/losses"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gomlx/types/tensors"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AssertEquivalent compiles both loss functions on the test backend (see graphtest.BuildTestBackend), runs them
+// on the same random inputs of the given shape, and asserts that their outputs have the same shape and agree
+// within tol.
+//
+// The labels and the predictions are each the softmax (over the last axis) of random normal values: they are
+// in the (0, 1) range and sum to 1 over the last axis, so they are valid inputs for regression losses, for
+// binary and categorical losses on probabilities, and for losses on logits. The random values are generated
+// with a fixed seed, so the test is reproducible.
+func AssertEquivalent(t *testing.T, lossA, lossB losses.LossFn, shape shapes.Shape, tol float64) {
+	backend := graphtest.BuildTestBackend()
+	rng := rand.New(rand.NewSource(42))
+	randomTensor := func() *tensors.Tensor {
+		values := make([]float64, shape.Size())
+		for ii := range values {
+			values[ii] = rng.NormFloat64()
+		}
+		return tensors.FromFlatDataAndDimensions(values, shape.Dimensions...)
+	}
+	labels, predictions := randomTensor(), randomTensor()
+
+	exec := NewExec(backend, func(labels, predictions *Node) (*Node, *Node) {
+		labels = Softmax(ConvertDType(labels, shape.DType), -1)
+		predictions = Softmax(ConvertDType(predictions, shape.DType), -1)
+		// Outputs are converted to Float64 to ease the comparison.
+		lossA := lossA([]*Node{labels}, []*Node{predictions})
+		lossB := lossB([]*Node{labels}, []*Node{predictions})
+		return ConvertDType(lossA, dtypes.Float64), ConvertDType(lossB, dtypes.Float64)
+	})
+	defer exec.Finalize()
+	var results []*tensors.Tensor
+	require.NotPanics(t, func() { results = exec.Call(labels, predictions) }, "failed to execute losses")
+	resultA, resultB := results[0], results[1]
+	defer resultA.FinalizeAll()
+	defer resultB.FinalizeAll()
+	require.Truef(t, resultA.Shape().Equal(resultB.Shape()), "losses returned different shapes: %s and %s",
+		resultA.Shape(), resultB.Shape())
+	flatA, flatB := tensors.CopyFlatData[float64](resultA), tensors.CopyFlatData[float64](resultB)
+	for ii := range flatA {
+		assert.InDeltaf(t, flatA[ii], flatB[ii], tol, "losses differ at flat index %d: %g and %g", ii, flatA[ii], flatB[ii])
+	}
+}
@@ -0,0 +1,23 @@
+package lossestest
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/ml/train/losses"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+// referenceCategoricalCrossEntropy is a straightforward implementation of the categorical cross-entropy,
+// without weights, mask or clipping.
+func referenceCategoricalCrossEntropy(labels, predictions []*Node) *Node {
+	return Neg(ReduceSum(Mul(labels[0], Log(predictions[0])), -1))
+}
+
+func TestAssertEquivalent(t *testing.T) {
+	for _, dtype := range []dtypes.DType{dtypes.Float32, dtypes.Float64} {
+		AssertEquivalent(t, losses.CategoricalCrossEntropy, referenceCategoricalCrossEntropy,
+			shapes.Make(dtype, 5, 3), 1e-4)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+// MakeMedianReducedLoss returns a LossFn that reduces the per-example losses returned by inner to their median,
+// a scalar. The median is robust to outliers (e.g.: mislabeled examples), as opposed to the mean.
+//
+// Notice this changes the optimization target: at each step only the example(s) with the median loss contribute to
+// the gradient -- one example for an odd number of examples, or the mean of the two middle ones for an even number.
+// So it's usually slower to train, and it's no longer the expected loss that is minimized.
+//
+// The inner loss must return the per-example (unreduced) losses, of any shape: all its elements are considered.
+//
+// Since the graph package has no sort operation, the rank of each example is computed by pairwise comparison,
+// which takes O(numExamples^2) memory: fine for typical batch sizes, but not for very large ones.
+func MakeMedianReducedLoss(inner LossFn) LossFn {
+	return func(labels, predictions []*Node) *Node {
+		losses := inner(labels, predictions)
+		if losses.IsScalar() {
+			Panicf("MakeMedianReducedLoss requires inner to return the per-example (unreduced) losses, got a scalar")
+		}
+		g := losses.Graph()
+		n := losses.Shape().Size()
+		flat := Reshape(losses, n)
+		rank := pairwiseRank(StopGradient(flat), false)
+		// Weight of each example in the median: the middle one, or half of each of the two middle ones.
+		middleLow, middleHigh := (n-1)/2, n/2
+		isMiddle := LogicalOr(
+			Equal(rank, Scalar(g, dtypes.Int32, middleLow)),
+			Equal(rank, Scalar(g, dtypes.Int32, middleHigh)))
+		weight := 1.0
+		if middleLow != middleHigh {
+			weight = 0.5
+		}
+		weights := Where(isMiddle, Scalar(g, flat.DType(), weight), ZerosLike(flat))
+		return ReduceAllSum(Mul(flat, weights))
+	}
+}
@@ -0,0 +1,35 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+)
+
+func TestMakeMedianReducedLoss(t *testing.T) {
+	absErrors := func(labels, predictions []*Node) *Node {
+		return Abs(Sub(predictions[0], labels[0]))
+	}
+	medianMAE := MakeMedianReducedLoss(absErrors)
+	graphtest.RunTestGraphFn(t, "MakeMedianReducedLoss", func(g *Graph) (inputs, outputs []*Node) {
+		// Per-example absolute errors: {3, 100, 1, 2, 2}, with an outlier and a tie.
+		oddLabels := Const(g, []float32{0, 0, 0, 0, 0})
+		oddPredictions := Const(g, []float32{3, -100, 1, 2, -2})
+		// Per-example absolute errors: {4, 1, 1000, 2}.
+		evenLabels := Const(g, [][]float32{{1, 1}, {1, 1}})
+		evenPredictions := Const(g, [][]float32{{5, 0}, {1001, 3}})
+		inputs = []*Node{oddPredictions, evenPredictions}
+		outputs = []*Node{
+			medianMAE([]*Node{oddLabels}, []*Node{oddPredictions}),
+			medianMAE([]*Node{evenLabels}, []*Node{evenPredictions}),
+			// Only the median example(s) have a gradient.
+			Gradient(medianMAE([]*Node{evenLabels}, []*Node{evenPredictions}), evenPredictions)[0],
+		}
+		return
+	}, []any{
+		float32(2),
+		float32(3),
+		[][]float32{{0.5, 0}, {0, 0.5}},
+	}, 1e-5)
+}
@@ -0,0 +1,68 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+)
+
+// MakeMultiHeadLoss returns a LossFn for multi-head models: it dispatches the labels and predictions of each head
+// to its loss in heads, and returns the sum of the ReduceAllMean of each head's loss, a scalar.
+//
+// The labels and predictions are flat slices, grouped per head, in the order of heads, with the same number of
+// entries for every head:
+//
+//   - predictions: len(predictions)/len(heads) entries per head, usually one -- predictions[h] is the prediction of
+//     head h.
+//   - labels: len(labels)/len(heads) entries per head -- the labels of head h are
+//     `labels[h*k : (h+1)*k]`, with k = len(labels)/len(heads). Following the usual convention, each group starts
+//     with the labels of the head, followed by its optional weights and/or mask. E.g.: with 2 heads and a mask for
+//     each, labels is `{labels0, mask0, labels1, mask1}`.
+//
+// Heads that don't use weights or masks should be given weights of 1 (or a mask all true), so the number of
+// entries per head is the same. It panics if len(labels) or len(predictions) is not a multiple of len(heads).
+//
+// To weight the heads differently, wrap their losses with MakeScaledLoss.
+func MakeMultiHeadLoss(heads []LossFn) LossFn {
+	numHeads := len(heads)
+	if numHeads == 0 {
+		Panicf("MakeMultiHeadLoss requires at least one head")
+	}
+	return func(labels, predictions []*Node) (loss *Node) {
+		if len(labels)%numHeads != 0 || len(predictions)%numHeads != 0 || len(predictions) == 0 {
+			Panicf("MakeMultiHeadLoss with %d heads requires labels (%d given) and predictions (%d given) with "+
+				"the same number of entries per head", numHeads, len(labels), len(predictions))
+		}
+		labelsPerHead := len(labels) / numHeads
+		predictionsPerHead := len(predictions) / numHeads
+		for ii, head := range heads {
+			headLoss := head(
+				labels[ii*labelsPerHead:(ii+1)*labelsPerHead],
+				predictions[ii*predictionsPerHead:(ii+1)*predictionsPerHead])
+			if !headLoss.Shape().IsScalar() {
+				headLoss = ReduceAllMean(headLoss)
+			}
+			if loss == nil {
+				loss = headLoss
+			} else {
+				loss = Add(loss, headLoss)
+			}
+		}
+		return loss
+	}
+}
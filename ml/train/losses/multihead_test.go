@@ -0,0 +1,46 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+)
+
+func TestMakeMultiHeadLoss(t *testing.T) {
+	multiHeadLoss := MakeMultiHeadLoss([]LossFn{MeanSquaredError, SparseCategoricalCrossEntropyLogits})
+	graphtest.RunTestGraphFn(t, "MakeMultiHeadLoss", func(g *Graph) (inputs, outputs []*Node) {
+		// Regression head.
+		regressionLabels := Const(g, [][]float32{{1}, {2}, {3}})
+		regressionPredictions := Const(g, [][]float32{{1.5}, {2}, {1}})
+		regressionMask := Const(g, [][]bool{{true}, {true}, {false}})
+		// Classification head.
+		classLabels := Const(g, []int32{0, 2, 1})
+		logits := Const(g, [][]float32{{1, 2, 0.5}, {0.1, 0.1, 3}, {0, 0, 0}})
+		classMask := Const(g, []bool{true, false, true})
+		inputs = []*Node{regressionPredictions, logits}
+		outputs = []*Node{
+			multiHeadLoss(
+				[]*Node{regressionLabels, classLabels},
+				[]*Node{regressionPredictions, logits}),
+			Add(
+				ReduceAllMean(MeanSquaredError([]*Node{regressionLabels}, []*Node{regressionPredictions})),
+				ReduceAllMean(SparseCategoricalCrossEntropyLogits([]*Node{classLabels}, []*Node{logits}))),
+			// With a mask per head.
+			multiHeadLoss(
+				[]*Node{regressionLabels, regressionMask, classLabels, classMask},
+				[]*Node{regressionPredictions, logits}),
+			Add(
+				ReduceAllMean(MeanSquaredError([]*Node{regressionLabels, regressionMask}, []*Node{regressionPredictions})),
+				ReduceAllMean(SparseCategoricalCrossEntropyLogits([]*Node{classLabels, classMask}, []*Node{logits}))),
+		}
+		return
+	}, []any{
+		// MSE: (0.25+0+4)/3; cross-entropy: (1.464369+0.104402+1.098612)/3.
+		float32(1.416667 + 0.889128),
+		float32(1.416667 + 0.889128),
+		// Masked MSE: 0.25/3; masked cross-entropy: (1.464369+1.098612)/3.
+		float32(0.083333 + 0.854327),
+		float32(0.083333 + 0.854327),
+	}, 1e-4)
+}
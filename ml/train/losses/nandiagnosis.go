@@ -0,0 +1,115 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/tensors"
+	"k8s.io/klog/v2"
+)
+
+// nanDiagnosisPrefix prefixes the messages of the nodes logged by MakeNaNDiagnosingLoss.
+const nanDiagnosisPrefix = "losses.NaNDiagnosis: "
+
+// MakeNaNDiagnosingLoss returns a LossFn that returns the loss of inner unchanged, but additionally checks which
+// of its intermediate values contain NaN or Inf values, in the order they are computed: the labels (labels[0]),
+// the predictions, the weights (the float labels after labels[0]) and the loss returned by inner. So when the loss
+// becomes NaN, the diagnosis tells which term caused it.
+//
+// Each check adds a logged node (see Node.SetLogged) to the graph: to report the diagnosis, set the node logger of
+// the executor with NewNaNDiagnosisLogger. Otherwise, the result of the checks is printed by the executor's default
+// node logger. Integer and boolean values (e.g.: sparse labels and masks) are not checked.
+//
+// It is a separate mechanism from the backend NaN guard (see xla.Backend.WithNaNGuard), and it doesn't run when
+// the guard fires: the guard is implemented by the backend, which only sees the flat outputs of the computation,
+// not the graph nodes that produced them, so it can only tell which output has NaN values. And it panics inside
+// the execution, before the values of the logged nodes are returned to be reported. So disable the NaN guard
+// while diagnosing the loss with MakeNaNDiagnosingLoss.
+//
+// It adds a reduction for each value checked, so it's meant for debugging only.
+func MakeNaNDiagnosingLoss(inner LossFn) LossFn {
+	return func(labels, predictions []*Node) *Node {
+		if len(labels) > 0 {
+			diagnoseNaN("labels[0]", labels[0])
+		}
+		for ii, prediction := range predictions {
+			diagnoseNaN(fmt.Sprintf("predictions[%d]", ii), prediction)
+		}
+		for ii := 1; ii < len(labels); ii++ {
+			diagnoseNaN(fmt.Sprintf("labels[%d] (weights)", ii), labels[ii])
+		}
+		loss := inner(labels, predictions)
+		diagnoseNaN("loss", loss)
+		return loss
+	}
+}
+
+// diagnoseNaN adds a logged node that is true if x has any NaN or Inf value, if x is a float.
+func diagnoseNaN(name string, x *Node) {
+	if x == nil || !x.DType().IsFloat() {
+		return
+	}
+	hasNaN := LogicalNot(LogicalAll(IsFinite(x)))
+	hasNaN.SetLoggedf("%s%s (shape %s) has NaN or Inf values", nanDiagnosisPrefix, name, x.Shape())
+}
+
+// NewNaNDiagnosisLogger returns a graph.LoggerFn that reports the checks of MakeNaNDiagnosingLoss: if any value has
+// NaN or Inf values, it panics (if panicOnNaN is set) or logs an error, naming the first value found with NaN or Inf
+// values -- the likely cause -- along with all the others. Checks that pass are not reported.
+//
+// Other logged nodes are passed to next, if it is not nil -- usually the graph.DefaultNodeLogger.
+//
+// Example:
+//
+//	lossFn = losses.MakeNaNDiagnosingLoss(lossFn)
+//	exec.SetNodeLogger(losses.NewNaNDiagnosisLogger(graph.DefaultNodeLogger, true))
+func NewNaNDiagnosisLogger(next LoggerFn, panicOnNaN bool) LoggerFn {
+	return func(g *Graph, messages []string, values []*tensors.Tensor, nodes []NodeId) {
+		var otherMessages []string
+		var otherValues []*tensors.Tensor
+		var otherNodes []NodeId
+		var failures []string
+		for ii, msg := range messages {
+			if !strings.HasPrefix(msg, nanDiagnosisPrefix) {
+				otherMessages = append(otherMessages, msg)
+				otherValues = append(otherValues, values[ii])
+				otherNodes = append(otherNodes, nodes[ii])
+				continue
+			}
+			if tensors.ToScalar[bool](values[ii]) {
+				failures = append(failures, strings.TrimPrefix(msg, nanDiagnosisPrefix))
+			}
+		}
+		if len(failures) > 0 {
+			report := fmt.Sprintf("graph %q: %sfirst found in %s", g.Name(), nanDiagnosisPrefix, failures[0])
+			if len(failures) > 1 {
+				report += "; also found in: " + strings.Join(failures[1:], "; ")
+			}
+			if panicOnNaN {
+				Panicf("%s", report)
+			}
+			klog.Errorf("%s", report)
+		}
+		if next != nil {
+			next(g, otherMessages, otherValues, otherNodes)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package losses
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/types/tensors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeNaNDiagnosingLoss(t *testing.T) {
+	backend := graphtest.BuildTestBackend()
+	lossFn := MakeNaNDiagnosingLoss(MeanSquaredError)
+	exec := NewExec(backend, func(labels, predictions, weights *Node) *Node {
+		return lossFn([]*Node{labels, weights}, []*Node{predictions})
+	})
+	defer exec.Finalize()
+	var otherMessages []string
+	exec.SetNodeLogger(NewNaNDiagnosisLogger(func(_ *Graph, messages []string, _ []*tensors.Tensor, _ []NodeId) {
+		otherMessages = append(otherMessages, messages...)
+	}, true))
+
+	labels := []float32{1, 2, 3}
+	weights := []float32{1, 1, 1}
+	nan := float32(math.NaN())
+	require.NotPanics(t, func() { exec.Call(labels, []float32{1, 2, 2}, weights) })
+
+	// NaN in the predictions: it is also in the loss, but predictions is named first.
+	err := exceptions.TryCatch[error](func() { exec.Call(labels, []float32{1, nan, 2}, weights) })
+	require.Error(t, err)
+	require.ErrorContains(t, err, "first found in predictions[0]")
+	require.ErrorContains(t, err, "also found in: loss")
+	require.NotContains(t, err.Error(), "labels[0]")
+
+	// Inf in the weights.
+	err = exceptions.TryCatch[error](func() {
+		exec.Call(labels, []float32{1, 2, 2}, []float32{1, float32(math.Inf(1)), 1})
+	})
+	require.ErrorContains(t, err, "first found in labels[1] (weights)")
+
+	// The diagnosis messages are not passed along.
+	require.Empty(t, otherMessages)
+}
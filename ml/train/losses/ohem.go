@@ -0,0 +1,93 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	"math"
+
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+// MakeOHEMLoss returns a LossFn that implements Online Hard Example Mining (OHEM): only the hardest
+// examples -- the ones with the highest loss -- contribute to the gradient.
+//
+// The inner loss must return the per-example (unreduced) losses. The ceil(keepFraction * numExamples) examples with
+// the highest loss are kept, and the loss of the others (the "easy" examples) is set to 0 before reduction, so they
+// contribute no gradient. Ties are broken by the position of the example.
+//
+// The returned loss has the same shape as the one returned by inner.
+//
+// keepFraction must be in (0, 1]: if it is 1, it's the same as inner.
+//
+// Since the graph package has no sort (or top-k) operation, the rank of each example is computed by pairwise
+// comparison, which takes O(numExamples^2) memory: fine for typical batch sizes (or number of anchors per image),
+// but not for very large ones.
+func MakeOHEMLoss(inner LossFn, keepFraction float64) LossFn {
+	if keepFraction <= 0 || keepFraction > 1 {
+		Panicf("MakeOHEMLoss requires keepFraction in (0, 1], got %g", keepFraction)
+	}
+	if keepFraction == 1 {
+		return inner
+	}
+	return func(labels, predictions []*Node) *Node {
+		losses := inner(labels, predictions)
+		if losses.IsScalar() {
+			Panicf("MakeOHEMLoss requires inner to return the per-example (unreduced) losses, got a scalar")
+		}
+		numExamples := losses.Shape().Size()
+		numKeep := int(math.Ceil(keepFraction * float64(numExamples)))
+		if numKeep >= numExamples {
+			return losses
+		}
+		keep := Reshape(topKMask(Reshape(StopGradient(losses), numExamples), numKeep), losses.Shape().Dimensions...)
+		return Where(keep, losses, ZerosLike(losses))
+	}
+}
+
+// topKMask returns a boolean mask of the same shape as the 1D values, set to true for its k largest values.
+// Ties are broken by position: the first ones are considered larger.
+func topKMask(values *Node, k int) *Node {
+	rank := pairwiseRank(values, true)
+	return LessThan(rank, Scalar(values.Graph(), dtypes.Int32, k))
+}
+
+// pairwiseRank returns the rank (as Int32) of each element of the 1D values, in ascending order -- or descending, if
+// descending is true. Ties are broken by position: the first ones ranked first. So the ranks are a permutation of
+// 0 to n-1.
+//
+// Since the graph package has no sort operation, it's computed by pairwise comparison, in O(n^2) memory.
+func pairwiseRank(values *Node, descending bool) *Node {
+	g := values.Graph()
+	n := values.Shape().Dim(0)
+	// rowValues[i, j] = values[i], colValues[i, j] = values[j].
+	rowValues := BroadcastToDims(InsertAxes(values, -1), n, n)
+	colValues := BroadcastToDims(InsertAxes(values, 0), n, n)
+	idxShape := shapes.Make(dtypes.Int32, n, n)
+	rowIdx, colIdx := Iota(g, idxShape, 0), Iota(g, idxShape, 1)
+	// before[i, j] is true if element j ranks before element i.
+	var before *Node
+	if descending {
+		before = GreaterThan(colValues, rowValues)
+	} else {
+		before = LessThan(colValues, rowValues)
+	}
+	before = LogicalOr(before, LogicalAnd(Equal(colValues, rowValues), LessThan(colIdx, rowIdx)))
+	return ReduceSum(ConvertDType(before, dtypes.Int32), -1)
+}
@@ -0,0 +1,32 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeOHEMLoss(t *testing.T) {
+	require.Panics(t, func() { MakeOHEMLoss(MeanSquaredError, 0) })
+	require.Panics(t, func() { MakeOHEMLoss(MeanSquaredError, 1.5) })
+	squaredError := func(labels, predictions []*Node) *Node {
+		return Square(Sub(predictions[0], labels[0]))
+	}
+	graphtest.RunTestGraphFn(t, "MakeOHEMLoss", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, [][]float32{{0, 0}, {0, 0}, {0, 0}})
+		predictions := Const(g, [][]float32{{3, 1}, {0.5, 2}, {2, -4}})
+		inputs = []*Node{labels, predictions}
+		// 6 examples, keeping the 3 hardest.
+		loss := MakeOHEMLoss(squaredError, 0.5)([]*Node{labels}, []*Node{predictions})
+		grad := Gradient(ReduceAllSum(loss), predictions)[0]
+		outputs = []*Node{loss, grad}
+		return
+	}, []any{
+		// The tie between the two examples with loss 4 is broken by position.
+		[][]float32{{9, 0}, {0, 4}, {0, 16}},
+		// The easy examples have zero gradient.
+		[][]float32{{6, 0}, {0, 4}, {0, -8}},
+	}, 1e-4)
+}
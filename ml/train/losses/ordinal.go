@@ -0,0 +1,83 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	"slices"
+
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/shapes"
+)
+
+// CoralLoss implements the CORAL (COnsistent RAnk Logits) loss for ordinal regression, see
+// "Rank consistent ordinal regression for neural networks with application to age estimation",
+// https://arxiv.org/abs/1901.07884
+//
+// For an ordinal target with numClasses classes, the model outputs logits[0] shaped `[batch_size, numClasses-1]`,
+// one logit per threshold "label > k", and labels[0] (same shape) holds the cumulative {0, 1} encoding of the target --
+// e.g.: for 4 classes, class 2 is encoded as `[1, 1, 0]`. The loss is the sum over the thresholds of the
+// BinaryCrossentropyLogits, so one loss per example is returned (shaped `[batch_size]`).
+//
+// It *does not* reduce-mean the losses, they are returned individually for each element of the batch and need
+// to be ReduceAllMean (usually the mean, but it could be the sum also) before used for training.
+//
+// Optional extra `labels` `*Node`:
+//   - Per-threshold importance weights, shaped `[numClasses-1]` or with the same shape as logits[0]. They are
+//     applied to each threshold loss before summing. If the batch size is equal to numClasses-1, use the full shape,
+//     to avoid ambiguity with the per-example weights.
+//   - Per-example weights, with the shape of logits without the last axis (usually simply `[batch_size]`).
+//   - Per-example mask, with booleans with the same dimensions as logits without the last axis.
+func CoralLoss(labels, logits []*Node) *Node {
+	logits0 := logits[0]
+	labels0 := convertLabels("CoralLoss", labels[0], logits0.DType())
+	logitsShape := logits0.Shape()
+	if logitsShape.Rank() < 2 {
+		Panicf("CoralLoss requires logits[0] to be shaped [batch_size, numClasses-1], got %s", logitsShape)
+	}
+	if !slices.Equal(labels0.Shape().Dimensions, logitsShape.Dimensions) {
+		Panicf("CoralLoss labels[0] (%s) must have the same dimensions as logits[0] (%s), with the cumulative encoding of the labels",
+			labels0.Shape(), logitsShape)
+	}
+	numThresholds := logitsShape.Dimensions[logitsShape.Rank()-1]
+
+	// Separate the per-threshold importance weights from the per-example weights and mask.
+	var importance *Node
+	extras := []*Node{labels0}
+	for _, extra := range labels[1:] {
+		if importance == nil && extra.DType() == logits0.DType() &&
+			(extra.Shape().Equal(logitsShape) || slices.Equal(extra.Shape().Dimensions, []int{numThresholds})) {
+			importance = extra
+			continue
+		}
+		extras = append(extras, extra)
+	}
+	weightsShape := shapes.Make(logits0.DType(), logitsShape.Dimensions[:logitsShape.Rank()-1]...)
+	weights, mask := CheckLabelsForWeightsAndMask(weightsShape, extras)
+
+	// Per-threshold losses, using the numerically stable BinaryCrossentropyLogits.
+	losses := BinaryCrossentropyLogits([]*Node{labels0}, []*Node{logits0})
+	if importance != nil {
+		if importance.Rank() != logitsShape.Rank() {
+			importance = ExpandLeftToRank(importance, logitsShape.Rank())
+		}
+		losses = Mul(losses, importance)
+	}
+	losses = ReduceSum(losses, -1)
+	losses = ApplyWeightsAndMask(losses, weights, mask)
+	return losses
+}
@@ -0,0 +1,31 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+)
+
+func TestCoralLoss(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "CoralLoss", func(g *Graph) (inputs, outputs []*Node) {
+		// 4 classes, 3 thresholds: both examples are labeled with class 2, cumulative encoding [1, 1, 0].
+		labels := Const(g, [][]float32{{1, 1, 0}, {1, 1, 0}})
+		logits := Const(g, [][]float32{
+			{2, 1, -1}, // Monotonic (rank consistent) thresholds.
+			{-1, 1, 2}, // Same logits, but non-monotonic.
+		})
+		importance := Const(g, []float32{1, 2, 3})
+		inputs = []*Node{labels, logits}
+		outputs = []*Node{
+			CoralLoss([]*Node{labels}, []*Node{logits}),
+			CoralLoss([]*Node{labels, importance}, []*Node{logits}),
+		}
+		return
+	}, []any{
+		// softplus(-2) + softplus(-1) + softplus(-1) vs softplus(1) + softplus(-1) + softplus(2):
+		// monotonic thresholds get the lower loss.
+		[]float32{0.75345, 3.75345},
+		[]float32{1.69323, 8.32057},
+	}, 1e-3)
+}
@@ -0,0 +1,68 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+)
+
+// poissonDeviance returns the per-element Poisson unit deviance `2 * (y*log(y/mu) - (y - mu))`, where the first
+// term is taken to be 0 for y == 0. mu is clamped to a small epsilon, so the deviance is always finite.
+func poissonDeviance(y, mu *Node) *Node {
+	g := mu.Graph()
+	epsilon := epsilonForDType(g, mu.DType())
+	mu = Max(mu, epsilon)
+	isPositive := GreaterThan(y, ZerosLike(y))
+	safeY := Where(isPositive, y, OnesLike(y))
+	logTerm := Where(isPositive, Mul(y, Log(Div(safeY, mu))), ZerosLike(y))
+	return MulScalar(Sub(logTerm, Sub(y, mu)), 2)
+}
+
+// PoissonDevianceExplained returns the fraction of the Poisson deviance explained by the predictions, the
+// analogue of R² for count data:
+//
+//	1 - D(labels, predictions) / D(labels, mean(labels))
+//
+// Where D is the total Poisson deviance, and mean(labels) is the (weighted) mean of the labels, the "null"
+// constant-mean model. It is 1 for perfect predictions, 0 for predictions as good as the mean, and negative for
+// worse ones.
+//
+// labels are non-negative counts, and predictions the predicted (positive) means, with the same shape. Optional
+// weights and mask, with the same shape as labels, can be given as extra labels, as in MakeTweedieLoss: they weight
+// both the deviance of the predictions and of the null model, and the mean of the labels.
+//
+// It's a metric, not a loss: it returns a scalar, reduced over all elements.
+func PoissonDevianceExplained(labels, predictions []*Node) *Node {
+	predictions0 := predictions[0]
+	g := predictions0.Graph()
+	dtype := predictions0.DType()
+	labels0 := convertLabels("PoissonDevianceExplained", labels[0], dtype)
+	if !labels0.Shape().Equal(predictions0.Shape()) {
+		Panicf("labels[0] (%s) and predictions[0] (%s) must have same shape", labels0.Shape(), predictions0.Shape())
+	}
+	weights, mask := CheckLabelsForWeightsAndMask(labels0.Shape(), labels)
+	epsilon := epsilonForDType(g, dtype)
+
+	weightSum := Max(effectiveWeightSum(labels0, weights, mask), epsilon)
+	meanLabels := Div(ReduceAllSum(ApplyWeightsAndMask(labels0, weights, mask)), weightSum)
+	nullPredictions := BroadcastToShape(meanLabels, labels0.Shape())
+
+	deviance := ReduceAllSum(ApplyWeightsAndMask(poissonDeviance(labels0, predictions0), weights, mask))
+	nullDeviance := ReduceAllSum(ApplyWeightsAndMask(poissonDeviance(labels0, nullPredictions), weights, mask))
+	return OneMinus(Div(deviance, Max(nullDeviance, epsilon)))
+}
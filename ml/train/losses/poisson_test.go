@@ -0,0 +1,33 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+)
+
+func TestPoissonDevianceExplained(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "PoissonDevianceExplained", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, []float32{0, 1, 2, 5})
+		predictions := Const(g, []float32{0.5, 1, 3, 4})
+		meanPredictions := Const(g, []float32{2, 2, 2, 2})
+		weights := Const(g, []float32{1, 2, 1, 1})
+		mask := Const(g, []bool{true, true, true, false})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{
+			// Perfect predictions.
+			PoissonDevianceExplained([]*Node{labels}, []*Node{labels}),
+			// Constant-mean baseline.
+			PoissonDevianceExplained([]*Node{labels}, []*Node{meanPredictions}),
+			PoissonDevianceExplained([]*Node{labels}, []*Node{predictions}),
+			PoissonDevianceExplained([]*Node{labels, weights, mask}, []*Node{predictions}),
+		}
+		return
+	}, []any{
+		float32(1),
+		float32(0),
+		float32(0.79302),
+		float32(0.50294),
+	}, 1e-4)
+}
@@ -0,0 +1,80 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/pkg/errors"
+)
+
+// ParamLossAccumDType is the context hyperparameter with the dtype (e.g.: "float32") in which the losses returned by
+// LossFromContext are computed, see MakeHigherPrecisionLoss.
+//
+// The default, or if set to empty, is to compute the loss in the dtype of the predictions.
+const ParamLossAccumDType = "loss_accum_dtype"
+
+// MakeHigherPrecisionLoss returns a LossFn that computes inner in the higher precision accumDType (usually
+// float32), and casts the returned loss back to the dtype of predictions[0].
+//
+// This is the standard practice for mixed-precision training: with float16/bfloat16 predictions, the sums in
+// the losses (e.g.: the log-sum-exp of the cross-entropy, or the reduction over the batch) lose a lot of accuracy
+// if accumulated in the predictions' dtype.
+//
+// Only the float predictions and labels (including the weights) of a lower precision than accumDType are
+// converted: integer labels and boolean masks are passed along unchanged.
+func MakeHigherPrecisionLoss(inner LossFn, accumDType dtypes.DType) LossFn {
+	if !accumDType.IsFloat() {
+		Panicf("MakeHigherPrecisionLoss requires a float accumDType, got %s", accumDType)
+	}
+	return func(labels, predictions []*Node) *Node {
+		dtype := predictions[0].DType()
+		loss := inner(upcastFloats(labels, accumDType), upcastFloats(predictions, accumDType))
+		if loss.DType() != dtype && dtype.IsFloat() {
+			loss = ConvertDType(loss, dtype)
+		}
+		return loss
+	}
+}
+
+// upcastFloats returns a copy of nodes with the float values of lower precision than dtype converted to dtype.
+func upcastFloats(nodes []*Node, dtype dtypes.DType) []*Node {
+	converted := make([]*Node, len(nodes))
+	for ii, node := range nodes {
+		if node != nil && node.DType().IsFloat() && node.DType().Size() < dtype.Size() {
+			node = ConvertDType(node, dtype)
+		}
+		converted[ii] = node
+	}
+	return converted
+}
+
+// higherPrecisionFromContext wraps lossFn with MakeHigherPrecisionLoss if ParamLossAccumDType is set.
+func higherPrecisionFromContext(ctx *context.Context, lossFn LossFn) (LossFn, error) {
+	dtypeStr := context.GetParamOr(ctx, ParamLossAccumDType, "")
+	if dtypeStr == "" {
+		return lossFn, nil
+	}
+	dtype, err := dtypes.DTypeString(dtypeStr)
+	if err != nil || !dtype.IsFloat() {
+		return nil, errors.Errorf("invalid hyperparameter %q=%q, it must be a float dtype, e.g.: \"float32\"",
+			ParamLossAccumDType, dtypeStr)
+	}
+	return MakeHigherPrecisionLoss(lossFn, dtype), nil
+}
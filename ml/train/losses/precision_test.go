@@ -0,0 +1,57 @@
+package losses
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeHigherPrecisionLoss(t *testing.T) {
+	backend := graphtest.BuildTestBackend()
+	ctx := context.New()
+	ctx.SetParams(map[string]any{ParamLoss: "categorical_cross_logits", ParamLossAccumDType: "float32"})
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+	ctx.SetParam(ParamLossAccumDType, "int32")
+	_, err = LossFromContext(ctx)
+	require.Error(t, err)
+
+	// Many classes, so the log-sum-exp accumulates many values.
+	const batchSize, numClasses = 8, 4096
+	logits := make([][]float32, batchSize)
+	for ii := range logits {
+		logits[ii] = make([]float32, numClasses)
+		for jj := range logits[ii] {
+			logits[ii][jj] = float32(3 * math.Sin(float64(ii*numClasses+jj)))
+		}
+	}
+	// Returns the absolute errors of the bfloat16 losses, computed in bfloat16, in float32 with
+	// MakeHigherPrecisionLoss, and with LossFromContext, when compared with the loss computed in float64.
+	results := ExecOnce(backend, func(logits *Node) *Node {
+		g := logits.Graph()
+		labels := OneHot(Iota(g, shapes.Make(dtypes.Int32, batchSize), 0), numClasses, dtypes.Float32)
+		lossOf := func(lossFn LossFn, logits *Node) *Node {
+			loss := lossFn([]*Node{ConvertDType(labels, logits.DType())}, []*Node{logits})
+			return ReduceAllSum(ConvertDType(loss, dtypes.Float64))
+		}
+		bf16Logits := ConvertDType(logits, dtypes.BFloat16)
+		// Reference computed from the same bfloat16 values, but in float64.
+		want := lossOf(CategoricalCrossEntropyLogits, ConvertDType(bf16Logits, dtypes.Float64))
+		return Abs(Sub(Stack([]*Node{
+			lossOf(CategoricalCrossEntropyLogits, bf16Logits),
+			lossOf(MakeHigherPrecisionLoss(CategoricalCrossEntropyLogits, dtypes.Float32), bf16Logits),
+			lossOf(contextLossFn, bf16Logits),
+		}, 0), want))
+	}, logits).Value().([]float64)
+	pureBF16Error, float32Error, contextError := results[0], results[1], results[2]
+	t.Logf("Absolute errors: bfloat16=%g, accumulated in float32=%g, from context=%g",
+		pureBF16Error, float32Error, contextError)
+	require.Less(t, float32Error, pureBF16Error)
+	require.Equal(t, float32Error, contextError)
+}
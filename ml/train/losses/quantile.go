@@ -0,0 +1,94 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/shapes"
+)
+
+// MakeMultiQuantileLoss returns a loss function for models predicting multiple quantiles simultaneously: it's the sum
+// of the pinball (quantile) losses of each quantile, plus a penalty for crossing quantiles.
+//
+// The quantiles must be given in strictly increasing order, each in the range (0, 1).
+//
+// The crossingPenalty discourages a lower quantile prediction from exceeding the prediction of the next higher
+// quantile: the term `crossingPenalty * sum_i(relu(pred[q_i] - pred[q_{i+1}]))` is added to the loss.
+// Set it to 0 to disable it.
+//
+// For the returned loss function:
+//   - predictions[0] must be shaped `[batch_size, len(quantiles)]`, with one prediction per quantile.
+//   - labels[0] must have batch_size elements, shaped `[batch_size]` or `[batch_size, 1]`.
+//   - If there is an extra element in the input labels shaped `[batch_size]`, it is assumed to be weights tensor to be
+//     applied to the losses.
+//   - If there is an extra element in the input labels with booleans shaped `[batch_size]`, it assumed to be a mask
+//     tensor to be applied to the losses.
+//   - The loss is returned per example (shaped `[batch_size]`), and not automatically reduced.
+//
+// See https://en.wikipedia.org/wiki/Quantile_regression
+func MakeMultiQuantileLoss(quantiles []float64, crossingPenalty float64) LossFn {
+	if len(quantiles) == 0 {
+		Panicf("MakeMultiQuantileLoss requires at least one quantile")
+	}
+	for ii, q := range quantiles {
+		if q <= 0 || q >= 1 {
+			Panicf("MakeMultiQuantileLoss quantiles must be in the range (0, 1), got quantiles[%d]=%g", ii, q)
+		}
+		if ii > 0 && q <= quantiles[ii-1] {
+			Panicf("MakeMultiQuantileLoss quantiles must be strictly increasing, got %v", quantiles)
+		}
+	}
+	if crossingPenalty < 0 {
+		Panicf("MakeMultiQuantileLoss requires crossingPenalty >= 0, got %g", crossingPenalty)
+	}
+	numQuantiles := len(quantiles)
+	return func(labels, predictions []*Node) (loss *Node) {
+		predictions0 := predictions[0]
+		g := predictions0.Graph()
+		dtype := predictions0.DType()
+		if predictions0.Rank() != 2 || predictions0.Shape().Dim(-1) != numQuantiles {
+			Panicf("MakeMultiQuantileLoss with %d quantiles requires predictions[0] shaped [batch_size, %d], got %s",
+				numQuantiles, numQuantiles, predictions0.Shape())
+		}
+		batchSize := predictions0.Shape().Dim(0)
+		labels0 := convertLabels("MakeMultiQuantileLoss", labels[0], dtype)
+		if labels0.Shape().Size() != batchSize {
+			Panicf("MakeMultiQuantileLoss requires labels[0] with batch_size=%d elements, got %s", batchSize, labels0.Shape())
+		}
+		labels0 = Reshape(labels0, batchSize, 1)
+		weights, mask := CheckLabelsForWeightsAndMask(shapes.Make(dtype, batchSize), labels)
+
+		// Pinball loss: max(q*(y-p), (q-1)*(y-p)).
+		qs := ExpandDims(Const(g, shapes.CastAsDType(quantiles, dtype)), 0)
+		residuals := Sub(labels0, predictions0)
+		loss = Max(Mul(qs, residuals), Mul(Sub(qs, OnesLike(qs)), residuals))
+		loss = ReduceSum(loss, -1)
+
+		// Crossing penalty.
+		if crossingPenalty > 0 && numQuantiles > 1 {
+			lower := Slice(predictions0, AxisRange(), AxisRange(0, numQuantiles-1))
+			higher := Slice(predictions0, AxisRange(), AxisRange(1))
+			crossings := ReduceSum(MaxScalar(Sub(lower, higher), 0), -1)
+			loss = Add(loss, MulScalar(crossings, crossingPenalty))
+		}
+
+		// Apply weights and mask.
+		loss = ApplyWeightsAndMask(loss, weights, mask)
+		return loss
+	}
+}
@@ -0,0 +1,32 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+)
+
+func TestMultiQuantileLoss(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "MakeMultiQuantileLoss", func(g *Graph) (inputs, outputs []*Node) {
+		quantiles := []float64{0.1, 0.5, 0.9}
+		labels := Const(g, []float32{0, 0, 0})
+		predictions := Const(g, [][]float32{
+			{-1, 0, 1}, // Quantiles in order.
+			{1, 0, -1}, // Crossing quantiles: 2 crossings of size 1.
+			{1, 0, -1}, // Masked out.
+		})
+		mask := Const(g, []bool{true, true, false})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{
+			MakeMultiQuantileLoss(quantiles, 0)([]*Node{labels, mask}, []*Node{predictions}),
+			MakeMultiQuantileLoss(quantiles, 2)([]*Node{labels, mask}, []*Node{predictions}),
+			MakeMultiQuantileLoss(quantiles, 4)([]*Node{labels, mask}, []*Node{predictions}),
+		}
+		return
+	}, []any{
+		[]float32{0.2, 1.8, 0},
+		[]float32{0.2, 1.8 + 2*2, 0}, // Extra loss only for the crossing prediction, proportional to the penalty.
+		[]float32{0.2, 1.8 + 4*2, 0},
+	}, 1e-4)
+}
@@ -0,0 +1,89 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	. "github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/pkg/errors"
+)
+
+// LossBuilder creates a LossFn configured from the context, see Register.
+type LossBuilder func(ctx *context.Context) (LossFn, error)
+
+var (
+	registryMu         sync.RWMutex
+	registeredBuilders = make(map[string]LossBuilder)
+)
+
+// Register a custom loss under the given name, so it can be selected with the ParamLoss hyperparameter, in
+// LossFromContext (including in a comma-separated list of losses).
+//
+// It panics if name is empty, contains a comma, or is already used by a built-in loss (see Type) or by a previously
+// registered loss.
+//
+// Register is safe for concurrent use, but to be safe it should be called during initialization of a package
+// (in an `init()` function), so the loss is available before any context is configured.
+func Register(name string, builder LossBuilder) {
+	if name == "" || strings.Contains(name, ",") {
+		Panicf("losses.Register: invalid loss name %q", name)
+	}
+	if _, err := TypeString(name); err == nil {
+		Panicf("losses.Register: name %q is already used by a built-in loss", name)
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, found := registeredBuilders[name]; found {
+		Panicf("losses.Register: loss %q registered more than once", name)
+	}
+	registeredBuilders[name] = builder
+}
+
+// lossFromName returns the loss function for the given name, either a built-in loss or a registered one.
+func lossFromName(ctx *context.Context, name string) (LossFn, error) {
+	if lossType, err := TypeString(name); err == nil {
+		return lossFromType(ctx, lossType)
+	}
+	registryMu.RLock()
+	builder, found := registeredBuilders[name]
+	registryMu.RUnlock()
+	if !found {
+		return nil, errors.Errorf("unknown loss %q for hyperparameter %q, known losses are: \"%s\"",
+			name, ParamLoss, strings.Join(knownLossNames(), "\", \""))
+	}
+	lossFn, err := builder(ctx)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to build registered loss %q", name)
+	}
+	return lossFn, nil
+}
+
+// knownLossNames returns the names of the built-in losses followed by the registered ones, sorted.
+func knownLossNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registeredBuilders))
+	for name := range registeredBuilders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return append(TypeStrings(), names...)
+}
@@ -0,0 +1,58 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	// Custom loss: mean absolute error scaled by a hyperparameter.
+	Register("test_scaled_mae", func(ctx *context.Context) (LossFn, error) {
+		scale := context.GetParamOr(ctx, "test_scale", 1.0)
+		if scale <= 0 {
+			return nil, errors.Errorf("test_scale must be > 0, got %g", scale)
+		}
+		return MakeScaledLoss(MeanAbsoluteError, scale), nil
+	})
+	require.Panics(t, func() { Register("test_scaled_mae", nil) })
+	require.Panics(t, func() { Register("mse", nil) })
+	require.Panics(t, func() { Register("a,b", nil) })
+
+	ctx := context.New()
+	ctx.SetParams(map[string]any{ParamLoss: "test_scaled_mae", "test_scale": 10.0})
+	lossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+	ctx.SetParam(ParamLoss, "mse,test_scaled_mae")
+	combinedFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+
+	graphtest.RunTestGraphFn(t, "Register", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, []float32{1, 2})
+		predictions := Const(g, []float32{2, 4})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{
+			lossFn([]*Node{labels}, []*Node{predictions}),
+			combinedFn([]*Node{labels}, []*Node{predictions}),
+		}
+		return
+	}, []any{
+		float32(15),
+		// (mse=2.5 + 15) / 2
+		float32(8.75),
+	}, 1e-4)
+
+	// Errors from the builder are returned.
+	ctx.SetParams(map[string]any{ParamLoss: "test_scaled_mae", "test_scale": -1.0})
+	_, err = LossFromContext(ctx)
+	require.ErrorContains(t, err, "test_scale must be > 0")
+
+	// Unknown losses list the registered ones.
+	ctx.SetParam(ParamLoss, "unknown")
+	_, err = LossFromContext(ctx)
+	require.ErrorContains(t, err, "test_scaled_mae")
+}
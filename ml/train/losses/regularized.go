@@ -0,0 +1,65 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/ml/context"
+)
+
+// MakeRegularizedLoss returns a LossFn that adds to the loss returned by inner the L1 and L2 regularization of the
+// trainable variables in the context:
+//
+//	l1 * sum(|w|) + l2 * sum(w²)
+//
+// The variables are the trainable (float) ones in the current scope of ctx (see Context.IterVariablesInScope), at
+// the time the loss is built -- so the model must be built before the loss, as done by train.Trainer. If exclude is
+// not nil, the variables for which exclude(v.ScopeAndName()) returns true are not regularized, e.g.: biases.
+//
+// The regularization is a scalar, added to every element of the (unreduced) loss returned by inner, so its
+// ReduceAllMean is incremented by the regularization.
+//
+// This is a simpler alternative to the per-layer regularizers (package ml/layers/regularizers), with the same
+// coefficients for all variables.
+func MakeRegularizedLoss(inner LossFn, l1, l2 float64, ctx *context.Context, exclude func(scopeAndName string) bool) LossFn {
+	if l1 < 0 || l2 < 0 {
+		Panicf("MakeRegularizedLoss requires l1 >= 0 and l2 >= 0, got l1=%g and l2=%g", l1, l2)
+	}
+	return func(labels, predictions []*Node) *Node {
+		loss := inner(labels, predictions)
+		if l1 == 0 && l2 == 0 {
+			return loss
+		}
+		g := loss.Graph()
+		dtype := loss.DType()
+		regularization := ScalarZero(g, dtype)
+		for v := range ctx.IterVariablesInScope() {
+			if !v.Trainable || !v.Shape().DType.IsFloat() || (exclude != nil && exclude(v.ScopeAndName())) {
+				continue
+			}
+			w := ConvertDType(v.ValueGraph(g), dtype)
+			if l1 != 0 {
+				regularization = Add(regularization, MulScalar(ReduceAllSum(Abs(w)), l1))
+			}
+			if l2 != 0 {
+				regularization = Add(regularization, MulScalar(ReduceAllSum(Square(w)), l2))
+			}
+		}
+		return Add(loss, regularization)
+	}
+}
@@ -0,0 +1,46 @@
+package losses
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeRegularizedLoss(t *testing.T) {
+	require.Panics(t, func() { MakeRegularizedLoss(MeanSquaredError, -1, 0, context.New(), nil) })
+	backend := graphtest.BuildTestBackend()
+	newModelCtx := func(weights, biases []float32) *context.Context {
+		ctx := context.New()
+		ctx.In("model").VariableWithValue("weights", weights)
+		ctx.In("model").VariableWithValue("biases", biases)
+		ctx.VariableWithValue("step", float32(1000)).SetTrainable(false)
+		return ctx
+	}
+	regularization := func(ctx *context.Context, l1, l2 float64, exclude func(string) bool) float32 {
+		lossFn := MakeRegularizedLoss(MeanSquaredError, l1, l2, ctx, exclude)
+		exec := context.NewExec(backend, ctx, func(ctx *context.Context, labels *Node) *Node {
+			// Zero inner loss: predictions equal to the labels.
+			return lossFn([]*Node{labels}, []*Node{labels})
+		})
+		defer exec.Finalize()
+		return exec.Call([]float32{1, 2})[0].Value().(float32)
+	}
+
+	// A zero-weight model has zero regularization.
+	assert.Equal(t, float32(0), regularization(newModelCtx([]float32{0, 0}, []float32{0}), 0.1, 0.01, nil))
+
+	// The regularization scales with the coefficients: sum(|w|) = 6 and sum(w²) = 14.
+	ctx := newModelCtx([]float32{1, -2}, []float32{3})
+	assert.InDelta(t, 0.1*6+0.01*14, regularization(ctx, 0.1, 0.01, nil), 1e-5)
+	assert.InDelta(t, 0.2*6+0.02*14, regularization(ctx, 0.2, 0.02, nil), 1e-5)
+	assert.InDelta(t, 0.01*14, regularization(ctx, 0, 0.01, nil), 1e-5)
+
+	// Excluding the biases.
+	excludeBiases := func(scopeAndName string) bool { return strings.HasSuffix(scopeAndName, "biases") }
+	assert.InDelta(t, 0.1*3+0.01*5, regularization(ctx, 0.1, 0.01, excludeBiases), 1e-5)
+}
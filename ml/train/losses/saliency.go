@@ -0,0 +1,75 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	"github.com/gomlx/gomlx/backends"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/ml/context"
+)
+
+// SaliencyModelFn is the model graph function used by SaliencyGraph and NewSaliencyExec: it takes the model inputs
+// and returns its predictions (or logits), as train.ModelFn, but without the dataset spec.
+type SaliencyModelFn func(ctx *context.Context, inputs []*Node) (predictions []*Node)
+
+// SaliencyGraph builds the loss of the model on the given inputs and labels, and its gradient w.r.t. each of the
+// inputs (the saliency maps), using the graph package's automatic differentiation (see graph.Gradient).
+//
+// The model is built in inference mode (see context.Context.SetTraining). If lossFn returns the per-example losses,
+// they are reduced with ReduceAllMean, as train.Trainer does, so the returned loss is always a scalar.
+//
+// All the inputs must be floating point, and the returned inputGrads have the same shapes as the inputs.
+func SaliencyGraph(ctx *context.Context, modelFn SaliencyModelFn, lossFn LossFn, inputs, labels []*Node) (
+	loss *Node, inputGrads []*Node) {
+	if len(inputs) == 0 {
+		Panicf("SaliencyGraph requires at least one input")
+	}
+	for ii, input := range inputs {
+		if !input.DType().IsFloat() {
+			Panicf("SaliencyGraph requires floating point inputs to take the gradient, but inputs[%d] is %s",
+				ii, input.Shape())
+		}
+	}
+	ctx.SetTraining(inputs[0].Graph(), false)
+	predictions := modelFn(ctx, inputs)
+	loss = lossFn(labels, predictions)
+	if !loss.Shape().IsScalar() {
+		loss = ReduceAllMean(loss)
+	}
+	inputGrads = Gradient(loss, inputs...)
+	return
+}
+
+// NewSaliencyExec returns an executor of SaliencyGraph: it takes numInputs model inputs followed by the labels, and
+// returns the scalar loss followed by its gradient w.r.t. each of the inputs.
+//
+// The model variables are taken from ctx, usually the context of a trained model.
+func NewSaliencyExec(backend backends.Backend, ctx *context.Context, numInputs int, modelFn SaliencyModelFn,
+	lossFn LossFn) *context.Exec {
+	if numInputs <= 0 {
+		Panicf("NewSaliencyExec requires numInputs > 0, got %d", numInputs)
+	}
+	return context.NewExec(backend, ctx, func(ctx *context.Context, inputsAndLabels []*Node) []*Node {
+		if len(inputsAndLabels) < numInputs {
+			Panicf("NewSaliencyExec expected %d inputs followed by the labels, got only %d values",
+				numInputs, len(inputsAndLabels))
+		}
+		loss, inputGrads := SaliencyGraph(ctx, modelFn, lossFn, inputsAndLabels[:numInputs], inputsAndLabels[numInputs:])
+		return append([]*Node{loss}, inputGrads...)
+	}).WithName("Saliency")
+}
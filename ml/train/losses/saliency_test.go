@@ -0,0 +1,44 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSaliencyExec(t *testing.T) {
+	backend := graphtest.BuildTestBackend()
+	ctx := context.New()
+	// Linear model: y = x·w + b.
+	w := []float32{2, -1, 0.5}
+	b := float32(1)
+	ctx.VariableWithValue("w", w)
+	ctx.VariableWithValue("b", b)
+	linearModel := func(ctx *context.Context, inputs []*Node) []*Node {
+		g := inputs[0].Graph()
+		wNode := ctx.GetVariable("w").ValueGraph(g)
+		bNode := ctx.GetVariable("b").ValueGraph(g)
+		y := Add(InsertAxes(ReduceSum(Mul(inputs[0], wNode), -1), -1), bNode)
+		return []*Node{y}
+	}
+	exec := NewSaliencyExec(backend, ctx, 1, linearModel, MeanSquaredError)
+	defer exec.Finalize()
+
+	x := [][]float32{{1, 2, 3}, {0, 1, -1}}
+	labels := [][]float32{{1}, {0.5}}
+	outputs := exec.Call(x, labels)
+
+	// Predictions are 2.5 and -0.5, so the residuals are r = {1.5, -1}, and the MSE is (1.5^2+1^2)/2.
+	assert.InDelta(t, 1.625, outputs[0].Value().(float32), 1e-5)
+	// d(MSE)/dx_i = 2*r_i/numExamples * w = r_i * w.
+	residuals := []float32{1.5, -1}
+	grads := outputs[1].Value().([][]float32)
+	for ii, r := range residuals {
+		for jj, wj := range w {
+			assert.InDelta(t, r*wj, grads[ii][jj], 1e-5, "gradient of example %d, feature %d", ii, jj)
+		}
+	}
+}
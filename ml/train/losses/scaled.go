@@ -0,0 +1,119 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gomlx/ml/train/optimizers"
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+const (
+	// LossScalingScope is the scope used by MakeDynamicScaledLoss to store its variables.
+	LossScalingScope = "loss_scaling"
+
+	// LossScaleVariableName is the name of the variable, under LossScalingScope, holding the current dynamic
+	// loss scale.
+	LossScaleVariableName = "scale"
+
+	// LossScaleFiniteStepsVariableName is the name of the variable, under LossScalingScope, counting the
+	// consecutive training steps without overflow, used to grow the dynamic loss scale.
+	LossScaleFiniteStepsVariableName = "finite_steps"
+)
+
+// MakeScaledLoss returns a LossFn that multiplies the (unreduced) loss returned by inner by the fixed factor scale.
+//
+// This is the standard remedy for gradient underflow when training in reduced precision (float16/bfloat16): with
+// the loss multiplied by scale, the gradients are also multiplied by scale, keeping small values representable.
+//
+// Notice the gradients reaching the optimizer are scaled as well, so the optimizer must undo it: either divide
+// the gradients by scale before applying them or, for optimizers linear on the gradients (plain SGD),
+// divide the learning rate by scale. Adaptive optimizers like Adam are (mostly) invariant to the scale of the
+// gradients, except for their epsilon term.
+func MakeScaledLoss(inner LossFn, scale float64) LossFn {
+	return func(labels, predictions []*Node) (loss *Node) {
+		loss = inner(labels, predictions)
+		return MulScalar(loss, scale)
+	}
+}
+
+// MakeDynamicScaledLoss is like MakeScaledLoss, but the scale is stored in the context as a non-trainable
+// variable (see LossScalingScope and LossScaleVariableName), initialized to initialScale, and adjusted during
+// training:
+//
+//   - If the scaled loss or its gradients with respect to the trainable variables contain any inf or NaN value
+//     (an overflow), the scale is halved (down to a minimum of 1) for the following steps, and the update of the
+//     step is skipped.
+//   - After growthInterval consecutive steps without overflow, the scale is doubled. If growthInterval <= 0
+//     the scale never grows.
+//
+// The gradients are checked with a hook (see optimizers.AddGradientsHook), on the gradients already computed by
+// the optimizer, which is also asked to skip the update on overflows. With optimizers that don't call the hooks
+// (see optimizers.CallGradientsHooks) only the loss is checked for overflows, and updates are never skipped.
+//
+// The scale is only adjusted when the context is in training mode (see context.Context.IsTraining), so
+// evaluations don't affect it. Use GetLossScaleVar to read the current scale, which the optimizer must use to
+// unscale the gradients, see MakeScaledLoss.
+func MakeDynamicScaledLoss(ctx *context.Context, inner LossFn, initialScale float64, growthInterval int) LossFn {
+	return func(labels, predictions []*Node) (loss *Node) {
+		loss = inner(labels, predictions)
+		g := loss.Graph()
+		scaleVar := GetLossScaleVar(ctx, initialScale)
+		scale := scaleVar.ValueGraph(g)
+		loss = Mul(loss, ConvertDType(scale, loss.DType()))
+		if !ctx.IsTraining(g) {
+			return loss
+		}
+
+		// updateScale sets the scale and the count of consecutive finite steps, given whether the step overflowed.
+		stepsVar := ctx.In(LossScalingScope).Checked(false).
+			VariableWithValue(LossScaleFiniteStepsVariableName, int64(0)).SetTrainable(false)
+		steps := stepsVar.ValueGraph(g)
+		updateScale := func(isFinite *Node) {
+			newScale := Where(isFinite, scale, MaxScalar(DivScalar(scale, 2), 1))
+			newSteps := Where(isFinite, AddScalar(steps, 1), ZerosLike(steps))
+			if growthInterval > 0 {
+				grow := GreaterOrEqual(newSteps, Scalar(g, dtypes.Int64, growthInterval))
+				newScale = Where(grow, MulScalar(scale, 2), newScale)
+				newSteps = Where(grow, ZerosLike(steps), newSteps)
+			}
+			scaleVar.SetValueGraph(newScale)
+			stepsVar.SetValueGraph(newSteps)
+		}
+
+		// The loss is checked here, and the update is replaced by the hook, if the optimizer calls it.
+		lossIsFinite := LogicalAll(IsFinite(loss))
+		updateScale(lossIsFinite)
+		optimizers.AddGradientsHook(ctx, g, func(g *Graph, grads []*Node) (skipUpdate *Node) {
+			isFinite := lossIsFinite
+			for _, grad := range grads {
+				isFinite = LogicalAnd(isFinite, LogicalAll(IsFinite(grad)))
+			}
+			updateScale(isFinite)
+			return LogicalNot(isFinite)
+		})
+		return loss
+	}
+}
+
+// GetLossScaleVar returns the variable holding the dynamic loss scale used by MakeDynamicScaledLoss, creating it
+// with initialScale if it doesn't exist yet.
+func GetLossScaleVar(ctx *context.Context, initialScale float64) *context.Variable {
+	return ctx.In(LossScalingScope).Checked(false).
+		VariableWithValue(LossScaleVariableName, float32(initialScale)).SetTrainable(false)
+}
@@ -0,0 +1,89 @@
+package losses
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gomlx/ml/train/optimizers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/gomlx/gomlx/graph"
+)
+
+func TestMakeScaledLoss(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "MakeScaledLoss", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, []float32{1, 2, 3, -1})
+		predictions := Const(g, []float32{1.5, 0, 3, 0.25})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{
+			MakeScaledLoss(MeanSquaredError, 1024)([]*Node{labels}, []*Node{predictions}),
+			MakeScaledLoss(MeanAbsoluteError, 0.5)([]*Node{labels}, []*Node{predictions}),
+		}
+		return
+	}, []any{
+		// Powers of 2 scale the loss exactly: mean squared error is 1.453125, mean absolute error is 0.9375.
+		float32(1.453125 * 1024),
+		float32(0.9375 * 0.5),
+	}, 0)
+}
+
+func TestMakeDynamicScaledLoss(t *testing.T) {
+	backend := graphtest.BuildTestBackend()
+	ctx := context.New()
+	wVar := ctx.VariableWithValue("w", float32(1))
+	// Sqrt of the absolute error: it has a finite value but non-finite gradients when predictions match labels.
+	inner := func(labels, predictions []*Node) *Node {
+		return Sqrt(Abs(Sub(predictions[0], labels[0])))
+	}
+	lossFn := MakeDynamicScaledLoss(ctx, inner, 1024, 2)
+	newExec := func(training bool) *context.Exec {
+		return context.NewExec(backend, ctx, func(ctx *context.Context, labels, x *Node) *Node {
+			g := x.Graph()
+			ctx.SetTraining(g, training)
+			loss := lossFn([]*Node{labels}, []*Node{Mul(x, wVar.ValueGraph(g))})
+			if training {
+				optimizers.StochasticGradientDescent().UpdateGraph(ctx, g, ReduceAllMean(loss))
+			}
+			return loss
+		})
+	}
+	trainExec, evalExec := newExec(true), newExec(false)
+	defer trainExec.Finalize()
+	defer evalExec.Finalize()
+	scale := func() float32 { return GetLossScaleVar(ctx, 0).Value().Value().(float32) }
+	w := func() float32 { return wVar.Value().Value().(float32) }
+
+	// Finite steps: scale is preserved, the update is applied, and the scale doubles after growthInterval=2 steps.
+	loss := trainExec.Call([]float32{1, 2}, []float32{5, 6})[0]
+	assert.Equal(t, []float32{2 * 1024, 2 * 1024}, loss.Value())
+	require.Equal(t, float32(1024), scale())
+	require.NotEqual(t, float32(1), w())
+	trainExec.Call([]float32{1, 2}, []float32{5, 6})
+	require.Equal(t, float32(2048), scale())
+
+	// Evaluation doesn't change the scale. Labels match the predictions: the loss is 0.
+	labels := []float32{w(), 2 * w()}
+	loss = evalExec.Call(labels, []float32{1, 2})[0]
+	assert.Equal(t, []float32{0, 0}, loss.Value())
+	require.Equal(t, float32(2048), scale())
+
+	// Finite loss with non-finite gradients: scale is halved, and the update is skipped.
+	wBefore := w()
+	trainExec.Call(labels, []float32{1, 2})
+	require.Equal(t, float32(1024), scale())
+	require.Equal(t, wBefore, w())
+
+	// Non-finite loss: scale is halved, and the update is skipped.
+	trainExec.Call([]float32{float32(math.Inf(1)), 2}, []float32{1, 2})
+	require.Equal(t, float32(512), scale())
+	require.Equal(t, wBefore, w())
+
+	// The count of finite steps restarts after an overflow.
+	trainExec.Call([]float32{1, 2}, []float32{5, 6})
+	require.Equal(t, float32(512), scale())
+	trainExec.Call([]float32{1, 2}, []float32{5, 6})
+	require.Equal(t, float32(1024), scale())
+}
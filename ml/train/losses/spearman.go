@@ -0,0 +1,100 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+// pairwiseDiffs returns diffs[..., i, j] = x[..., i] - x[..., j], for x shaped `[..., n]`.
+func pairwiseDiffs(x *Node) *Node {
+	n := x.Shape().Dim(-1)
+	dims := append(x.Shape().Clone().Dimensions, n)
+	rows := BroadcastToDims(InsertAxes(x, -1), dims...)
+	cols := BroadcastToDims(InsertAxes(x, -2), dims...)
+	return Sub(rows, cols)
+}
+
+// MakeSoftSpearmanLoss returns a listwise learning-to-rank loss that computes `1 - ρ`, where ρ is a differentiable
+// approximation of the Spearman rank correlation between the predicted scores and the target relevances.
+//
+// labels[0] are the relevances and predictions[0] the scores, both shaped `[..., listSize]`: the last axis is the
+// list to rank, and the returned losses are shaped `[...]` (one per list). The loss ranges from 0 (same order)
+// to 2 (reversed order).
+//
+// The Spearman correlation is the Pearson correlation of the ranks. The (hard) ranks of the relevances are
+// computed by pairwise comparison, with ties getting the average rank. The ranks of the scores are smoothed by
+// replacing the comparisons with a sigmoid of the difference of the scores (temperature 1), so the loss has a
+// gradient: the scores of perfectly ordered lists must be well separated (compared to 1) for the loss to approach 0.
+//
+// The only extra label accepted is an optional mask shaped as predictions[0], to mask out padding positions of
+// the lists: they don't take part in the ranking nor in the correlation. Lists with fewer than 2 valid positions
+// have a loss of 0. Weights are not accepted (it panics if given), since the correlation of a list is not a sum
+// over its positions: to weight the lists, multiply the returned losses instead.
+//
+// The ranks are computed by pairwise comparison, so it takes O(listSize^2) memory.
+func MakeSoftSpearmanLoss() LossFn {
+	return func(labels, predictions []*Node) *Node {
+		scores := predictions[0]
+		g := scores.Graph()
+		dtype := scores.DType()
+		relevances := convertLabels("MakeSoftSpearmanLoss", labels[0], dtype)
+		if !relevances.Shape().Equal(scores.Shape()) {
+			Panicf("labels[0] (%s) and predictions[0] (%s) must have same shape", relevances.Shape(), scores.Shape())
+		}
+		if scores.Rank() == 0 {
+			Panicf("MakeSoftSpearmanLoss requires scores shaped [..., listSize], got a scalar")
+		}
+		weights, mask := CheckLabelsForWeightsAndMask(scores.Shape(), labels)
+		if weights != nil {
+			Panicf("MakeSoftSpearmanLoss doesn't accept weights, only a boolean mask shaped %s as an extra label",
+				shapes.Make(dtypes.Bool, scores.Shape().Dimensions...))
+		}
+		if mask == nil {
+			mask = OnesLike(ConvertDType(scores, dtypes.Bool))
+		}
+		maskF := ConvertDType(mask, dtype)
+		scoreDiffs := pairwiseDiffs(scores)
+		// pairMask[..., i, j] = mask[..., j]: only valid positions are counted when ranking.
+		pairMask := BroadcastToDims(InsertAxes(maskF, -2), scoreDiffs.Shape().Dimensions...)
+
+		// Soft ranks of the scores and hard (average for ties) ranks of the relevances.
+		softRanks := ReduceSum(Mul(Sigmoid(scoreDiffs), pairMask), -1)
+		relevanceDiffs := pairwiseDiffs(StopGradient(relevances))
+		zeros := ZerosLike(relevanceDiffs)
+		steps := Where(GreaterThan(relevanceDiffs, zeros), OnesLike(relevanceDiffs),
+			Where(Equal(relevanceDiffs, zeros), Scalar(g, dtype, 0.5), zeros))
+		hardRanks := ReduceSum(Mul(steps, pairMask), -1)
+
+		// Pearson correlation over the valid positions.
+		count := ReduceSum(maskF, -1)
+		centered := func(x *Node) *Node {
+			mean := Div(ReduceSum(Mul(x, maskF), -1), Max(count, OnesLike(count)))
+			return Mul(Sub(x, InsertAxes(mean, -1)), maskF)
+		}
+		softCentered, hardCentered := centered(softRanks), centered(hardRanks)
+		covariance := ReduceSum(Mul(softCentered, hardCentered), -1)
+		variances := Mul(ReduceSum(Square(softCentered), -1), ReduceSum(Square(hardCentered), -1))
+		epsilon := epsilonForDType(g, dtype)
+		correlation := Div(covariance, Sqrt(Add(variances, epsilon)))
+		loss := OneMinus(correlation)
+		return Where(GreaterOrEqual(count, Scalar(g, dtype, 2)), loss, ZerosLike(loss))
+	}
+}
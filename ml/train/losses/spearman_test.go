@@ -0,0 +1,56 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeSoftSpearmanLoss(t *testing.T) {
+	ctx := context.New()
+	ctx.SetParam(ParamLoss, TypeSoftSpearman.String())
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+
+	graphtest.RunTestGraphFn(t, "MakeSoftSpearmanLoss", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, [][]float32{{1, 2, 3, 4}, {1, 2, 3, 4}, {1, 2, 3, 4}, {1, 2, 3, 9}, {0, 0, 0, 1}, {0, 0, 0, 1}})
+		predictions := Const(g, [][]float32{
+			{0, 10, 20, 30}, // Perfectly ordered.
+			{30, 20, 10, 0}, // Reversed.
+			{0, 1, 2, 3},    // Ordered, but not well separated scores.
+			{0, 10, 20, -5}, // Ordered, except for the padding.
+			{1, 0, 2, 3},    // Ties in the relevances.
+			{1, 0, 2, 3},    // Only one valid position.
+		})
+		mask := Const(g, [][]bool{
+			{true, true, true, true},
+			{true, true, true, true},
+			{true, true, true, true},
+			{true, true, true, false},
+			{true, true, true, true},
+			{true, false, false, false},
+		})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{
+			MakeSoftSpearmanLoss()([]*Node{labels, mask}, []*Node{predictions}),
+			contextLossFn([]*Node{labels}, []*Node{predictions}),
+		}
+		return
+	}, []any{
+		[]float32{0, 2, 0.000238, 0, 0.231218, 0},
+		// Without the mask: the padding position is ranked first, and the single valid position list is a regular list.
+		[]float32{0, 2, 0.000238, 1.196267, 0.231218, 0.231218},
+	}, 1e-3)
+
+	// Weights are not accepted.
+	backend := graphtest.BuildTestBackend()
+	g := NewGraph(backend, "MakeSoftSpearmanLoss weights")
+	defer g.Finalize()
+	labels := Const(g, [][]float32{{1, 2, 3}})
+	require.Panics(t, func() {
+		MakeSoftSpearmanLoss()([]*Node{labels, Const(g, [][]float32{{1, 1, 2}})}, []*Node{labels})
+	})
+}
@@ -0,0 +1,156 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/types/shapes"
+)
+
+// This file holds variants of the losses that return the summed loss and the number of elements (the count)
+// separately, instead of the mean.
+//
+// They are useful for gradient accumulation across micro-batches: the accumulated sum divided by the accumulated
+// count gives the exact mean over the full batch -- as opposed to averaging the averages of each micro-batch,
+// which is wrong when the micro-batches have different number of valid (not masked) elements.
+
+// sumAndCount returns the sum of the losses, and the number of elements not masked out, both scalars with
+// the losses dtype. mask can be nil, in which case all elements are counted.
+func sumAndCount(losses, mask *Node) (sum, count *Node) {
+	g := losses.Graph()
+	dtype := losses.DType()
+	sum = ReduceAllSum(losses)
+	if mask == nil {
+		count = Scalar(g, dtype, float64(losses.Shape().Size()))
+	} else {
+		count = ReduceAllSum(ConvertDType(mask, dtype))
+	}
+	return
+}
+
+// MeanSquaredErrorSumCount returns the sum of the squared errors between labels and predictions, and the
+// count of elements used, excluding the masked out ones.
+//
+// Optional weights and mask are taken from the extra labels, as in MeanSquaredError.
+// Notice that sum/count is the mean over the non-masked elements only.
+func MeanSquaredErrorSumCount(labels, predictions []*Node) (sum, count *Node) {
+	return sumAndCount(squaredErrors(labels, predictions))
+}
+
+// MeanAbsoluteErrorSumCount returns the sum of the absolute errors between labels and predictions, and the
+// count of elements used, excluding the masked out ones.
+//
+// Optional weights and mask are taken from the extra labels, as in MeanAbsoluteError.
+// Notice that sum/count is the mean over the non-masked elements only.
+func MeanAbsoluteErrorSumCount(labels, predictions []*Node) (sum, count *Node) {
+	return sumAndCount(absoluteErrors(labels, predictions))
+}
+
+// BinaryCrossentropyLogitsSumCount returns the sum of the BinaryCrossentropyLogits losses, and the
+// count of elements used, excluding the masked out ones.
+//
+// Optional weights and mask are taken from the extra labels, as in BinaryCrossentropyLogits.
+func BinaryCrossentropyLogitsSumCount(labels, logits []*Node) (sum, count *Node) {
+	_, mask := CheckLabelsForWeightsAndMask(logits[0].Shape(), labels)
+	return sumAndCount(BinaryCrossentropyLogits(labels, logits), mask)
+}
+
+// CategoricalCrossEntropyLogitsSumCount returns the sum of the CategoricalCrossEntropyLogits losses, and the
+// count of examples used, excluding the masked out ones.
+//
+// Optional weights and mask are taken from the extra labels, as in CategoricalCrossEntropyLogits.
+func CategoricalCrossEntropyLogitsSumCount(labels, logits []*Node) (sum, count *Node) {
+	logits0 := logits[0]
+	labels0 := labels[0]
+	weightsShape := shapes.Make(logits0.DType(), labels0.Shape().Dimensions[:labels0.Rank()-1]...)
+	weights, mask := CheckLabelsForWeightsAndMask(weightsShape, labels)
+	return sumAndCount(categoricalCrossEntropyLogitsImpl(labels0, logits0, weights, mask), mask)
+}
+
+// SparseCategoricalCrossEntropyLogitsSumCount returns the sum of the SparseCategoricalCrossEntropyLogits losses,
+// and the count of examples used, excluding the masked out ones.
+//
+// Optional weights and mask are taken from the extra labels, as in SparseCategoricalCrossEntropyLogits.
+func SparseCategoricalCrossEntropyLogitsSumCount(labels, logits []*Node) (sum, count *Node) {
+	_, _, _, mask := checkSparseLabels(labels, logits)
+	return sumAndCount(SparseCategoricalCrossEntropyLogits(labels, logits), mask)
+}
+
+// effectiveWeightSum returns the sum of the weights effectively applied to the losses, that is, with the masked out
+// elements zeroed, as in ApplyWeightsAndMask. If weights is nil, each element has weight 1 and it's the same as the
+// count returned by sumAndCount.
+func effectiveWeightSum(losses, weights, mask *Node) *Node {
+	if weights == nil {
+		_, count := sumAndCount(losses, mask)
+		return count
+	}
+	weights = ConvertDType(weights, losses.DType())
+	if mask != nil {
+		weights = Where(mask, weights, ZerosLike(weights))
+	}
+	if !weights.Shape().Equal(losses.Shape()) {
+		weights = BroadcastToShape(weights, losses.Shape())
+	}
+	return ReduceAllSum(weights)
+}
+
+// MeanAbsoluteErrorWeightedSum returns the weighted sum of the absolute errors between labels and predictions, and
+// the sum of the weights effectively applied -- with the weights of masked out elements zeroed.
+//
+// Optional weights and mask are taken from the extra labels, as in MeanAbsoluteError.
+// weightedLoss/weightSum is the weighted mean over the non-masked elements.
+func MeanAbsoluteErrorWeightedSum(labels, predictions []*Node) (weightedLoss, weightSum *Node) {
+	losses, _ := absoluteErrors(labels, predictions)
+	weights, mask := CheckLabelsForWeightsAndMask(errorsShape(labels[0]), labels)
+	return ReduceAllSum(losses), effectiveWeightSum(losses, weights, mask)
+}
+
+// MeanSquaredErrorWeightedSum returns the weighted sum of the squared errors between labels and predictions, and
+// the sum of the weights effectively applied -- with the weights of masked out elements zeroed.
+//
+// Optional weights and mask are taken from the extra labels, as in MeanSquaredError.
+// weightedLoss/weightSum is the weighted mean over the non-masked elements.
+func MeanSquaredErrorWeightedSum(labels, predictions []*Node) (weightedLoss, weightSum *Node) {
+	losses, _ := squaredErrors(labels, predictions)
+	weights, mask := CheckLabelsForWeightsAndMask(errorsShape(labels[0]), labels)
+	return ReduceAllSum(losses), effectiveWeightSum(losses, weights, mask)
+}
+
+// MakeWeightedMeanLoss returns a LossFn that reduces the per-example losses returned by inner to their weighted
+// mean, `sum(weighted_loss)/sum(weights)`, a scalar, taking the weights and mask from the extra labels (as in
+// CheckLabelsForWeightsAndMask), with the masked out examples excluded from the denominator.
+//
+// train.Trainer reduces non-scalar losses with ReduceAllMean, which divides the weighted losses by the number of
+// examples, not by the sum of the weights. Since this returns a scalar, the trainer's ReduceAllMean is a no-op,
+// and the loss optimized is the proper weighted mean.
+//
+// inner must return the unreduced losses (e.g.: BinaryCrossentropyLogits), with the weights and mask already
+// applied, and the weights must have the same shape as the returned losses. It panics if inner returns a scalar.
+func MakeWeightedMeanLoss(inner LossFn) LossFn {
+	return func(labels, predictions []*Node) (loss *Node) {
+		losses := inner(labels, predictions)
+		if losses.Shape().IsScalar() {
+			Panicf("MakeWeightedMeanLoss requires the inner loss to return unreduced losses, but it returned a scalar")
+		}
+		weights, mask := CheckLabelsForWeightsAndMask(losses.Shape(), labels)
+		sumWeights := effectiveWeightSum(losses, weights, mask)
+		// If all weights are zero (or all examples masked), the sum of the losses is also zero, and the result is 0.
+		sumWeights = Max(sumWeights, epsilonForDType(losses.Graph(), losses.DType()))
+		return Div(ReduceAllSum(losses), sumWeights)
+	}
+}
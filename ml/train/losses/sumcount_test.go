@@ -0,0 +1,93 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+)
+
+func TestSumCount(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "MeanSquaredErrorSumCount", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, []float32{1, 2, 3, 4, 5})
+		predictions := Const(g, []float32{2, 2, 5, 4, 0})
+		mask := Const(g, []bool{true, true, true, true, false})
+		inputs = []*Node{labels, predictions}
+
+		// Full batch.
+		fullSum, fullCount := MeanSquaredErrorSumCount([]*Node{labels, mask}, []*Node{predictions})
+
+		// Accumulated over 2 micro-batches of different sizes.
+		sum0, count0 := MeanSquaredErrorSumCount(
+			[]*Node{Slice(labels, AxisRange(0, 2)), Slice(mask, AxisRange(0, 2))},
+			[]*Node{Slice(predictions, AxisRange(0, 2))})
+		sum1, count1 := MeanSquaredErrorSumCount(
+			[]*Node{Slice(labels, AxisRange(2)), Slice(mask, AxisRange(2))},
+			[]*Node{Slice(predictions, AxisRange(2))})
+		accumulatedMean := Div(Add(sum0, sum1), Add(count0, count1))
+
+		// MAE and cross-entropy variants.
+		maeSum, maeCount := MeanAbsoluteErrorSumCount([]*Node{labels, mask}, []*Node{predictions})
+		ceLabels := Const(g, [][]int32{{0}, {1}, {1}})
+		ceLogits := Const(g, [][]float32{{0, 0}, {0, 0}, {0, 0}})
+		ceMask := Const(g, []bool{true, false, true})
+		ceSum, ceCount := SparseCategoricalCrossEntropyLogitsSumCount([]*Node{ceLabels, ceMask}, []*Node{ceLogits})
+		outputs = []*Node{fullSum, fullCount, accumulatedMean, Div(fullSum, fullCount), maeSum, maeCount, ceSum, ceCount}
+		return
+	}, []any{
+		float32(1 + 4),         // sum
+		float32(4),             // count, excluding the masked element.
+		float32((1 + 4) / 4.0), // accumulated mean == full-batch mean.
+		float32((1 + 4) / 4.0),
+		float32(1 + 2),
+		float32(4),
+		float32(2 * 0.6931472),
+		float32(2),
+	}, 1e-4)
+}
+
+func TestWeightedSum(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "MeanAbsoluteErrorWeightedSum", func(g *Graph) (inputs, outputs []*Node) {
+		labels := Const(g, []float32{1, 2, 3, 4, 5})
+		predictions := Const(g, []float32{2, 2, 5, 4, 0})
+		weights := Const(g, []float32{2, 1, 0.5, 1, 3})
+		mask := Const(g, []bool{true, true, true, true, false})
+		inputs = []*Node{labels, predictions}
+		maeLoss, maeWeightSum := MeanAbsoluteErrorWeightedSum([]*Node{labels, weights, mask}, []*Node{predictions})
+		mseLoss, mseWeightSum := MeanSquaredErrorWeightedSum([]*Node{labels, weights, mask}, []*Node{predictions})
+		_, maskOnlyWeightSum := MeanAbsoluteErrorWeightedSum([]*Node{labels, mask}, []*Node{predictions})
+		outputs = []*Node{maeLoss, maeWeightSum, Div(maeLoss, maeWeightSum), mseLoss, mseWeightSum, maskOnlyWeightSum}
+		return
+	}, []any{
+		float32(2*1 + 0.5*2), // Weighted sum, the masked element (weight 3) is excluded.
+		float32(2 + 1 + 0.5 + 1),
+		float32(3.0 / 4.5),
+		float32(2*1 + 0.5*4),
+		float32(4.5),
+		float32(4), // Without weights, it's the count of non-masked elements.
+	}, 1e-4)
+}
+
+func TestMakeWeightedMeanLoss(t *testing.T) {
+	graphtest.RunTestGraphFn(t, "MakeWeightedMeanLoss", func(g *Graph) (inputs, outputs []*Node) {
+		logits := Const(g, [][]float32{{0, 0}, {0, 0}, {0, 0}, {0, 0}})
+		labels := Const(g, [][]int32{{0}, {1}, {1}, {0}})
+		weights := Const(g, []float32{1, 3, 0.5, 2})
+		mask := Const(g, []bool{true, true, true, false})
+		inputs = []*Node{labels, logits}
+		lossFn := MakeWeightedMeanLoss(SparseCategoricalCrossEntropyLogits)
+		// Different losses per example: the 3rd label is for a logit shifted by ln(3).
+		logits = Add(logits, Const(g, [][]float32{{0, 0}, {0, 0}, {0, 1.0986123}, {0, 0}}))
+		outputs = []*Node{
+			lossFn([]*Node{labels, weights}, []*Node{logits}),
+			lossFn([]*Node{labels, weights, mask}, []*Node{logits}),
+			lossFn([]*Node{labels}, []*Node{logits}),
+		}
+		return
+	}, []any{
+		// Losses are ln(2), ln(2), ln(4/3), ln(2).
+		float32((1*0.6931472 + 3*0.6931472 + 0.5*0.2876821 + 2*0.6931472) / 6.5),
+		float32((1*0.6931472 + 3*0.6931472 + 0.5*0.2876821) / 4.5),
+		float32((3*0.6931472 + 0.2876821) / 4),
+	}, 1e-4)
+}
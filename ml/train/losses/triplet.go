@@ -17,8 +17,13 @@
 package losses
 
 import (
+	"math"
+
+	. "github.com/gomlx/exceptions"
 	. "github.com/gomlx/gomlx/graph"
 	"github.com/gomlx/gomlx/ml/context"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gopjrt/dtypes"
 )
 
 //go:generate enumer -type=PairwiseDistanceMetric -trimprefix=PairwiseDistanceMetric -transform=snake -values -text -json -yaml triplet.go
@@ -152,6 +157,14 @@ var (
 	//
 	// See MakeTripletLossFromContext.
 	ParamTripletLossMargin = "triplet_loss_margin"
+
+	// ParamPairSampleFraction is the name of the hyperparameter that defines the fraction (in (0, 1]) of the pairwise
+	// distances of the batch computed by the TripletLoss, see SampledTripletLoss.
+	//
+	// It defaults to 1.0, which computes all the pairwise distances with TripletLoss.
+	//
+	// See MakeTripletLossFromContext.
+	ParamPairSampleFraction = "pair_sample_fraction"
 )
 
 // TripletLoss Computes the triplet loss for valid triplet with different mining strategies for positives and negatives over a batch of embeddings.
@@ -182,8 +195,6 @@ func TripletLoss(labels, predictions []*Node,
 
 	g := predictions0.Graph()
 	batchSize := predictions0.Shape().Dim(0)
-	dtype := predictions0.DType()
-	zero := ScalarZero(g, dtype)
 
 	distances := pairwiseDistances(predictions0, metric)
 	distances.AssertDims(batchSize, batchSize)
@@ -198,6 +209,16 @@ func TripletLoss(labels, predictions []*Node,
 
 	positiveMask.AssertDims(batchSize, batchSize)
 	negativeMask.AssertDims(batchSize, batchSize)
+	return tripletLossFromDistances(distances, positiveMask, negativeMask, weights, mask, miningStrategy, margin)
+}
+
+// tripletLossFromDistances implements TripletLoss and SampledTripletLoss, given the distances of each anchor (the
+// examples of the batch) to its candidates (the positives and negatives), and the masks of the candidates that are
+// positive and negative, all shaped `[batch_size, num_candidates]`.
+func tripletLossFromDistances(distances, positiveMask, negativeMask, weights, mask *Node,
+	miningStrategy TripletMiningStrategy, margin float64) *Node {
+	batchSize := distances.Shape().Dim(0)
+	numCandidates := distances.Shape().Dim(1)
 
 	// Computes Positive and Negative distances with respect to mining strategy
 	var positiveDistances *Node
@@ -215,9 +236,9 @@ func TripletLoss(labels, predictions []*Node,
 		// and example k has a different label, we say that (i,j,k) is a valid triplet
 		validTriplets = LogicalAnd(InsertAxes(positiveMask, 2), InsertAxes(negativeMask, 1))
 
-		positiveDistances.AssertDims(batchSize, batchSize, 1)
-		negativeDistances.AssertDims(batchSize, 1, batchSize)
-		validTriplets.AssertDims(batchSize, batchSize, batchSize)
+		positiveDistances.AssertDims(batchSize, numCandidates, 1)
+		negativeDistances.AssertDims(batchSize, 1, numCandidates)
+		validTriplets.AssertDims(batchSize, numCandidates, numCandidates)
 
 	case TripletMiningStrategyHard:
 		// triplets where the negative is closer to the anchor than the positive, i.e. d(a,n)<d(a,p)
@@ -251,7 +272,7 @@ func TripletLoss(labels, predictions []*Node,
 		negativeEasyDistances := maskedMaximums(distances, negativeMask, 1)
 
 		// keep negative label that is greater than the maximal positive distance, otherwise use with the maximal negative distance
-		negativeDistances = Where(greaterDistances, distances, BroadcastToDims(negativeEasyDistances, batchSize, batchSize))
+		negativeDistances = Where(greaterDistances, distances, BroadcastToDims(negativeEasyDistances, batchSize, numCandidates))
 
 		// find the  minimal distance between negative labels above threshold
 		negativeDistances = maskedMinimums(negativeDistances, negativeMask, 1)
@@ -276,23 +297,123 @@ func TripletLoss(labels, predictions []*Node,
 	}
 
 	// Apply weights and mask.
-	if weights != nil {
-		loss = Mul(loss, weights)
-	}
-	if mask != nil {
-		loss = Where(mask, loss, zero)
-	}
+	loss = ApplyWeightsAndMask(loss, weights, mask)
 
 	return MaskedReduceAllMean(loss, validTriplets)
 }
 
 // MakeTripletLossFromContext calls TripletLoss using the configured by the hyperparameter
 // in the context.
+//
+// It is configured by the following hyperparameters:
+//
+//   - ParamTripletLossPairwiseDistanceMetric ("triplet_loss_pairwise_distance_metric"): the distance metric used
+//     to build the anchor-positive and anchor-negative distances, one of "l2" (Euclidean distance), "squared_l2"
+//     (squared Euclidean distance) or "cosine" (1 - cosine similarity), see PairwiseDistanceMetric.
+//     It defaults to "l2".
+//   - ParamTripletLossMargin ("triplet_loss_margin"): the margin of the TripletLoss. It defaults to 1.0.
+//   - ParamTripletLossMiningStrategy ("triplet_loss_mining_strategy"): one of "all", "hard" or "semi_hard", see
+//     TripletMiningStrategy. It defaults to "semi_hard".
+//   - ParamPairSampleFraction: if set to less than 1, SampledTripletLoss is used instead, with the random number
+//     generator of ctx.
+//
+// The returned LossFn expects labels[0] to hold the class ids of the examples, shaped `[batch_size, 1]` (or
+// `[batch_size]`), and predictions[0] to hold the embeddings, shaped `[batch_size, embed_dim]`.
+// Examples with the same class id are positives of each other, and examples with different class ids are negatives.
 func MakeTripletLossFromContext(ctx *context.Context) LossFn {
 	miningStrategy := context.GetParamOr(ctx, ParamTripletLossMiningStrategy, TripletMiningStrategySemiHard)
 	margin := context.GetParamOr(ctx, ParamTripletLossMargin, 1.0)
 	metric := context.GetParamOr(ctx, ParamTripletLossPairwiseDistanceMetric, PairwiseDistanceMetricL2)
+	sampleFraction := context.GetParamOr(ctx, ParamPairSampleFraction, 1.0)
+	if sampleFraction <= 0 || sampleFraction > 1 {
+		Panicf("invalid hyperparameter %q=%g, it must be in (0, 1]", ParamPairSampleFraction, sampleFraction)
+	}
+	if sampleFraction < 1 {
+		return func(labels, predictions []*Node) (loss *Node) {
+			return SampledTripletLoss(ctx, labels, predictions, miningStrategy, margin, metric, sampleFraction)
+		}
+	}
 	return func(labels, predictions []*Node) (loss *Node) {
 		return TripletLoss(labels, predictions, miningStrategy, margin, metric)
 	}
 }
+
+// SampledTripletLoss is like TripletLoss, but instead of the distances between all pairs of examples of the batch,
+// it only computes the distances of each anchor to `numCandidates = ceil(sampleFraction * batch_size)` candidates
+// (the positives and negatives), sampled uniformly (with replacement) from the batch with the random number
+// generator of ctx. It reduces the cost of the distances from O(batch_size^2) to
+// O(batch_size * numCandidates) -- and of the TripletMiningStrategyAll triplets from O(batch_size^3) to
+// O(batch_size * numCandidates^2).
+//
+// The tradeoff is the variance: at each step the loss is estimated from a random subset of the triplets, so it's
+// noisier, the more so the smaller the sampleFraction. For TripletMiningStrategyAll the sampled loss approximates
+// the full loss on average. The hard and semi-hard strategies only mine the hardest triplets among the sampled
+// candidates, so their loss is on average lower (easier triplets) than the full loss. Anchors without a sampled
+// positive and negative are not counted.
+//
+// There is no equivalent for MakeContrastiveLoss, since it takes the pairs explicitly, and only computes one
+// distance per example.
+func SampledTripletLoss(ctx *context.Context, labels, predictions []*Node,
+	miningStrategy TripletMiningStrategy,
+	margin float64,
+	metric PairwiseDistanceMetric,
+	sampleFraction float64) *Node {
+	if sampleFraction <= 0 || sampleFraction > 1 {
+		Panicf("SampledTripletLoss requires sampleFraction in (0, 1], got %g", sampleFraction)
+	}
+	predictions0 := predictions[0]
+	labels0 := labels[0]
+	weights, mask := CheckLabelsForWeightsAndMask(labels0.Shape(), labels)
+
+	g := predictions0.Graph()
+	batchSize := predictions0.Shape().Dim(0)
+	numCandidates := max(int(math.Ceil(sampleFraction*float64(batchSize))), 1)
+	candidates := ctx.RandomIntN(g, int32(batchSize), shapes.Make(dtypes.Int32, batchSize, numCandidates))
+	distances := sampledPairwiseDistances(predictions0, candidates, metric)
+
+	// Positive and negative masks of the candidates.
+	anchors := Iota(g, candidates.Shape(), 0)
+	flatLabels := Reshape(labels0, batchSize, 1)
+	candidatesLabels := Reshape(Gather(flatLabels, InsertAxes(candidates, -1)), batchSize, numCandidates)
+	labelsEqual := Equal(flatLabels, candidatesLabels)
+	negativeMask := LogicalNot(labelsEqual)
+	positiveMask := LogicalAnd(NotEqual(anchors, candidates), labelsEqual)
+	return tripletLossFromDistances(distances, positiveMask, negativeMask, weights, mask, miningStrategy, margin)
+}
+
+// sampledPairwiseDistances computes the distances of each embedding to its candidates.
+//
+// Parameters:
+//   - embeddings *Node 2-D tensor of shape (batch_size, embed_dim)
+//   - candidates *Node 2-D tensor of shape (batch_size, num_candidates) with the indices of the candidates of
+//     each embedding.
+//   - metric PairwiseDistanceMetric could be one of L2, squared L2 or cosine similarly distance metric
+//
+// Returns:
+//   - *Node 2-D tensor of shape (batch_size, num_candidates)
+func sampledPairwiseDistances(embeddings, candidates *Node, metric PairwiseDistanceMetric) *Node {
+	g := embeddings.Graph()
+	dtype := embeddings.DType()
+	eps := epsilonForDType(g, dtype)
+	anchorEmbeddings := InsertAxes(embeddings, 1)
+	candidateEmbeddings := Gather(embeddings, InsertAxes(candidates, -1))
+
+	var distances *Node
+	switch metric {
+	case PairwiseDistanceMetricSquaredL2:
+		distances = ReduceSum(Square(Sub(anchorEmbeddings, candidateEmbeddings)), -1)
+	case PairwiseDistanceMetricL2:
+		distances = euclideanDistance(BroadcastToShape(anchorEmbeddings, candidateEmbeddings.Shape()), candidateEmbeddings)
+	case PairwiseDistanceMetricCosine:
+		norms := Sqrt(MaxScalar(ReduceSum(Square(embeddings), -1), 0))
+		candidateNorms := Gather(InsertAxes(norms, -1), InsertAxes(candidates, -1))
+		dotProducts := ReduceSum(Mul(anchorEmbeddings, candidateEmbeddings), -1)
+		norms = Max(Mul(InsertAxes(norms, -1), Squeeze(candidateNorms, -1)), eps)
+		distances = OneMinus(Div(dotProducts, norms))
+	}
+
+	// Because of computation errors, some distances might be negative so we put everything >= 0.0
+	distances = MaxScalar(distances, 0.0)
+	distances.AssertDims(candidates.Shape().Dimensions...)
+	return distances
+}
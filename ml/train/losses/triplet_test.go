@@ -1,10 +1,13 @@
 package losses
 
 import (
+	"fmt"
 	"testing"
 
 	. "github.com/gomlx/gomlx/graph"
 	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTripletLoss(t *testing.T) {
@@ -95,3 +98,77 @@ func TestTripletLoss(t *testing.T) {
 			//  [0., 0., 0., 0., 0., 1., 0., 1., 0., 0.]]
 		}, 1e-3)
 }
+
+func TestMakeTripletLossFromContextDistances(t *testing.T) {
+	backend := graphtest.BuildTestBackend()
+	for _, distance := range []string{"l2", "squared_l2", "cosine"} {
+		ctx := context.New()
+		ctx.SetParams(map[string]any{
+			ParamTripletLossPairwiseDistanceMetric: distance,
+			ParamTripletLossMargin:                 0.5,
+			ParamTripletLossMiningStrategy:         "all",
+		})
+		lossFn := MakeTripletLossFromContext(ctx)
+		// Hand-built triplet: anchor, positive close to the anchor, and negative far away.
+		embeddings := [][]float32{{1, 0}, {0.9, 0.1}, {0, 1}}
+		correctLabels := [][]int32{{0}, {0}, {1}} // Positive is the close example.
+		swappedLabels := [][]int32{{0}, {1}, {0}} // Positive is the far example.
+		exec := NewExec(backend, func(embeddings, correctLabels, swappedLabels *Node) (correctLoss, swappedLoss *Node) {
+			correctLoss = lossFn([]*Node{correctLabels}, []*Node{embeddings})
+			swappedLoss = lossFn([]*Node{swappedLabels}, []*Node{embeddings})
+			return
+		})
+		losses := exec.Call(embeddings, correctLabels, swappedLabels)
+		exec.Finalize()
+		correctLoss, swappedLoss := losses[0].Value().(float32), losses[1].Value().(float32)
+		fmt.Printf("\tdistance=%q: correct triplet loss=%g, swapped triplet loss=%g\n", distance, correctLoss, swappedLoss)
+		require.Lessf(t, correctLoss, swappedLoss, "distance %q should favor the closer positive", distance)
+	}
+}
+
+func TestSampledTripletLoss(t *testing.T) {
+	backend := graphtest.BuildTestBackend()
+	ctx := context.New()
+	ctx.RngStateFromSeed(42)
+	ctx.SetParams(map[string]any{
+		ParamTripletLossMiningStrategy: "all",
+		ParamPairSampleFraction:        0.5,
+	})
+	sampledLossFn := MakeTripletLossFromContext(ctx)
+	labels := [][]float32{{1}, {0}, {0}, {0}, {3}, {2}, {3}, {2}, {1}, {2}}
+	embeddings := [][]float32{
+		{0.08208963, 0.11788353, 0.46360782, 0.3360519, 0.2702437, 0.6951965},
+		{0.598121, 0.14609586, 0.07872304, 0.949776, 0.41479972, 0.36961815},
+		{0.11646613, 0.8878409, 0.4034519, 0.9632401, 0.6313564, 0.0198459},
+		{0.03582959, 0.3428808, 0.843301, 0.6335877, 0.8623248, 0.16186231},
+		{0.09054314, 0.746887, 0.56099737, 0.7181275, 0.60642695, 0.02207313},
+		{0.2735666, 0.08748698, 0.13752021, 0.4570993, 0.8813543, 0.98528206},
+		{0.5412437, 0.2382705, 0.6263132, 0.29713312, 0.9241606, 0.734765},
+		{0.22289598, 0.84535605, 0.4398808, 0.5816502, 0.31203038, 0.5436755},
+		{0.5512105, 0.6922551, 0.11149547, 0.6343566, 0.20425326, 0.3884894},
+		{0.51529086, 0.35541356, 0.77092594, 0.3715265, 0.40550032, 0.7369012},
+	}
+	exec := context.NewExec(backend, ctx, func(ctx *context.Context, labels, embeddings *Node) (sampledLoss, fullLoss *Node) {
+		sampledLoss = sampledLossFn([]*Node{labels}, []*Node{embeddings})
+		fullLoss = TripletLoss([]*Node{labels}, []*Node{embeddings}, TripletMiningStrategyAll, 1.0, PairwiseDistanceMetricL2)
+		return
+	})
+	defer exec.Finalize()
+
+	// Each call samples a different subset of the pairs: on average, it approximates the full loss.
+	const numSteps = 400
+	var sum float64
+	var fullLoss float32
+	for range numSteps {
+		results := exec.Call(labels, embeddings)
+		sum += float64(results[0].Value().(float32))
+		fullLoss = results[1].Value().(float32)
+	}
+	mean := sum / numSteps
+	fmt.Printf("\tsampled triplet loss mean=%g, full triplet loss=%g\n", mean, fullLoss)
+	require.InDelta(t, 1.0418946, fullLoss, 1e-3)
+	require.InDelta(t, float64(fullLoss), mean, 0.1*float64(fullLoss))
+
+	ctx.SetParam(ParamPairSampleFraction, 1.5)
+	require.Panics(t, func() { MakeTripletLossFromContext(ctx) })
+}
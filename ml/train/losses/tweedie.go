@@ -0,0 +1,78 @@
+/*
+ *	Copyright 2024 Jan Pfeifer
+ *
+ *	Licensed under the Apache License, Version 2.0 (the "License");
+ *	you may not use this file except in compliance with the License.
+ *	You may obtain a copy of the License at
+ *
+ *	http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *	Unless required by applicable law or agreed to in writing, software
+ *	distributed under the License is distributed on an "AS IS" BASIS,
+ *	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *	See the License for the specific language governing permissions and
+ *	limitations under the License.
+ */
+
+package losses
+
+import (
+	. "github.com/gomlx/exceptions"
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/ml/context"
+)
+
+var (
+	// ParamTweediePower is the name of the hyperparameter that defines the power parameter p of the Tweedie
+	// deviance loss. It must be 1 < p < 2, and it defaults to 1.5.
+	//
+	// See MakeTweedieLoss.
+	ParamTweediePower = "tweedie_power"
+)
+
+// MakeTweedieLoss returns the Tweedie deviance loss with power parameter p, with 1 < p < 2 (compound Poisson-Gamma
+// distribution), commonly used in insurance pricing to model claim amounts, which are zero for most policies.
+//
+// For labels y and predictions mu (the predicted mean, which must be positive), the deviance is:
+//
+//	2 * (y^(2-p) / ((1-p)*(2-p)) - y * mu^(1-p) / (1-p) + mu^(2-p) / (2-p))
+//
+// As p approaches 1 it approaches the Poisson deviance, and as p approaches 2 it approaches the Gamma deviance.
+// Predictions are clamped to a small epsilon, so the powers are always finite -- models usually predict
+// log(mu) and take the exponential, to guarantee positive predictions.
+//
+// It returns the per-element losses, with weights and mask applied as in ApplyWeightsAndMask.
+//
+// It panics if p is not in the range (1, 2).
+func MakeTweedieLoss(p float64) LossFn {
+	if p <= 1 || p >= 2 {
+		Panicf("MakeTweedieLoss requires 1 < p < 2, got %g", p)
+	}
+	return func(labels, predictions []*Node) (loss *Node) {
+		predictions0 := predictions[0]
+		g := predictions0.Graph()
+		dtype := predictions0.DType()
+		labels0 := convertLabels("MakeTweedieLoss", labels[0], dtype)
+		if !labels0.Shape().Equal(predictions0.Shape()) {
+			Panicf("labels[0] (%s) and predictions[0] (%s) must have same shape", labels0.Shape(), predictions0.Shape())
+		}
+		weights, mask := CheckLabelsForWeightsAndMask(labels0.Shape(), labels)
+
+		epsilon := epsilonForDType(g, dtype)
+		mu := Max(predictions0, epsilon)
+		y := Max(labels0, ZerosLike(labels0))
+		labelsTerm := DivScalar(Pow(y, Scalar(g, dtype, 2-p)), (1-p)*(2-p))
+		crossTerm := DivScalar(Mul(y, Pow(mu, Scalar(g, dtype, 1-p))), 1-p)
+		predictionsTerm := DivScalar(Pow(mu, Scalar(g, dtype, 2-p)), 2-p)
+		loss = MulScalar(Add(Sub(labelsTerm, crossTerm), predictionsTerm), 2)
+		loss = ApplyWeightsAndMask(loss, weights, mask)
+		return loss
+	}
+}
+
+// MakeTweedieLossFromContext calls MakeTweedieLoss using the power configured by the hyperparameter
+// ParamTweediePower in the context.
+func MakeTweedieLossFromContext(ctx *context.Context) LossFn {
+	p := context.GetParamOr(ctx, ParamTweediePower, 1.5)
+	return MakeTweedieLoss(p)
+}
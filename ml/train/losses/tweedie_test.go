@@ -0,0 +1,55 @@
+package losses
+
+import (
+	"testing"
+
+	. "github.com/gomlx/gomlx/graph"
+	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTweedieLoss(t *testing.T) {
+	ctx := context.New()
+	ctx.SetParams(map[string]any{ParamLoss: "tweedie", ParamTweediePower: 1.5})
+	contextLossFn, err := LossFromContext(ctx)
+	require.NoError(t, err)
+	require.Panics(t, func() { MakeTweedieLoss(1) })
+	require.Panics(t, func() { MakeTweedieLoss(2) })
+
+	graphtest.RunTestGraphFn(t, "MakeTweedieLoss", func(g *Graph) (inputs, outputs []*Node) {
+		predictions := Const(g, []float64{0.5, 1, 3, 4})
+		labels := Const(g, []float64{0, 1, 2, 5})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{
+			MakeTweedieLoss(1.5)([]*Node{labels}, []*Node{predictions}),
+			contextLossFn([]*Node{labels}, []*Node{predictions}),
+		}
+		return
+	}, []any{
+		[]float64{0.48528, 0.68629, 0.58666, 3.34315},
+		[]float64{0.48528, 0.68629, 0.58666, 3.34315},
+	}, 1e-4)
+
+	graphtest.RunTestGraphFn(t, "MakeTweedieLoss: Poisson limit", func(g *Graph) (inputs, outputs []*Node) {
+		predictions := Const(g, []float64{0.5, 1, 3, 4})
+		labels := Const(g, []float64{0, 1, 2, 5})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{MakeTweedieLoss(1.01)([]*Node{labels}, []*Node{predictions})}
+		return
+	}, []any{
+		// Poisson deviance: 2 * (y*log(y/mu) - (y-mu)).
+		[]float64{1, 0, 0.37814, 0.23144},
+	}, 0.02)
+
+	graphtest.RunTestGraphFn(t, "MakeTweedieLoss: Gamma limit", func(g *Graph) (inputs, outputs []*Node) {
+		predictions := Const(g, []float64{0.5, 1, 3, 4})
+		labels := Const(g, []float64{1, 2, 5, 0.5})
+		inputs = []*Node{labels, predictions}
+		outputs = []*Node{MakeTweedieLoss(1.99)([]*Node{labels}, []*Node{predictions})}
+		return
+	}, []any{
+		// Gamma deviance: 2 * (log(mu/y) + y/mu - 1).
+		[]float64{0.61371, 0.61371, 0.31168, 2.40888},
+	}, 0.02)
+}
@@ -8,11 +8,11 @@ import (
 	"strings"
 )
 
-const _TypeName = "maemsehuberaplbin_crossbin_cross_logitscategorical_crosscategorical_cross_logitssparse_cross_logitstriplet"
+const _TypeName = "maemsehuberaplbin_crossbin_cross_logitscategorical_crosscategorical_cross_logitssparse_cross_logitstripletcoralcontrastivekl_logits_bothhinge_embeddingtweediesparse_focal_logitsdice_cesparse_crossbalanced_focalf_divergencegiousoft_spearmancoxclass_balanced_focalbriercategorical_hinge"
 
-var _TypeIndex = [...]uint8{0, 3, 6, 11, 14, 23, 39, 56, 80, 99, 106}
+var _TypeIndex = [...]uint16{0, 3, 6, 11, 14, 23, 39, 56, 80, 99, 106, 111, 122, 136, 151, 158, 177, 184, 196, 210, 222, 226, 239, 242, 262, 267, 284}
 
-const _TypeLowerName = "maemsehuberaplbin_crossbin_cross_logitscategorical_crosscategorical_cross_logitssparse_cross_logitstriplet"
+const _TypeLowerName = "maemsehuberaplbin_crossbin_cross_logitscategorical_crosscategorical_cross_logitssparse_cross_logitstripletcoralcontrastivekl_logits_bothhinge_embeddingtweediesparse_focal_logitsdice_cesparse_crossbalanced_focalf_divergencegiousoft_spearmancoxclass_balanced_focalbriercategorical_hinge"
 
 func (i Type) String() string {
 	if i < 0 || i >= Type(len(_TypeIndex)-1) {
@@ -39,31 +39,79 @@ func _TypeNoOp() {
 	_ = x[TypeCategoricalCrossLogits-(7)]
 	_ = x[TypeSparseCrossLogits-(8)]
 	_ = x[TypeTriplet-(9)]
+	_ = x[TypeCoral-(10)]
+	_ = x[TypeContrastive-(11)]
+	_ = x[TypeKLLogitsBoth-(12)]
+	_ = x[TypeHingeEmbedding-(13)]
+	_ = x[TypeTweedie-(14)]
+	_ = x[TypeSparseFocalLogits-(15)]
+	_ = x[TypeDiceCE-(16)]
+	_ = x[TypeSparseCross-(17)]
+	_ = x[TypeBalancedFocal-(18)]
+	_ = x[TypeFDivergence-(19)]
+	_ = x[TypeGiou-(20)]
+	_ = x[TypeSoftSpearman-(21)]
+	_ = x[TypeCox-(22)]
+	_ = x[TypeClassBalancedFocal-(23)]
+	_ = x[TypeBrier-(24)]
+	_ = x[TypeCategoricalHinge-(25)]
 }
 
-var _TypeValues = []Type{TypeMAE, TypeMSE, TypeHuber, TypeAPL, TypeBinCross, TypeBinCrossLogits, TypeCategoricalCross, TypeCategoricalCrossLogits, TypeSparseCrossLogits, TypeTriplet}
+var _TypeValues = []Type{TypeMAE, TypeMSE, TypeHuber, TypeAPL, TypeBinCross, TypeBinCrossLogits, TypeCategoricalCross, TypeCategoricalCrossLogits, TypeSparseCrossLogits, TypeTriplet, TypeCoral, TypeContrastive, TypeKLLogitsBoth, TypeHingeEmbedding, TypeTweedie, TypeSparseFocalLogits, TypeDiceCE, TypeSparseCross, TypeBalancedFocal, TypeFDivergence, TypeGiou, TypeSoftSpearman, TypeCox, TypeClassBalancedFocal, TypeBrier, TypeCategoricalHinge}
 
 var _TypeNameToValueMap = map[string]Type{
-	_TypeName[0:3]:         TypeMAE,
-	_TypeLowerName[0:3]:    TypeMAE,
-	_TypeName[3:6]:         TypeMSE,
-	_TypeLowerName[3:6]:    TypeMSE,
-	_TypeName[6:11]:        TypeHuber,
-	_TypeLowerName[6:11]:   TypeHuber,
-	_TypeName[11:14]:       TypeAPL,
-	_TypeLowerName[11:14]:  TypeAPL,
-	_TypeName[14:23]:       TypeBinCross,
-	_TypeLowerName[14:23]:  TypeBinCross,
-	_TypeName[23:39]:       TypeBinCrossLogits,
-	_TypeLowerName[23:39]:  TypeBinCrossLogits,
-	_TypeName[39:56]:       TypeCategoricalCross,
-	_TypeLowerName[39:56]:  TypeCategoricalCross,
-	_TypeName[56:80]:       TypeCategoricalCrossLogits,
-	_TypeLowerName[56:80]:  TypeCategoricalCrossLogits,
-	_TypeName[80:99]:       TypeSparseCrossLogits,
-	_TypeLowerName[80:99]:  TypeSparseCrossLogits,
-	_TypeName[99:106]:      TypeTriplet,
-	_TypeLowerName[99:106]: TypeTriplet,
+	_TypeName[0:3]:          TypeMAE,
+	_TypeLowerName[0:3]:     TypeMAE,
+	_TypeName[3:6]:          TypeMSE,
+	_TypeLowerName[3:6]:     TypeMSE,
+	_TypeName[6:11]:         TypeHuber,
+	_TypeLowerName[6:11]:    TypeHuber,
+	_TypeName[11:14]:        TypeAPL,
+	_TypeLowerName[11:14]:   TypeAPL,
+	_TypeName[14:23]:        TypeBinCross,
+	_TypeLowerName[14:23]:   TypeBinCross,
+	_TypeName[23:39]:        TypeBinCrossLogits,
+	_TypeLowerName[23:39]:   TypeBinCrossLogits,
+	_TypeName[39:56]:        TypeCategoricalCross,
+	_TypeLowerName[39:56]:   TypeCategoricalCross,
+	_TypeName[56:80]:        TypeCategoricalCrossLogits,
+	_TypeLowerName[56:80]:   TypeCategoricalCrossLogits,
+	_TypeName[80:99]:        TypeSparseCrossLogits,
+	_TypeLowerName[80:99]:   TypeSparseCrossLogits,
+	_TypeName[99:106]:       TypeTriplet,
+	_TypeLowerName[99:106]:  TypeTriplet,
+	_TypeName[106:111]:      TypeCoral,
+	_TypeLowerName[106:111]: TypeCoral,
+	_TypeName[111:122]:      TypeContrastive,
+	_TypeLowerName[111:122]: TypeContrastive,
+	_TypeName[122:136]:      TypeKLLogitsBoth,
+	_TypeLowerName[122:136]: TypeKLLogitsBoth,
+	_TypeName[136:151]:      TypeHingeEmbedding,
+	_TypeLowerName[136:151]: TypeHingeEmbedding,
+	_TypeName[151:158]:      TypeTweedie,
+	_TypeLowerName[151:158]: TypeTweedie,
+	_TypeName[158:177]:      TypeSparseFocalLogits,
+	_TypeLowerName[158:177]: TypeSparseFocalLogits,
+	_TypeName[177:184]:      TypeDiceCE,
+	_TypeLowerName[177:184]: TypeDiceCE,
+	_TypeName[184:196]:      TypeSparseCross,
+	_TypeLowerName[184:196]: TypeSparseCross,
+	_TypeName[196:210]:      TypeBalancedFocal,
+	_TypeLowerName[196:210]: TypeBalancedFocal,
+	_TypeName[210:222]:      TypeFDivergence,
+	_TypeLowerName[210:222]: TypeFDivergence,
+	_TypeName[222:226]:      TypeGiou,
+	_TypeLowerName[222:226]: TypeGiou,
+	_TypeName[226:239]:      TypeSoftSpearman,
+	_TypeLowerName[226:239]: TypeSoftSpearman,
+	_TypeName[239:242]:      TypeCox,
+	_TypeLowerName[239:242]: TypeCox,
+	_TypeName[242:262]:      TypeClassBalancedFocal,
+	_TypeLowerName[242:262]: TypeClassBalancedFocal,
+	_TypeName[262:267]:      TypeBrier,
+	_TypeLowerName[262:267]: TypeBrier,
+	_TypeName[267:284]:      TypeCategoricalHinge,
+	_TypeLowerName[267:284]: TypeCategoricalHinge,
 }
 
 var _TypeNames = []string{
@@ -77,6 +125,22 @@ var _TypeNames = []string{
 	_TypeName[56:80],
 	_TypeName[80:99],
 	_TypeName[99:106],
+	_TypeName[106:111],
+	_TypeName[111:122],
+	_TypeName[122:136],
+	_TypeName[136:151],
+	_TypeName[151:158],
+	_TypeName[158:177],
+	_TypeName[177:184],
+	_TypeName[184:196],
+	_TypeName[196:210],
+	_TypeName[210:222],
+	_TypeName[222:226],
+	_TypeName[226:239],
+	_TypeName[239:242],
+	_TypeName[242:262],
+	_TypeName[262:267],
+	_TypeName[267:284],
 }
 
 // TypeString retrieves an enum value from the enum constants string name.
@@ -461,3 +461,25 @@ func NewSparseCategoricalAccuracy(name, shortName string) Interface {
 func NewMovingAverageSparseCategoricalAccuracy(name, shortName string, newExampleWeight float64) Interface {
 	return NewExponentialMovingAverageMetric(name, shortName, AccuracyMetricType, SparseCategoricalAccuracyGraph, accuracyPPrint, newExampleWeight)
 }
+
+// BrierScoreGraph returns the mean multiclass Brier score of the predictions (probabilities), given the labels, see
+// losses.BrierScoreLoss for details. Labels can be sparse (integer) or dense (e.g.: one-hot encoded).
+//
+// Weights and mask can be given in the `labels` slice, following the labels themselves, and they
+// will be accounted for: the result is the weighted mean over the non-masked examples.
+func BrierScoreGraph(_ *context.Context, labels, predictions []*Node) *Node {
+	return losses.MakeWeightedMeanLoss(losses.BrierScoreLoss)(labels, predictions)
+}
+
+// NewMeanBrierScore returns a new multiclass Brier score metric with the given names, a measure of the
+// calibration of predicted probabilities -- lower is better. See BrierScoreGraph.
+func NewMeanBrierScore(name, shortName string) Interface {
+	return NewMeanMetric(name, shortName, LossMetricType, BrierScoreGraph, nil)
+}
+
+// NewMovingAverageBrierScore returns a new multiclass Brier score metric with the given names, a measure of the
+// calibration of predicted probabilities -- lower is better. See BrierScoreGraph.
+// A typical value of newExampleWeight is 0.01, the smaller the value, the slower the moving average moves.
+func NewMovingAverageBrierScore(name, shortName string, newExampleWeight float64) Interface {
+	return NewExponentialMovingAverageMetric(name, shortName, LossMetricType, BrierScoreGraph, nil, newExampleWeight)
+}
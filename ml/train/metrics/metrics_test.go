@@ -142,3 +142,21 @@ func TestSparseCategoricalAccuracyGraph(t *testing.T) {
 		assert.Equal(t, float32((2.0*1.0)/(1.0+2.0+0.5)), got, "TestSparseCategoricalAccuracyGraph[with mask/weights]")
 	}
 }
+
+func TestBrierScoreGraph(t *testing.T) {
+	manager := graphtest.BuildTestBackend()
+	ctx := context.New()
+	brierExec := context.NewExec(manager, ctx, takeLabelsMaskWeightPredictionsFn(BrierScoreGraph))
+	labels := [][]int{{0}, {2}, {1}, {0}}
+	mask := []bool{true, true, true, false}
+	weights := []float32{1.0, 2.0, 1.0, 100.0}
+	predictions := [][]float32{
+		{0.7, 0.2, 0.1}, // Brier score: 0.14
+		{0.1, 0.3, 0.6}, // Brier score: 0.26
+		{0, 1, 0},       // Perfect prediction: 0
+		{0, 1, 0},       // Disabled by mask.
+	}
+	results := brierExec.Call(labels, mask, weights, predictions)
+	got, _ := results[0].Value().(float32)
+	assert.InDelta(t, (0.14*1.0+0.26*2.0)/(1.0+2.0+1.0), got, 1e-5, "TestBrierScoreGraph[with mask/weights]")
+}
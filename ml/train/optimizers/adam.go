@@ -212,6 +212,7 @@ func (o *adam) UpdateGraph(ctx *context.Context, g *Graph, loss *Node) {
 	if len(grads) == 0 {
 		Panicf("Context.BuildTrainableVariablesGradientsGraph returned 0 gradients, are there any trainable variables ?")
 	}
+	skipUpdate := CallGradientsHooks(ctx, g, grads)
 
 	// Apply gradient one variable at a time.
 	numTrainable := len(grads)
@@ -219,7 +220,7 @@ func (o *adam) UpdateGraph(ctx *context.Context, g *Graph, loss *Node) {
 	ctx.EnumerateVariables(func(v *context.Variable) {
 		if v.Trainable && v.InUseByGraph(g) {
 			if varIdx < numTrainable {
-				o.applyAdamGraph(ctx, g, v, dtype, grads[varIdx], learningRate, beta1, debiasTermBeta1, beta2, debiasTermBeta2, epsilon)
+				o.applyAdamGraph(ctx, g, v, dtype, grads[varIdx], learningRate, beta1, debiasTermBeta1, beta2, debiasTermBeta2, epsilon, skipUpdate)
 			}
 			varIdx++
 		}
@@ -236,9 +237,10 @@ func (o *adam) UpdateGraph(ctx *context.Context, g *Graph, loss *Node) {
 // applyAdamGraph calculates variable and its 1st and 2nd order moments updates.
 // If `Adamax` is set, we use instead moment2 to store the L-infinity (the max) of the gradient.
 func (o *adam) applyAdamGraph(ctx *context.Context, g *Graph, v *context.Variable, dtype dtypes.DType, grad *Node,
-	learningRate, beta1, debiasTermBeta1, beta2, debiasTermBeta2, epsilon *Node) {
+	learningRate, beta1, debiasTermBeta1, beta2, debiasTermBeta2, epsilon, skipUpdate *Node) {
 	m1Var, m2Var := o.getMomentVariables(ctx, v, dtype)
 	moment1, moment2 := m1Var.ValueGraph(g), m2Var.ValueGraph(g)
+	originalMoment1, originalMoment2 := moment1, moment2
 
 	// Adam runs on a fixed dtype -- defaults to the dtype of the loss, but it can be configured.
 	// We convert the grad to the dtype used by Adam for its computation.
@@ -251,7 +253,7 @@ func (o *adam) applyAdamGraph(ctx *context.Context, g *Graph, v *context.Variabl
 	moment1 = Add(
 		Mul(beta1, moment1),
 		Mul(OneMinus(beta1), grad))
-	m1Var.SetValueGraph(moment1)
+	m1Var.SetValueGraph(SkipUpdate(skipUpdate, originalMoment1, moment1))
 	debiasedMoment1 := Mul(moment1, debiasTermBeta1)
 
 	var denominator *Node
@@ -260,7 +262,7 @@ func (o *adam) applyAdamGraph(ctx *context.Context, g *Graph, v *context.Variabl
 		moment2 = Max(
 			Mul(beta2, moment2),
 			Abs(grad)) // L-infinity norm. Notice Abs() can change dtypes for complex numbers.
-		m2Var.SetValueGraph(moment2)
+		m2Var.SetValueGraph(SkipUpdate(skipUpdate, originalMoment2, moment2))
 		denominator = Add(moment2, epsilon)
 
 	} else {
@@ -268,7 +270,7 @@ func (o *adam) applyAdamGraph(ctx *context.Context, g *Graph, v *context.Variabl
 		moment2 = Add(
 			Mul(beta2, moment2),
 			Mul(OneMinus(beta2), Square(grad)))
-		m2Var.SetValueGraph(moment2)
+		m2Var.SetValueGraph(SkipUpdate(skipUpdate, originalMoment2, moment2))
 		debiasedMoment2 := Mul(moment2, debiasTermBeta2)
 		denominator = Add(Sqrt(debiasedMoment2), epsilon)
 	}
@@ -298,7 +300,7 @@ func (o *adam) applyAdamGraph(ctx *context.Context, g *Graph, v *context.Variabl
 		// Convert back to the variable type.
 		updated = ConvertDType(updated, v.Shape().DType)
 	}
-	v.SetValueGraph(updated)
+	v.SetValueGraph(SkipUpdate(skipUpdate, v.ValueGraph(g), updated))
 	return
 }
 
@@ -225,6 +225,60 @@ func ClipNaNsInUpdates(ctx *context.Context, original, updates *Node) *Node {
 	return Where(IsFinite(updates), updates, original)
 }
 
+// gradientsHooksGraphParam is the graph parameter, in the root scope, holding the hooks registered with
+// AddGradientsHook.
+const gradientsHooksGraphParam = "optimizers_gradients_hooks"
+
+// GradientsHook is called by the optimizers with the gradients of the loss with respect to the trainable variables,
+// in the order of Context.EnumerateVariables. See AddGradientsHook.
+//
+// It can return a boolean scalar node that, when true, makes the optimizer skip the update of the step -- the
+// trainable variables and the optimizer's own state (e.g.: Adam moments) are kept unchanged, only the global step
+// counters are still incremented. Or it can return nil.
+type GradientsHook func(g *Graph, grads []*Node) (skipUpdate *Node)
+
+// AddGradientsHook registers hook to be called with the gradients computed by the optimizer for the graph g, before
+// they are applied. It allows other components to inspect the gradients without building another backward pass
+// -- e.g.: losses.MakeDynamicScaledLoss checks them for overflows, and skips the update if there are any.
+//
+// Hooks are registered per graph, and called in the order they were added. The optimizers of this package call
+// them; custom optimizers should use CallGradientsHooks.
+func AddGradientsHook(ctx *context.Context, g *Graph, hook GradientsHook) {
+	ctx = ctx.InAbsPath(context.RootScope)
+	hooks := context.GetGraphParamOr[[]GradientsHook](ctx, g, gradientsHooksGraphParam, nil)
+	ctx.SetGraphParam(g, gradientsHooksGraphParam, append(hooks, hook))
+}
+
+// CallGradientsHooks calls the hooks registered with AddGradientsHook for the graph g with the gradients of the
+// loss with respect to the trainable variables, as returned by Context.BuildTrainableVariablesGradientsGraph.
+//
+// It returns whether any of the hooks requested to skip the update, as a boolean scalar node, or nil if none of
+// them can, see SkipUpdate.
+func CallGradientsHooks(ctx *context.Context, g *Graph, grads []*Node) (skipUpdate *Node) {
+	ctx = ctx.InAbsPath(context.RootScope)
+	for _, hook := range context.GetGraphParamOr[[]GradientsHook](ctx, g, gradientsHooksGraphParam, nil) {
+		skip := hook(g, grads)
+		if skip == nil {
+			continue
+		}
+		if skipUpdate == nil {
+			skipUpdate = skip
+		} else {
+			skipUpdate = LogicalOr(skipUpdate, skip)
+		}
+	}
+	return
+}
+
+// SkipUpdate returns original if skipUpdate (returned by CallGradientsHooks) is true, or updated otherwise.
+// If skipUpdate is nil, it returns updated.
+func SkipUpdate(skipUpdate, original, updated *Node) *Node {
+	if skipUpdate == nil {
+		return updated
+	}
+	return Where(skipUpdate, original, updated)
+}
+
 // sgd is an empty struct that implements Interface for SGD.
 type sgd struct{}
 
@@ -273,6 +327,7 @@ func addGradientsToVariablesGraph(ctx *context.Context, loss, learningRate, glob
 	if len(grads) == 0 {
 		return
 	}
+	skipUpdate := CallGradientsHooks(ctx, g, grads)
 	numTrainable := len(grads)
 	ii := 0
 	ctx.EnumerateVariables(func(v *context.Variable) {
@@ -293,7 +348,7 @@ func addGradientsToVariablesGraph(ctx *context.Context, loss, learningRate, glob
 		vNode := v.ValueGraph(g)
 		updatedValue := Sub(vNode, scaledGradient)
 		updatedValue = ClipNaNsInUpdates(ctx, vNode, updatedValue)
-		v.SetValueGraph(updatedValue)
+		v.SetValueGraph(SkipUpdate(skipUpdate, vNode, updatedValue))
 		ii++
 	})
 	if ii != numTrainable {
@@ -3,6 +3,8 @@ package optimizers
 import (
 	. "github.com/gomlx/gomlx/graph"
 	"github.com/gomlx/gomlx/graph/graphtest"
+	"github.com/gomlx/gomlx/ml/context"
+	"github.com/stretchr/testify/require"
 	"testing"
 
 	_ "github.com/gomlx/gomlx/backends/xla"
@@ -32,3 +34,33 @@ func TestMonotonicProjection(t *testing.T) {
 	}, 1e-4)
 
 }
+
+func TestGradientsHooks(t *testing.T) {
+	backend := graphtest.BuildTestBackend()
+	for name, opt := range map[string]Interface{"sgd": StochasticGradientDescent(), "adam": Adam().Done()} {
+		for _, skip := range []bool{false, true} {
+			ctx := context.New()
+			wVar := ctx.VariableWithValue("w", float32(1))
+			var numGrads int
+			exec := context.NewExec(backend, ctx, func(ctx *context.Context, x *Node) *Node {
+				g := x.Graph()
+				loss := ReduceAllSum(Mul(x, wVar.ValueGraph(g)))
+				AddGradientsHook(ctx, g, func(g *Graph, grads []*Node) (skipUpdate *Node) {
+					numGrads = len(grads)
+					return Const(g, skip)
+				})
+				opt.UpdateGraph(ctx, g, loss)
+				return loss
+			})
+			exec.Call([]float32{1, 2})
+			exec.Finalize()
+			require.Equalf(t, 1, numGrads, "optimizer %q", name)
+			w := wVar.Value().Value().(float32)
+			if skip {
+				require.Equalf(t, float32(1), w, "optimizer %q should have skipped the update", name)
+			} else {
+				require.NotEqualf(t, float32(1), w, "optimizer %q should have updated the variable", name)
+			}
+		}
+	}
+}
@@ -101,8 +101,8 @@ type ModelFn func(ctx *context.Context, spec any, inputs []*graph.Node) (predict
 // For some types of self-supervised models for which there are no labels, the labels can be empty.
 //
 // Most of the predefined losses in package `gomlx/ml/train/losses` assume labels and predictions are
-// both of length one. For multi-head models, it's very easy to write a small custom LossFn that splits
-// the slice and send each label/prediction pair to a predefined loss.
+// both of length one. For multi-head models, losses.MakeMultiHeadLoss splits the slices and sends each head's
+// labels/predictions to its loss -- or it's very easy to write a small custom LossFn that does it.
 //
 // Interface is defined in the losses package.
 type LossFn = losses.LossFn